@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessGrantController issues single-use hospital access grants
+type AccessGrantController struct {
+	Repo    *routes.Repository
+	Service *services.AccessGrantService
+}
+
+// NewAccessGrantController creates a new AccessGrantController
+func NewAccessGrantController(repo *routes.Repository) *AccessGrantController {
+	service := services.NewAccessGrantService(repo.Firestore)
+	return &AccessGrantController{Repo: repo, Service: service}
+}
+
+// IssuePatientGrantHandler lets a patient grant a hospital one read of their data
+func (gc *AccessGrantController) IssuePatientGrantHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		HospitalID string `json:"hospital_id" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	grant, err := gc.Service.IssuePatientGrant(c.UserContext(), userID, req.HospitalID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(grant)
+}
+
+// IssueDoctorGrantHandler lets a doctor with the patient's card grant a hospital
+// one read of the patient's data
+func (gc *AccessGrantController) IssueDoctorGrantHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		NFCID      string `json:"nfc_id" validate:"required"`
+		HospitalID string `json:"hospital_id" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), gc.Repo.Firestore, req.NFCID)...)
+	grant, err := gc.Service.IssueDoctorGrant(c.UserContext(), doctorID, req.NFCID, req.HospitalID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(grant)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditController exposes the audit log to administrators
+type AuditController struct {
+	Repo    *routes.Repository
+	Service *services.AuditService
+}
+
+// NewAuditController creates a new AuditController
+func NewAuditController(repo *routes.Repository) *AuditController {
+	service := services.NewAuditService(repo.Audit)
+	return &AuditController{Repo: repo, Service: service}
+}
+
+// AuditLogHandler searches the audit log. Filters: patient_id, actor_id and
+// role, plus the page options (sort by time).
+func (ac *AuditController) AuditLogHandler(c *fiber.Ctx) error {
+	page, err := parsePageQuery(c)
+	if err != nil {
+		return err
+	}
+	query := models.AuditQuery{
+		PatientID: c.Query("patient_id"),
+		ActorID:   c.Query("actor_id"),
+		ActorRole: c.Query("role"),
+		PageQuery: page,
+	}
+
+	entries, nextCursor, err := ac.Service.Query(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+	return c.JSON(models.Page[*models.AuditEntry]{Items: entries, NextCursor: nextCursor})
+}
@@ -25,23 +25,18 @@ func NewAuthController(authClient *firebase.AuthClient) *AuthController {
 // LoginHandler handles user login and returns a Firebase JWT token
 func (ac *AuthController) LoginHandler(c *fiber.Ctx) error {
 	type Request struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
 	}
 	var req Request
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Call AuthService to authenticate and get a token
-	token, err := ac.AuthService.Login(c, req.Email, req.Password)
+	token, err := ac.AuthService.Login(c.UserContext(), req.Email, req.Password)
 	if err != nil {
-		// Use fiber.Error.Status to get the status code
-		status := fiber.StatusInternalServerError
-		if e, ok := err.(*fiber.Error); ok {
-			status = e.Code
-		}
-		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	// Return the token (mocked for now, update for real Firebase ID token in production)
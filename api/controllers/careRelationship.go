@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CareRelationshipController manages doctors' treating relationships with patients
+type CareRelationshipController struct {
+	Repo    *routes.Repository
+	Service *services.CareRelationshipService
+}
+
+// NewCareRelationshipController creates a new CareRelationshipController
+func NewCareRelationshipController(repo *routes.Repository) *CareRelationshipController {
+	service := services.NewCareRelationshipService(repo.Firestore)
+	return &CareRelationshipController{Repo: repo, Service: service}
+}
+
+// EstablishHandler starts or renews a relationship with the patient whose card
+// the doctor has just scanned
+func (cc *CareRelationshipController) EstablishHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		NFCID string `json:"nfc_id" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), cc.Repo.Firestore, req.NFCID)...)
+	relationship, err := cc.Service.Establish(c.UserContext(), doctorID, req.NFCID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(relationship)
+}
+
+// DoctorRelationshipsHandler lists the doctor's current patients
+func (cc *CareRelationshipController) DoctorRelationshipsHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	relationships, err := cc.Service.ListForDoctor(c.UserContext(), doctorID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(relationships)
+}
+
+// PatientRelationshipsHandler lists the doctors currently treating the patient
+// and those the patient has stopped
+func (cc *CareRelationshipController) PatientRelationshipsHandler(c *fiber.Ctx) error {
+	patientID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	relationships, err := cc.Service.ListForPatient(c.UserContext(), patientID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(relationships)
+}
+
+// EndHandler ends a relationship; either its doctor or its patient may call it
+func (cc *CareRelationshipController) EndHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	if err := cc.Service.End(c.UserContext(), userID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Care relationship ended"})
+}
+
+// AllowHandler lets a doctor the patient stopped treat them again after another
+// scan of their card
+func (cc *CareRelationshipController) AllowHandler(c *fiber.Ctx) error {
+	patientID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	if err := cc.Service.Allow(c.UserContext(), patientID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Doctor allowed again"})
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ConsentController lets patients manage who may access their records
+type ConsentController struct {
+	Repo    *routes.Repository
+	Service *services.ConsentService
+}
+
+// NewConsentController creates a new ConsentController
+func NewConsentController(repo *routes.Repository) *ConsentController {
+	service := services.NewConsentService(repo.Firestore)
+	return &ConsentController{Repo: repo, Service: service}
+}
+
+// GrantConsentHandler gives a practitioner or organization access to parts of the
+// patient's record, optionally until expires_at (RFC 3339)
+func (cc *ConsentController) GrantConsentHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		GranteeID string     `json:"grantee_id" validate:"required"`
+		Scopes    []string   `json:"scopes" validate:"required,unique"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	consent, err := cc.Service.GrantConsent(c.UserContext(), userID, req.GranteeID, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(consent)
+}
+
+// ListConsentsHandler returns the consents the patient has granted
+func (cc *ConsentController) ListConsentsHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	consents, err := cc.Service.ListConsents(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(consents)
+}
+
+// RevokeConsentHandler withdraws one of the patient's consents
+func (cc *ConsentController) RevokeConsentHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	if err := cc.Service.RevokeConsent(c.UserContext(), userID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Consent revoked"})
+}
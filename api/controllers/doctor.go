@@ -1,8 +1,15 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
 	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -14,32 +21,42 @@ type DoctorController struct {
 
 // NewDoctorController creates a new DoctorController
 func NewDoctorController(repo *routes.Repository) *DoctorController {
-	service := services.NewDoctorService(repo.Firestore, repo.IPFS)
+	service := services.NewDoctorService(repo.Firestore, repo.Storage)
 	return &DoctorController{Repo: repo, Service: service}
 }
 
 func (dc *DoctorController) GetPatientHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
 	nfcID := c.Params("nfc_id")
-	patient, err := dc.Service.GetPatientByNFC(nfcID)
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), dc.Repo.Firestore, nfcID)...)
+	patient, err := dc.Service.GetPatientByNFC(c.UserContext(), doctorID, nfcID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(patient)
 }
 
 func (dc *DoctorController) CreatePrescriptionHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
 	type Request struct {
-		PatientID  string `json:"patient_id"`
-		Medication string `json:"medication"`
-		Dosage     uint64 `json:"dosage"`
+		PatientID  string `json:"patient_id" validate:"required"`
+		Medication string `json:"medication" validate:"required,max=200"`
+		Dosage     uint64 `json:"dosage" validate:"min=1"`
 	}
 	var req Request
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
-	prescriptionID, err := dc.Service.CreatePrescription(req.PatientID, req.Medication, req.Dosage)
+	middleware.AuditPatients(c, req.PatientID)
+	prescriptionID, err := dc.Service.CreatePrescription(c.UserContext(), doctorID, req.PatientID, req.Medication, req.Dosage)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	if prescriptionID == "" {
 		return c.JSON(fiber.Map{"message": "Prescription creation not implemented yet—waiting for blockchain"})
@@ -47,29 +64,252 @@ func (dc *DoctorController) CreatePrescriptionHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"prescription_id": prescriptionID})
 }
 
+// AddMedicalHistoryHandler adds a typed entry to a patient's history. A bare
+// history string from older clients is stored as a note.
 func (dc *DoctorController) AddMedicalHistoryHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		PatientID string          `json:"patient_id" validate:"required"`
+		Type      string          `json:"type"`
+		Data      json.RawMessage `json:"data"`
+		History   string          `json:"history"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Type == "" && req.History != "" {
+		req.Type = models.HistoryTypeNote
+		req.Data, _ = json.Marshal(fiber.Map{"text": req.History})
+	}
+	middleware.AuditPatients(c, req.PatientID)
+	docID, err := dc.Service.AddMedicalHistory(c.UserContext(), doctorID, req.PatientID, req.Type, req.Data, dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"doc_id": docID})
+}
+
+// AmendMedicalHistoryHandler replaces a history entry with a corrected version
+func (dc *DoctorController) AmendMedicalHistoryHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		Type   string          `json:"type" validate:"required"`
+		Data   json.RawMessage `json:"data" validate:"required"`
+		Reason string          `json:"reason" validate:"required,max=500"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, dc.historyPatients(c)...)
+	record, err := dc.Service.AmendMedicalHistory(c.UserContext(), doctorID, c.Params("id"), req.Type, req.Data, req.Reason, dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"doc_id": record.ID, "version": record.Version})
+}
+
+// RetractMedicalHistoryHandler withdraws a history entry recorded in error
+func (dc *DoctorController) RetractMedicalHistoryHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		Reason string `json:"reason" validate:"required,max=500"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, dc.historyPatients(c)...)
+	record, err := dc.Service.RetractMedicalHistory(c.UserContext(), doctorID, c.Params("id"), req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"doc_id": record.ID, "version": record.Version, "retracted": true})
+}
+
+// MedicalHistoryVersionsHandler returns every version of a history entry of a
+// patient the doctor is treating
+func (dc *DoctorController) MedicalHistoryVersionsHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	middleware.AuditPatients(c, dc.historyPatients(c)...)
+	versions, err := dc.Service.GetMedicalHistoryVersions(c.UserContext(), doctorID, c.Params("id"), dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(versions)
+}
+
+// HistorySchemasHandler returns the JSON schema of each medical history entry type
+func (dc *DoctorController) HistorySchemasHandler(c *fiber.Ctx) error {
+	return c.JSON(services.HistorySchemas())
+}
+
+// AddEncryptedMedicalHistoryHandler stores a history entry encrypted on the doctor's device
+func (dc *DoctorController) AddEncryptedMedicalHistoryHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
 	type Request struct {
-		PatientID string `json:"patient_id"`
-		History   string `json:"history"`
+		PatientID   string                       `json:"patient_id" validate:"required"`
+		Ciphertext  []byte                       `json:"ciphertext" validate:"required"`
+		WrappedKeys map[string]models.WrappedKey `json:"wrapped_keys" validate:"required"`
 	}
 	var req Request
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
-	// Temporary key—replace with real key management
-	key := []byte("32-byte-key-here-1234567890123456")
-	docID, err := dc.Service.AddMedicalHistory(req.PatientID, req.History, key)
+	middleware.AuditPatients(c, req.PatientID)
+	docID, err := dc.Service.AddEncryptedMedicalHistory(c.UserContext(), doctorID, req.PatientID, req.Ciphertext, req.WrappedKeys)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 	return c.JSON(fiber.Map{"doc_id": docID})
 }
 
+// EncryptedMedicalHistoryHandler returns the end-to-end entries shared with this doctor
+func (dc *DoctorController) EncryptedMedicalHistoryHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	middleware.AuditPatients(c, c.Params("patient_id"))
+	entries, err := dc.Service.GetEncryptedMedicalHistory(c.UserContext(), doctorID, c.Params("patient_id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(entries)
+}
+
+// AddAttachmentHandler streams a raw file body into the patient's encrypted attachments.
+// The file is sent as the request body, with patient_id and file_name as query parameters.
+func (dc *DoctorController) AddAttachmentHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Query struct {
+		PatientID string `query:"patient_id" validate:"required"`
+		FileName  string `query:"file_name" validate:"required,max=255"`
+	}
+	var query Query
+	if err := parseQuery(c, &query); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, query.PatientID)
+
+	// Read straight from the connection when request body streaming is enabled
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	docID, err := dc.Service.AddAttachment(c.UserContext(), doctorID, query.PatientID, query.FileName, c.Get(fiber.HeaderContentType, fiber.MIMEOctetStream), body, dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"attachment_id": docID})
+}
+
+// PatientProfileHandler returns the clinical profile of a patient the doctor is treating
+func (dc *DoctorController) PatientProfileHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	patientID := c.Params("patient_id")
+	middleware.AuditPatients(c, patientID)
+	profile, err := dc.Service.GetPatientProfile(c.UserContext(), doctorID, patientID, dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(profile)
+}
+
+// SavePatientProfileHandler creates or replaces the clinical profile of a
+// patient the doctor is treating
+func (dc *DoctorController) SavePatientProfileHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	var profile models.PatientProfile
+	if err := parseBody(c, &profile); err != nil {
+		return err
+	}
+	patientID := c.Params("patient_id")
+	middleware.AuditPatients(c, patientID)
+	saved, err := dc.Service.SavePatientProfile(c.UserContext(), doctorID, patientID, &profile, dc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(saved)
+}
+
+// SearchPatientsHandler searches the doctor's patients, ordered by name. Filters:
+// name (word prefixes), date_of_birth (YYYY-MM-DD) and nfc_id; paged with cursor
+// and limit.
 func (dc *DoctorController) SearchPatientsHandler(c *fiber.Ctx) error {
-	name := c.Query("name")
-	patients, err := dc.Service.SearchPatients(name)
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	query := models.PatientSearchQuery{
+		Name:        c.Query("name"),
+		DateOfBirth: c.Query("date_of_birth"),
+		NFCID:       c.Query("nfc_id"),
+		Cursor:      c.Query("cursor"),
+		Limit:       c.QueryInt("limit"),
+	}
+	result, err := dc.Service.SearchPatients(c.UserContext(), doctorID, query)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	return c.JSON(patients)
+	for _, patient := range result.Items {
+		middleware.AuditPatients(c, patient.UID)
+	}
+	return c.JSON(result)
+}
+
+// MatchPatientsHandler ranks the doctor's patients by how closely their names are
+// spelled or sound like name, boosting those born on date_of_birth (YYYY-MM-DD)
+func (dc *DoctorController) MatchPatientsHandler(c *fiber.Ctx) error {
+	doctorID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	query := models.PatientMatchQuery{
+		Name:        c.Query("name"),
+		DateOfBirth: c.Query("date_of_birth"),
+		Limit:       c.QueryInt("limit"),
+	}
+	if query.Name == "" {
+		return errs.Invalid("name is required")
+	}
+	matches, err := dc.Service.MatchPatients(c.UserContext(), doctorID, query)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		middleware.AuditPatients(c, match.PatientID)
+	}
+	return c.JSON(matches)
+}
+
+// historyPatients returns the patient owning the history entry in the route's :id
+func (dc *DoctorController) historyPatients(c *fiber.Ctx) []string {
+	return services.PatientsForDocument(c.UserContext(), dc.Repo.Firestore, "medical_history", c.Params("id"), "user_id")
 }
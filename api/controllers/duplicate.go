@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DuplicateController lets administrators review possible duplicate patients
+// and merge them
+type DuplicateController struct {
+	Repo    *routes.Repository
+	Service *services.DuplicateService
+}
+
+// NewDuplicateController creates a new DuplicateController
+func NewDuplicateController(repo *routes.Repository) *DuplicateController {
+	service := services.NewDuplicateService(repo.Firestore, repo.Blockchain)
+	return &DuplicateController{Repo: repo, Service: service}
+}
+
+// ListHandler returns the review queue; status defaults to pending
+func (dc *DuplicateController) ListHandler(c *fiber.Ctx) error {
+	candidates, err := dc.Service.ListCandidates(c.UserContext(), c.Query("status"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(candidates)
+}
+
+// ScanHandler checks one patient for duplicates when patient_id is given,
+// otherwise every patient
+func (dc *DuplicateController) ScanHandler(c *fiber.Ctx) error {
+	if patientID := c.Query("patient_id"); patientID != "" {
+		middleware.AuditPatients(c, patientID)
+		candidates, err := dc.Service.DetectDuplicates(c.UserContext(), patientID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"flagged": len(candidates)})
+	}
+	flagged, err := dc.Service.Scan(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"flagged": flagged})
+}
+
+// MergeHandler merges a queued pair into the patient named by survivor_id
+func (dc *DuplicateController) MergeHandler(c *fiber.Ctx) error {
+	adminID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		SurvivorID string `json:"survivor_id" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, services.PatientsForDocument(c.UserContext(), dc.Repo.Firestore, "duplicate_candidates", c.Params("id"), "patient_ids")...)
+	merge, err := dc.Service.Merge(c.UserContext(), adminID, c.Params("id"), req.SurvivorID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(merge)
+}
+
+// DismissHandler records that a queued pair are different people
+func (dc *DuplicateController) DismissHandler(c *fiber.Ctx) error {
+	adminID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	if err := dc.Service.Dismiss(c.UserContext(), adminID, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Duplicate candidate dismissed"})
+}
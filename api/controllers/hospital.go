@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
 	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,17 +16,117 @@ type HospitalController struct {
 }
 
 func NewHospitalController(repo *routes.Repository) *HospitalController {
-	service := services.NewHospitalService(repo.Firestore, repo.IPFS)
+	service := services.NewHospitalService(repo.Firestore, repo.Storage)
 	return &HospitalController{Repo: repo, Service: service}
 }
 
 func (hc *HospitalController) PatientDataHandler(c *fiber.Ctx) error {
+	hospitalID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
 	nfcID := c.Params("nfc_id")
-	// Temporary key—replace with real key management
-	key := []byte("32-byte-key-here-1234567890123456")
-	data, err := hc.Service.GetPatientData(nfcID, key)
+	// OneTimeAccess has already named the patient for the audit log
+	data, err := hc.Service.GetPatientData(c.UserContext(), hospitalID, nfcID, hc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	// Tie the response to the grant redeemed by OneTimeAccess
+	if grant, ok := c.Locals("accessGrant").(*models.AccessGrant); ok {
+		data.AccessID = grant.ID
+	}
+	return c.JSON(data)
+}
+
+// EmergencySummaryHandler returns a patient's emergency summary; with
+// format=text only the compact text is returned, for printing or a QR code
+func (hc *HospitalController) EmergencySummaryHandler(c *fiber.Ctx) error {
+	hospitalID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), hc.Repo.Firestore, c.Params("nfc_id"))...)
+	summary, err := hc.Service.GetEmergencySummary(c.UserContext(), hospitalID, c.Params("nfc_id"), hc.Repo.DataKey)
+	if err != nil {
+		return err
+	}
+	return sendEmergencySummary(c, summary)
+}
+
+// BreakGlassRequestHandler opens an emergency access request for an unconscious patient
+func (hc *HospitalController) BreakGlassRequestHandler(c *fiber.Ctx) error {
+	hospitalID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		NFCID  string `json:"nfc_id" validate:"required"`
+		Reason string `json:"reason" validate:"required,max=500"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), hc.Repo.Firestore, req.NFCID)...)
+	request, err := hc.Service.RequestBreakGlass(c.UserContext(), hospitalID, req.NFCID, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(request)
+}
+
+// BreakGlassShareHandler returns the calling custodian's share for a request,
+// wrapped to their public key
+func (hc *HospitalController) BreakGlassShareHandler(c *fiber.Ctx) error {
+	custodianID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	middleware.AuditPatients(c, hc.breakGlassPatients(c)...)
+	share, err := hc.Service.GetEscrowShare(c.UserContext(), c.Params("id"), custodianID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return err
+	}
+	return c.JSON(share)
+}
+
+// BreakGlassApproveHandler lets a custodian approve a request by submitting their
+// unwrapped share
+func (hc *HospitalController) BreakGlassApproveHandler(c *fiber.Ctx) error {
+	custodianID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		Share string `json:"share" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	middleware.AuditPatients(c, hc.breakGlassPatients(c)...)
+	request, err := hc.Service.ApproveBreakGlass(c.UserContext(), c.Params("id"), custodianID, req.Share)
+	if err != nil {
+		return err
+	}
+	return c.JSON(request)
+}
+
+// BreakGlassRedeemHandler returns the patient's data once a quorum has approved
+func (hc *HospitalController) BreakGlassRedeemHandler(c *fiber.Ctx) error {
+	hospitalID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	middleware.AuditPatients(c, hc.breakGlassPatients(c)...)
+	data, err := hc.Service.RedeemBreakGlass(c.UserContext(), c.Params("id"), hospitalID, hc.Repo.DataKey)
+	if err != nil {
+		return err
 	}
 	return c.JSON(data)
 }
+
+// breakGlassPatients returns the patient of the break-glass request in the route's :id
+func (hc *HospitalController) breakGlassPatients(c *fiber.Ctx) []string {
+	return services.PatientsForDocument(c.UserContext(), hc.Repo.Firestore, "break_glass_requests", c.Params("id"), "patient_id")
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// KeyController handles public keys and grants for end-to-end encrypted records
+type KeyController struct {
+	Repo    *routes.Repository
+	Service *services.KeyService
+}
+
+// NewKeyController creates a new KeyController
+func NewKeyController(repo *routes.Repository) *KeyController {
+	service := services.NewKeyService(repo.Firestore)
+	return &KeyController{Repo: repo, Service: service}
+}
+
+// PublishPublicKeyHandler stores the caller's X25519 public key (base64 in JSON)
+func (kc *KeyController) PublishPublicKeyHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		PublicKey []byte `json:"public_key" validate:"required,len=32"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if err := kc.Service.PublishPublicKey(c.UserContext(), userID, req.PublicKey); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Public key published"})
+}
+
+// GetPublicKeyHandler returns another user's public key so records can be wrapped for them
+func (kc *KeyController) GetPublicKeyHandler(c *fiber.Ctx) error {
+	key, err := kc.Service.GetPublicKey(c.UserContext(), c.Params("uid"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(key)
+}
+
+// GrantHistoryAccessHandler stores a data key the patient re-wrapped for another practitioner
+func (kc *KeyController) GrantHistoryAccessHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		RecipientID string            `json:"recipient_id" validate:"required"`
+		WrappedKey  models.WrappedKey `json:"wrapped_key" validate:"required"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if err := kc.Service.GrantHistoryAccess(c.UserContext(), userID, c.Params("id"), req.RecipientID, req.WrappedKey); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Access granted"})
+}
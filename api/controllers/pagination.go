@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parsePageQuery reads the options shared by list endpoints: cursor, limit,
+// from, to and sort
+func parsePageQuery(c *fiber.Ctx) (models.PageQuery, error) {
+	q := models.PageQuery{
+		Cursor: c.Query("cursor"),
+		Limit:  c.QueryInt("limit"),
+		Sort:   c.Query("sort"),
+	}
+	var err error
+	q.From, q.To, err = parseTimeRange(c)
+	return q, err
+}
+
+// parseTimeRange reads the optional from and to query parameters as RFC 3339
+// timestamps or YYYY-MM-DD dates. A date in to includes that whole day.
+func parseTimeRange(c *fiber.Ctx) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = parseTimeBound(value, false); err != nil {
+			return from, to, errs.Invalid("from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = parseTimeBound(value, true); err != nil {
+			return from, to, errs.Invalid("to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	return from, to, nil
+}
+
+// parseTimeBound parses one end of a time range; an end date means the start of
+// the following day
+func parseTimeBound(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return day, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
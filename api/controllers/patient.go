@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"context"
+
 	"github.com/Frhnmj2004/hippocard-server/api/routes"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
 
@@ -12,18 +16,26 @@ import (
 // PatientController handles patient-related endpoints
 type PatientController struct {
 	PatientService *services.PatientService
+	AccessLog      *services.AccessLogService
+	Profiles       *services.PatientProfileService
+	Summaries      *services.EmergencySummaryService
 	AuthClient     *firebase.AuthClient
 	Firestore      *firebase.FirestoreClient
-	IPFS           *storage.IPFSClient
+	Storage        storage.BlobStore
+	DataKey        []byte
 }
 
 // NewPatientController initializes a new PatientController with the repository
 func NewPatientController(repo *routes.Repository) *PatientController {
 	return &PatientController{
-		PatientService: services.NewPatientService(repo.Firestore, repo.IPFS),
+		PatientService: services.NewPatientService(repo.Firestore, repo.Storage),
+		AccessLog:      services.NewAccessLogService(repo.Firestore, services.NewAuditService(repo.Audit)),
+		Profiles:       services.NewPatientProfileService(repo.Firestore),
+		Summaries:      services.NewEmergencySummaryService(repo.Firestore),
 		AuthClient:     repo.Auth,
 		Firestore:      repo.Firestore,
-		IPFS:           repo.IPFS,
+		Storage:        repo.Storage,
+		DataKey:        repo.DataKey,
 	}
 }
 
@@ -31,35 +43,229 @@ func NewPatientController(repo *routes.Repository) *PatientController {
 func (pc *PatientController) ProfileHandler(c *fiber.Ctx) error {
 	userID, ok := c.Locals("userID").(string)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		return errs.Unauthorized("Unauthorized")
 	}
 
-	user, err := firebase.GetUserByUID(pc.Firestore.Client, userID)
+	user, err := firebase.GetUserByUID(c.UserContext(), pc.Firestore.Client, userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(user)
 }
 
+// ClinicalProfileHandler returns the patient's demographic and clinical profile
+func (pc *PatientController) ClinicalProfileHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	profile, err := pc.Profiles.GetProfile(c.UserContext(), userID, pc.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(profile)
+}
+
+// SaveClinicalProfileHandler creates or replaces the patient's clinical profile
+func (pc *PatientController) SaveClinicalProfileHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	var profile models.PatientProfile
+	if err := parseBody(c, &profile); err != nil {
+		return err
+	}
+
+	saved, err := pc.Profiles.SaveProfile(c.UserContext(), userID, userID, &profile, pc.DataKey)
+	if err != nil {
+		return err
+	}
+	return c.JSON(saved)
+}
+
+// DeleteClinicalProfileHandler erases the patient's clinical profile
+func (pc *PatientController) DeleteClinicalProfileHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	if err := pc.Profiles.DeleteProfile(c.UserContext(), userID); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"message": "Profile erased"})
+}
+
+// EmergencySummaryHandler returns the patient's own emergency summary, for a
+// printed card; with format=text only the compact text is returned
+func (pc *PatientController) EmergencySummaryHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	summary, err := pc.Summaries.GetSummary(c.UserContext(), userID, pc.DataKey)
+	if err != nil {
+		return err
+	}
+	return sendEmergencySummary(c, summary)
+}
+
+// sendEmergencySummary writes a summary as JSON, or as its compact text when
+// the format query parameter is "text"
+func sendEmergencySummary(c *fiber.Ctx, summary *models.EmergencySummary) error {
+	if c.Query("format") == "text" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(summary.Compact)
+	}
+	return c.JSON(summary)
+}
+
 // PrescriptionsHandler returns the patient's prescriptions (placeholder until blockchain)
 func (pc *PatientController) PrescriptionsHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Prescriptions not implemented yet—waiting for blockchain"})
 }
 
-// MedicalHistoryHandler returns the patient's medical history
+// MedicalHistoryHandler returns a page of the patient's medical history. Takes
+// the page options (sort by created_at, newest first by default).
 func (pc *PatientController) MedicalHistoryHandler(c *fiber.Ctx) error {
 	userID, ok := c.Locals("userID").(string)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		return errs.Unauthorized("Unauthorized")
+	}
+	page, err := parsePageQuery(c)
+	if err != nil {
+		return err
 	}
 
-	// Simulate a key for decryption (replace with actual key management)
-	key := []byte("your-32-byte-encryption-key-here") // Use a secure key from crypto package
-	history, err := pc.PatientService.GetMedicalHistory(userID, key)
+	history, err := pc.PatientService.GetMedicalHistory(c.UserContext(), userID, page, pc.DataKey)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(history)
 }
+
+// MedicalHistoryVersionsHandler returns every version of one of the patient's
+// history entries, with who amended it and why
+func (pc *PatientController) MedicalHistoryVersionsHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	versions, err := pc.PatientService.GetMedicalHistoryVersions(c.UserContext(), userID, c.Params("id"), pc.DataKey)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(versions)
+}
+
+// EncryptedMedicalHistoryHandler returns the patient's end-to-end entries for local decryption
+func (pc *PatientController) EncryptedMedicalHistoryHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	entries, err := pc.PatientService.GetEncryptedMedicalHistory(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(entries)
+}
+
+// AttachmentHandler streams a decrypted attachment back to the patient
+func (pc *PatientController) AttachmentHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	// The body is streamed after this handler returns and the request context is
+	// cancelled, so the stream keeps the request's values but not its lifetime
+	ctx := context.WithoutCancel(c.UserContext())
+	attachment, stream, err := pc.PatientService.GetAttachment(ctx, userID, c.Params("id"), pc.DataKey)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Attachment(attachment.FileName)
+	return c.SendStream(stream)
+}
+
+// KeyEscrowHandler splits the patient's data key among emergency custodians. The
+// shares go to the custodians, so only the escrow's details are returned.
+func (pc *PatientController) KeyEscrowHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+	type Request struct {
+		Custodians []string `json:"custodians" validate:"required,min=2,max=255,unique"`
+		Threshold  int      `json:"threshold" validate:"min=2"`
+	}
+	var req Request
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	escrow, err := pc.PatientService.EscrowKey(c.UserContext(), userID, pc.DataKey, req.Custodians, req.Threshold)
+	if err != nil {
+		return err
+	}
+	return c.JSON(escrow)
+}
+
+// AccessLogHandler lists the doctors, pharmacists and hospitals that accessed the
+// patient's data. Filters: role, plus the page options (sort by time).
+func (pc *PatientController) AccessLogHandler(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	page, err := parsePageQuery(c)
+	if err != nil {
+		return err
+	}
+	query := models.AuditQuery{
+		ActorRole: c.Query("role"),
+		PageQuery: page,
+	}
+
+	accesses, err := pc.AccessLog.GetAccessLog(c.UserContext(), userID, query)
+	if err != nil {
+		return err
+	}
+	return c.JSON(accesses)
+}
+
+// EraseMedicalHistoryHandler erases one of the patient's history entries
+func (pc *PatientController) EraseMedicalHistoryHandler(c *fiber.Ctx) error {
+	return pc.eraseRecord(c, "medical_history")
+}
+
+// EraseAttachmentHandler erases one of the patient's attachments
+func (pc *PatientController) EraseAttachmentHandler(c *fiber.Ctx) error {
+	return pc.eraseRecord(c, "attachment")
+}
+
+func (pc *PatientController) eraseRecord(c *fiber.Ctx, recordType string) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok {
+		return errs.Unauthorized("Unauthorized")
+	}
+
+	if err := pc.PatientService.EraseRecord(c.UserContext(), userID, recordType, c.Params("id")); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{"message": "Record erased"})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
 	"github.com/Frhnmj2004/hippocard-server/api/routes"
 	"github.com/Frhnmj2004/hippocard-server/internals/services"
 
@@ -17,28 +18,33 @@ func NewPharmacistController(repo *routes.Repository) *PharmacistController {
 	return &PharmacistController{Repo: repo, Service: service}
 }
 
+// ActivePrescriptionsHandler returns a page of the active prescriptions of the
+// patient with the card. Takes the page options (sort by created_at, newest
+// first by default).
 func (pc *PharmacistController) ActivePrescriptionsHandler(c *fiber.Ctx) error {
-	nfcID := c.Params("nfc_id")
-	prescriptions, err := pc.Service.GetActivePrescriptions(nfcID)
+	page, err := parsePageQuery(c)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	if len(prescriptions) == 0 {
-		return c.JSON(fiber.Map{"message": "No active prescriptions found"})
+	nfcID := c.Params("nfc_id")
+	middleware.AuditPatients(c, services.PatientsForNFC(c.UserContext(), pc.Repo.Firestore, nfcID)...)
+	prescriptions, err := pc.Service.GetActivePrescriptions(c.UserContext(), nfcID, page)
+	if err != nil {
+		return err
 	}
 	return c.JSON(prescriptions)
 }
 
 func (pc *PharmacistController) DispensePrescriptionHandler(c *fiber.Ctx) error {
 	type Request struct {
-		TokenID string `json:"token_id"`
+		TokenID string `json:"token_id" validate:"required"`
 	}
 	var req Request
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
-	if err := pc.Service.DispensePrescription(req.TokenID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	if err := pc.Service.DispensePrescription(c.UserContext(), req.TokenID); err != nil {
+		return err
 	}
 	return c.JSON(fiber.Map{"message": "Prescription dispensed"})
 }
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/validate"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseBody decodes the request body into req and checks it against the rules
+// in req's validate tags, so a handler only sees well-formed requests
+func parseBody(c *fiber.Ctx, req any) error {
+	if err := c.BodyParser(req); err != nil {
+		return errs.Invalid("Invalid request body")
+	}
+	return validate.Struct(req)
+}
+
+// parseQuery decodes the query string into req, by its query tags, and checks
+// it like parseBody
+func parseQuery(c *fiber.Ctx, req any) error {
+	if err := c.QueryParser(req); err != nil {
+		return errs.Invalid("Invalid query parameters")
+	}
+	return validate.Struct(req)
+}
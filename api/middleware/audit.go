@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// AccessPurposeHeader lets the caller state why they are accessing patient data
+	AccessPurposeHeader = "X-Access-Purpose"
+
+	// EmergencyOverrideHeader gives the reason for reading a patient's data
+	// without their consent in an emergency
+	EmergencyOverrideHeader = "X-Emergency-Override"
+)
+
+const auditPatientsKey = "auditPatientIDs"
+
+// Audit records every request in the group to the audit log once the handler
+// has run, with the caller, the patients involved and the outcome. It goes after
+// AuthMiddleware, so requests without a valid token cannot flood the log or
+// trip its fail-closed response. Patients' own routes are
+// attributed to the caller; other handlers name the patients they touched with
+// AuditPatients. If the entry cannot be written the response is replaced with an
+// error, so no access goes unrecorded. Accesses that relied on an emergency
+// override of consent are flagged.
+func Audit(audit *services.AuditService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Step 1: Run the handler, unless it carries an unusable emergency override,
+		// and let Fiber turn any returned error into a response
+		ctx, emergency, err := services.WithEmergencyAccess(c.UserContext(), c.Get(EmergencyOverrideHeader), auditAction(c.Method()) == "read")
+		c.SetUserContext(ctx)
+		if err == nil {
+			err = c.Next()
+		}
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				return handlerErr
+			}
+		}
+
+		// Step 2: Describe the request
+		actorID, _ := c.Locals("userID").(string)
+		role, _ := c.Locals("role").(string)
+		status := c.Response().StatusCode()
+		entry := &models.AuditEntry{
+			ActorID:    actorID,
+			ActorRole:  role,
+			PatientIDs: auditedPatients(c),
+			NFCID:      c.Params("nfc_id"),
+			Resource:   auditResource(c.Route().Path),
+			Action:     auditAction(c.Method()),
+			Endpoint:   c.Method() + " " + c.Route().Path,
+			Purpose:    c.Get(AccessPurposeHeader),
+			Emergency:  emergency.Used(),
+			Outcome:    auditOutcome(status),
+			StatusCode: status,
+			RequestIP:  c.IP(),
+		}
+		if role == "patient" && len(entry.PatientIDs) == 0 {
+			entry.PatientIDs = []string{actorID}
+		}
+		if entry.Emergency && entry.Purpose == "" {
+			entry.Purpose = emergency.Reason
+		}
+
+		// Step 3: Fail closed if the access cannot be recorded
+		if err := audit.Record(c.UserContext(), entry); err != nil {
+			log.Printf("Withholding response for %s: audit log unavailable: %v", entry.Endpoint, err)
+			return fiber.NewError(fiber.StatusServiceUnavailable, "Audit log unavailable")
+		}
+		return nil
+	}
+}
+
+// AuditPatients names patients whose data the current request reads or changes
+func AuditPatients(c *fiber.Ctx, patientIDs ...string) {
+	ids, _ := c.Locals(auditPatientsKey).([]string)
+	for _, id := range patientIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	c.Locals(auditPatientsKey, ids)
+}
+
+// auditedPatients returns the patients named by the handler, without duplicates
+func auditedPatients(c *fiber.Ctx) []string {
+	ids, _ := c.Locals(auditPatientsKey).([]string)
+	seen := make(map[string]bool, len(ids))
+	unique := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// auditResource classifies a route by the kind of patient data it serves
+func auditResource(path string) string {
+	switch {
+	case strings.Contains(path, "prescription"):
+		return "prescriptions"
+	case strings.Contains(path, "medical-history"):
+		return "medical_history"
+	case strings.Contains(path, "attachment"):
+		return "attachments"
+	case strings.Contains(path, "break-glass"):
+		return "break_glass"
+	case strings.Contains(path, "access-grants"):
+		return "access_grants"
+	case strings.Contains(path, "access-log"), strings.Contains(path, "audit-log"):
+		return "audit_log"
+	case strings.Contains(path, "consent"):
+		return "consents"
+	case strings.Contains(path, "care-relationships"):
+		return "care_relationships"
+	case strings.Contains(path, "emergency-summary"):
+		return "emergency_summary"
+	case strings.Contains(path, "duplicates"):
+		return "duplicate_candidates"
+	case strings.Contains(path, "key"):
+		return "keys"
+	case strings.Contains(path, "profile"), strings.Contains(path, "patient"):
+		return "profile"
+	default:
+		return "other"
+	}
+}
+
+// auditAction maps an HTTP method to what it does to the data
+func auditAction(method string) string {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead:
+		return "read"
+	case fiber.MethodDelete:
+		return "delete"
+	default:
+		return "write"
+	}
+}
+
+// auditOutcome summarises a response status
+func auditOutcome(status int) string {
+	switch {
+	case status < 400:
+		return "success"
+	case status == fiber.StatusUnauthorized, status == fiber.StatusForbidden:
+		return "denied"
+	default:
+		return "error"
+	}
+}
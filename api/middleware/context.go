@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestContext gives each request a context, available through c.UserContext(),
+// that services pass to every Firestore, storage, blockchain and auth call. It is
+// cancelled when the handler returns or after timeout, whichever comes first, so
+// work for an abandoned or overrunning request stops instead of running on.
+// Mounted again on a route, it replaces the context's deadline with the route's
+// own timeout, e.g. for uploads that outlast the usual one, keeping any values
+// earlier middleware attached.
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parent := context.WithoutCancel(c.UserContext())
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(parent, timeout)
+		} else {
+			ctx, cancel = context.WithCancel(parent)
+		}
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
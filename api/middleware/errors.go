@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader carries the ID that ties a response to the server's logs
+const RequestIDHeader = fiber.HeaderXRequestID
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type      string `json:"type"`   // Always "about:blank": the status says what went wrong
+	Title     string `json:"title"`  // The status text
+	Status    int    `json:"status"` // The HTTP status code
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`             // The request path
+	Code      string `json:"code"`                 // Machine-readable kind, e.g. "not_found"
+	RequestID string `json:"request_id,omitempty"` // Quote it when reporting a problem
+
+	Errors []errs.FieldError `json:"errors,omitempty"` // Each invalid field of the request
+}
+
+// RequestID tags each request with an ID, taken from the caller's X-Request-ID
+// header when present, and echoes it in the response
+func RequestID() fiber.Handler {
+	return requestid.New(requestid.Config{Header: RequestIDHeader})
+}
+
+// ErrorHandler is the app's error handler: it answers every error returned by a
+// handler or middleware with problem JSON. Clients see only the message of a
+// typed error; anything else is logged with the request ID and reported as an
+// internal error.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	problem := describe(err)
+	problem.Type = "about:blank"
+	problem.Title = utils.StatusMessage(problem.Status)
+	problem.Instance = c.Path()
+	problem.RequestID, _ = c.Locals(requestid.ConfigDefault.ContextKey).(string)
+
+	if problem.Status >= fiber.StatusInternalServerError {
+		log.Printf("Request %s %s %s failed: %v", problem.RequestID, c.Method(), c.Path(), err)
+	}
+
+	c.Status(problem.Status)
+	if err := c.JSON(problem); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "application/problem+json")
+	return nil
+}
+
+// describe picks the status, code and client-safe detail for an error
+func describe(err error) Problem {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return Problem{Status: fiberErr.Code, Code: statusCode(fiberErr.Code), Detail: fiberErr.Message}
+	}
+
+	if message, ok := errs.Message(err); ok {
+		var typed *errs.Error
+		errors.As(err, &typed)
+		for _, kind := range []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{errs.ErrInvalid, fiber.StatusBadRequest, "invalid"},
+			{errs.ErrUnauthorized, fiber.StatusUnauthorized, "unauthorized"},
+			{errs.ErrForbidden, fiber.StatusForbidden, "forbidden"},
+			{errs.ErrNotFound, fiber.StatusNotFound, "not_found"},
+			{errs.ErrConflict, fiber.StatusConflict, "conflict"},
+			{errs.ErrUpstream, fiber.StatusBadGateway, "upstream"},
+		} {
+			if errors.Is(err, kind.err) {
+				return Problem{Status: kind.status, Code: kind.code, Detail: message, Errors: typed.Fields}
+			}
+		}
+	}
+
+	// Untyped errors are mostly Firestore's, which carry a gRPC code
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+		return Problem{Status: fiber.StatusGatewayTimeout, Code: "timeout", Detail: "The request took too long"}
+	case status.Code(err) == codes.NotFound:
+		return Problem{Status: fiber.StatusNotFound, Code: "not_found", Detail: "Not found"}
+	case status.Code(err) == codes.Unavailable, status.Code(err) == codes.ResourceExhausted:
+		return Problem{Status: fiber.StatusServiceUnavailable, Code: "upstream", Detail: "A service the server depends on is unavailable"}
+	default:
+		return Problem{Status: fiber.StatusInternalServerError, Code: "internal", Detail: "Internal server error"}
+	}
+}
+
+// statusCode names the kind of a Fiber error by its status
+func statusCode(code int) string {
+	switch code {
+	case fiber.StatusBadRequest, fiber.StatusRequestEntityTooLarge, fiber.StatusUnprocessableEntity:
+		return "invalid"
+	case fiber.StatusUnauthorized:
+		return "unauthorized"
+	case fiber.StatusForbidden:
+		return "forbidden"
+	case fiber.StatusNotFound, fiber.StatusMethodNotAllowed:
+		return "not_found"
+	case fiber.StatusConflict:
+		return "conflict"
+	case fiber.StatusBadGateway, fiber.StatusServiceUnavailable:
+		return "upstream"
+	case fiber.StatusGatewayTimeout:
+		return "timeout"
+	default:
+		if code >= fiber.StatusInternalServerError {
+			return "internal"
+		}
+		return "invalid"
+	}
+}
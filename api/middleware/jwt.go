@@ -4,6 +4,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,39 +16,32 @@ func AuthMiddleware(authClient *firebase.AuthClient, requiredRole string) fiber.
 		// Get the Authorization header (e.g., "Bearer <token>")
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "No Authorization header provided",
-			})
+			return errs.Unauthorized("No Authorization header provided")
 		}
 
 		// Split into "Bearer" and token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid Authorization header format (use 'Bearer <token>')",
-			})
+			return errs.Unauthorized("Invalid Authorization header format (use 'Bearer <token>')")
 		}
 		token := parts[1]
 
 		// Verify token with Firebase Auth
-		verifiedToken, err := authClient.VerifyIDToken(token)
+		verifiedToken, err := authClient.VerifyIDToken(c.UserContext(), token)
 		if err != nil {
 			log.Printf("Token verification failed: %v", err)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
+			return errs.Unauthorized("Invalid or expired token")
 		}
 
 		// Check role from custom claims (assumes "role" is set in Firebase token)
 		role, ok := verifiedToken.Claims["role"].(string)
 		if !ok || role != requiredRole {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Insufficient permissions for this role",
-			})
+			return errs.Forbidden("Insufficient permissions for this role")
 		}
 
-		// Store user ID for handlers
+		// Store user ID and role for handlers and the audit log
 		c.Locals("userID", verifiedToken.UID)
+		c.Locals("role", role)
 
 		// Continue to the handler
 		return c.Next()
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// openAPISpec describes every route SetupRoutes registers; the contract test
+// in openapi_test.go keeps the two in step
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec returns the OpenAPI 3 document describing the API
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// openAPIHandler serves the OpenAPI document
+func openAPIHandler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(openAPISpec)
+}
@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// TestOpenAPISpecCoversRoutes checks that every route SetupRoutes registers is
+// described in openapi.json, and that the document describes no other routes
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(OpenAPISpec(), &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range setupTestRoutes().GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue // Fiber registers HEAD alongside every GET
+		}
+		registered[route.Method+" "+pathParam.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("%s is registered but missing from openapi.json", route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			t.Errorf("%s is in openapi.json but not registered", route)
+		}
+	}
+}
+
+// TestOpenAPIHandler checks that the document is served as JSON
+func TestOpenAPIHandler(t *testing.T) {
+	app := setupTestRoutes()
+	req, _ := http.NewRequest(fiber.MethodGet, "/api/openapi.json", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(got, fiber.MIMEApplicationJSON) {
+		t.Errorf("content type = %q, want JSON", got)
+	}
+}
+
+// setupTestRoutes registers every route with a stub handler. SetupRoutes takes
+// one handler per route, so they are passed by reflection to keep the test in
+// step as routes are added.
+func setupTestRoutes() *fiber.App {
+	app := fiber.New()
+	repo := NewRepository(nil, nil, nil, nil, nil, nil)
+	stub := reflect.ValueOf(func(c *fiber.Ctx) error { return nil })
+
+	setup := reflect.ValueOf(repo).MethodByName("SetupRoutes")
+	args := []reflect.Value{reflect.ValueOf(app)}
+	for i := 1; i < setup.Type().NumIn(); i++ {
+		args = append(args, stub)
+	}
+	setup.Call(args)
+	return app
+}
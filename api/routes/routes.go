@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/Frhnmj2004/hippocard-server/api/middleware"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
 	"github.com/Frhnmj2004/hippocard-server/pkg/blockchain"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
@@ -11,20 +14,25 @@ import (
 
 // Repository holds all clients and services for routing
 type Repository struct {
-	Auth       *firebase.AuthClient
-	Firestore  *firebase.FirestoreClient
-	Blockchain *blockchain.Client
-	IPFS       *storage.IPFSClient
-	App        *fiber.App
+	Auth          *firebase.AuthClient
+	Firestore     *firebase.FirestoreClient
+	Blockchain    *blockchain.Client
+	Storage       storage.BlobStore
+	Audit         *firebase.FirestoreClient // Audit log store, with create-only credentials in production
+	DataKey       []byte                    // Master key wrapping each patient's own data key
+	UploadTimeout time.Duration             // Request timeout for streamed uploads, which outlast the usual one
+	App           *fiber.App
 }
 
 // NewRepository initializes a new Repository
-func NewRepository(auth *firebase.AuthClient, firestore *firebase.FirestoreClient, blockchain *blockchain.Client, ipfs *storage.IPFSClient) *Repository {
+func NewRepository(auth *firebase.AuthClient, firestore *firebase.FirestoreClient, blockchain *blockchain.Client, store storage.BlobStore, audit *firebase.FirestoreClient, dataKey []byte) *Repository {
 	return &Repository{
 		Auth:       auth,
 		Firestore:  firestore,
 		Blockchain: blockchain,
-		IPFS:       ipfs,
+		Storage:    store,
+		Audit:      audit,
+		DataKey:    dataKey,
 	}
 }
 
@@ -33,37 +41,141 @@ func (r *Repository) SetupRoutes(app *fiber.App, loginHandler func(*fiber.Ctx) e
 	patientProfileHandler func(*fiber.Ctx) error,
 	patientPrescriptionsHandler func(*fiber.Ctx) error,
 	patientMedicalHistoryHandler func(*fiber.Ctx) error,
+	patientHistoryVersionsHandler func(*fiber.Ctx) error,
+	patientAttachmentHandler func(*fiber.Ctx) error,
+	patientKeyEscrowHandler func(*fiber.Ctx) error,
+	patientEncryptedHistoryHandler func(*fiber.Ctx) error,
+	patientGrantHistoryAccessHandler func(*fiber.Ctx) error,
+	patientEraseHistoryHandler func(*fiber.Ctx) error,
+	patientEraseAttachmentHandler func(*fiber.Ctx) error,
+	patientIssueAccessGrantHandler func(*fiber.Ctx) error,
+	patientAccessLogHandler func(*fiber.Ctx) error,
+	patientGrantConsentHandler func(*fiber.Ctx) error,
+	patientListConsentsHandler func(*fiber.Ctx) error,
+	patientRevokeConsentHandler func(*fiber.Ctx) error,
+	patientCareRelationshipsHandler func(*fiber.Ctx) error,
+	patientAllowCareHandler func(*fiber.Ctx) error,
+	patientClinicalProfileHandler func(*fiber.Ctx) error,
+	patientSaveClinicalProfileHandler func(*fiber.Ctx) error,
+	patientDeleteClinicalProfileHandler func(*fiber.Ctx) error,
+	patientEmergencySummaryHandler func(*fiber.Ctx) error,
 	doctorPatientHandler func(*fiber.Ctx) error,
 	doctorPrescriptionHandler func(*fiber.Ctx) error,
 	doctorMedicalHistoryHandler func(*fiber.Ctx) error,
+	doctorHistorySchemasHandler func(*fiber.Ctx) error,
+	doctorAmendHistoryHandler func(*fiber.Ctx) error,
+	doctorRetractHistoryHandler func(*fiber.Ctx) error,
+	doctorHistoryVersionsHandler func(*fiber.Ctx) error,
 	doctorSearchPatientsHandler func(*fiber.Ctx) error,
+	doctorMatchPatientsHandler func(*fiber.Ctx) error,
+	doctorPatientProfileHandler func(*fiber.Ctx) error,
+	doctorSavePatientProfileHandler func(*fiber.Ctx) error,
+	doctorAttachmentHandler func(*fiber.Ctx) error,
+	doctorEncryptedHistoryHandler func(*fiber.Ctx) error,
+	doctorAddEncryptedHistoryHandler func(*fiber.Ctx) error,
+	doctorIssueAccessGrantHandler func(*fiber.Ctx) error,
+	doctorEstablishCareHandler func(*fiber.Ctx) error,
+	doctorCareRelationshipsHandler func(*fiber.Ctx) error,
+	endCareRelationshipHandler func(*fiber.Ctx) error,
 	pharmacistActivePrescriptionsHandler func(*fiber.Ctx) error,
 	pharmacistDispenseHandler func(*fiber.Ctx) error,
-	hospitalPatientDataHandler func(*fiber.Ctx) error) {
+	hospitalPatientDataHandler func(*fiber.Ctx) error,
+	hospitalEmergencySummaryHandler func(*fiber.Ctx) error,
+	hospitalBreakGlassRequestHandler func(*fiber.Ctx) error,
+	hospitalBreakGlassShareHandler func(*fiber.Ctx) error,
+	hospitalBreakGlassApproveHandler func(*fiber.Ctx) error,
+	hospitalBreakGlassRedeemHandler func(*fiber.Ctx) error,
+	publishPublicKeyHandler func(*fiber.Ctx) error,
+	getPublicKeyHandler func(*fiber.Ctx) error,
+	adminAuditLogHandler func(*fiber.Ctx) error,
+	adminListDuplicatesHandler func(*fiber.Ctx) error,
+	adminScanDuplicatesHandler func(*fiber.Ctx) error,
+	adminMergeDuplicateHandler func(*fiber.Ctx) error,
+	adminDismissDuplicateHandler func(*fiber.Ctx) error) {
 	r.App = app
 
 	// Public routes
 	app.Post("/api/login", loginHandler)
+	app.Get("/api/openapi.json", openAPIHandler)
+
+	// Every authenticated request is recorded in the audit log
+	audit := middleware.Audit(services.NewAuditService(r.Audit))
 
 	// Patient routes
-	patient := app.Group("/api/patient", middleware.AuthMiddleware(r.Auth, "patient"))
+	patient := app.Group("/api/patient", middleware.AuthMiddleware(r.Auth, "patient"), audit)
 	patient.Get("/profile", patientProfileHandler)
 	patient.Get("/prescriptions", patientPrescriptionsHandler)
 	patient.Get("/medical-history", patientMedicalHistoryHandler)
+	patient.Get("/medical-history/:id/versions", patientHistoryVersionsHandler)
+	patient.Get("/attachments/:id", patientAttachmentHandler)
+	patient.Post("/key-escrow", patientKeyEscrowHandler)
+	patient.Get("/medical-history/e2e", patientEncryptedHistoryHandler)
+	patient.Post("/medical-history/:id/grants", patientGrantHistoryAccessHandler)
+	patient.Delete("/medical-history/:id", patientEraseHistoryHandler)
+	patient.Delete("/attachments/:id", patientEraseAttachmentHandler)
+	patient.Post("/access-grants", patientIssueAccessGrantHandler)
+	patient.Get("/access-log", patientAccessLogHandler)
+	patient.Post("/consents", patientGrantConsentHandler)
+	patient.Get("/consents", patientListConsentsHandler)
+	patient.Delete("/consents/:id", patientRevokeConsentHandler)
+	patient.Get("/care-relationships", patientCareRelationshipsHandler)
+	patient.Delete("/care-relationships/:id", endCareRelationshipHandler)
+	patient.Post("/care-relationships/:id/allow", patientAllowCareHandler)
+	patient.Get("/clinical-profile", patientClinicalProfileHandler)
+	patient.Put("/clinical-profile", patientSaveClinicalProfileHandler)
+	patient.Delete("/clinical-profile", patientDeleteClinicalProfileHandler)
+	patient.Get("/emergency-summary", patientEmergencySummaryHandler)
+	patient.Put("/public-key", publishPublicKeyHandler)
+	patient.Get("/public-keys/:uid", getPublicKeyHandler)
 
 	// Doctor routes
-	doctor := app.Group("/api/doctor", middleware.AuthMiddleware(r.Auth, "doctor"))
+	doctor := app.Group("/api/doctor", middleware.AuthMiddleware(r.Auth, "doctor"), audit)
 	doctor.Get("/patient/:nfc_id", doctorPatientHandler)
 	doctor.Post("/prescription", doctorPrescriptionHandler)
 	doctor.Post("/medical-history", doctorMedicalHistoryHandler)
+	doctor.Get("/medical-history/schemas", doctorHistorySchemasHandler)
+	doctor.Put("/medical-history/:id", doctorAmendHistoryHandler)
+	doctor.Post("/medical-history/:id/retract", doctorRetractHistoryHandler)
+	doctor.Get("/medical-history/:id/versions", doctorHistoryVersionsHandler)
 	doctor.Get("/patients/search", doctorSearchPatientsHandler)
+	doctor.Get("/patients/match", doctorMatchPatientsHandler)
+	doctor.Get("/patients/:patient_id/clinical-profile", doctorPatientProfileHandler)
+	doctor.Put("/patients/:patient_id/clinical-profile", doctorSavePatientProfileHandler)
+	doctor.Post("/attachment", middleware.RequestContext(r.UploadTimeout), doctorAttachmentHandler)
+	doctor.Get("/medical-history/e2e/:patient_id", doctorEncryptedHistoryHandler)
+	doctor.Post("/medical-history/e2e", doctorAddEncryptedHistoryHandler)
+	doctor.Post("/access-grants", doctorIssueAccessGrantHandler)
+	doctor.Post("/care-relationships", doctorEstablishCareHandler)
+	doctor.Get("/care-relationships", doctorCareRelationshipsHandler)
+	doctor.Delete("/care-relationships/:id", endCareRelationshipHandler)
+	doctor.Put("/public-key", publishPublicKeyHandler)
+	doctor.Get("/public-keys/:uid", getPublicKeyHandler)
 
 	// Pharmacist routes
-	pharmacist := app.Group("/api/pharmacy", middleware.AuthMiddleware(r.Auth, "pharmacist"))
+	pharmacist := app.Group("/api/pharmacy", middleware.AuthMiddleware(r.Auth, "pharmacist"), audit)
 	pharmacist.Get("/prescriptions/active/:nfc_id", pharmacistActivePrescriptionsHandler)
 	pharmacist.Post("/prescription/dispense", pharmacistDispenseHandler)
 
-	// Hospital routes (with one-time access)
-	hospital := app.Group("/api/hospital", middleware.AuthMiddleware(r.Auth, "hospital"), middleware.OneTimeAccess(r.Auth, r.Firestore))
-	hospital.Get("/patient/:nfc_id", hospitalPatientDataHandler)
+	// Hospital routes; patient data needs a single-use access grant and the patient's consent
+	grants := services.NewAccessGrantService(r.Firestore)
+	hospital := app.Group("/api/hospital", middleware.AuthMiddleware(r.Auth, "hospital"), audit)
+	hospital.Get("/patient/:nfc_id", middleware.OneTimeAccess(grants), hospitalPatientDataHandler)
+
+	// The emergency summary is limited to what a resuscitation team needs, so it
+	// needs profile consent or an emergency override rather than a grant
+	hospital.Get("/patient/:nfc_id/emergency-summary", hospitalEmergencySummaryHandler)
+
+	// Emergency break-glass access via escrowed keys
+	hospital.Post("/break-glass", hospitalBreakGlassRequestHandler)
+	hospital.Get("/break-glass/:id/share", hospitalBreakGlassShareHandler)
+	hospital.Post("/break-glass/:id/approve", hospitalBreakGlassApproveHandler)
+	hospital.Post("/break-glass/:id/redeem", hospitalBreakGlassRedeemHandler)
+
+	// Admin routes
+	admin := app.Group("/api/admin", middleware.AuthMiddleware(r.Auth, "admin"), audit)
+	admin.Get("/audit-log", adminAuditLogHandler)
+	admin.Get("/duplicates", adminListDuplicatesHandler)
+	admin.Post("/duplicates/scan", adminScanDuplicatesHandler)
+	admin.Post("/duplicates/:id/merge", adminMergeDuplicateHandler)
+	admin.Post("/duplicates/:id/dismiss", adminDismissDuplicateHandler)
 }
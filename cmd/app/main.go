@@ -9,8 +9,10 @@ import (
 	//"os"
 
 	"github.com/Frhnmj2004/hippocard-server/api/controllers"
+	"github.com/Frhnmj2004/hippocard-server/api/middleware"
 	"github.com/Frhnmj2004/hippocard-server/api/routes"
 	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
 	"github.com/Frhnmj2004/hippocard-server/pkg/blockchain"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
@@ -31,37 +33,105 @@ func main() {
 		log.Fatal("Failed to load config: ", err)
 	}
 
-	// Initialize Firebase App
-	firebaseApp, err := firebaseLib.NewApp(context.Background(), nil, option.WithCredentialsFile(config.Firebase.CredentialsPath))
+	ctx := context.Background()
+
+	// Initialize Firebase App; every Firestore RPC is bounded by FIRESTORE_TIMEOUT
+	firebaseOptions := append([]option.ClientOption{option.WithCredentialsFile(config.Firebase.CredentialsPath)},
+		firebase.RPCTimeoutOptions(config.Timeouts.Firestore)...)
+	firebaseApp, err := firebaseLib.NewApp(ctx, nil, firebaseOptions...)
 	if err != nil {
 		log.Fatal("Failed to initialize Firebase app: ", err)
 	}
 
 	// Initialize Firebase Auth and Firestore clients
-	authClient, err := firebase.NewAuthClient(firebaseApp)
+	authClient, err := firebase.NewAuthClient(ctx, firebaseApp, config.Timeouts.Auth)
 	if err != nil {
 		log.Fatal("Could not initialize Firebase Auth: ", err)
 	}
 
-	firestoreClient, err := firebase.NewFirestoreClient(firebaseApp)
+	firestoreClient, err := firebase.NewFirestoreClient(ctx, firebaseApp)
 	if err != nil {
 		log.Fatal("Could not initialize Firestore: ", err)
 	}
 
-	// Initialize blockchain and IPFS clients
-	blockchainClient, err := blockchain.NewClient(config)
+	// The audit log gets its own client so its credentials can be denied update and delete
+	auditClient := firestoreClient
+	if config.Firebase.AuditCredentialsPath != "" {
+		auditOptions := append([]option.ClientOption{option.WithCredentialsFile(config.Firebase.AuditCredentialsPath)},
+			firebase.RPCTimeoutOptions(config.Timeouts.Firestore)...)
+		auditApp, err := firebaseLib.NewApp(ctx, nil, auditOptions...)
+		if err != nil {
+			log.Fatal("Failed to initialize audit Firebase app: ", err)
+		}
+		auditClient, err = firebase.NewFirestoreClient(ctx, auditApp)
+		if err != nil {
+			log.Fatal("Could not initialize audit Firestore: ", err)
+		}
+	} else {
+		log.Println("AUDIT_CREDENTIALS_PATH not set; the audit log shares the main Firestore credentials")
+	}
+
+	// Initialize blockchain and blob storage clients
+	blockchainClient, err := blockchain.NewClient(ctx, config)
 	if err != nil {
 		log.Fatal("Could not initialize Blockchain client: ", err)
 	}
 
-	ipfsClient, err := storage.NewIPFSClient(config)
+	blobStore, err := storage.NewBlobStore(config)
 	if err != nil {
-		log.Fatal("Could not initialize IPFS client: ", err)
+		log.Fatal("Could not initialize blob storage: ", err)
+	}
+
+	// Periodically check that every referenced blob is still pinned
+	if config.Storage.PinVerifyInterval > 0 {
+		pinService := services.NewPinService(firestoreClient, blobStore)
+		pinService.UnpinOrphans = config.Storage.UnpinOrphans
+		pinService.OrphanGrace = config.Storage.OrphanGrace
+		go pinService.RunVerifier(ctx, config.Storage.PinVerifyInterval)
+	}
+
+	// Expire unredeemed hospital access grants
+	if config.AccessGrantSweepInterval > 0 {
+		go services.NewAccessGrantService(firestoreClient).RunSweeper(ctx, config.AccessGrantSweepInterval)
+	}
+
+	// Link new audit entries into the hash chain
+	if config.AuditSequenceInterval > 0 {
+		go services.NewAuditService(auditClient).RunSequencer(ctx, config.AuditSequenceInterval)
+	}
+
+	// Periodically publish the audit log's Merkle root on chain
+	if config.AuditAnchorInterval > 0 {
+		go services.NewAuditAnchorService(auditClient, blockchainClient).RunAnchorer(ctx, config.AuditAnchorInterval)
+	}
+
+	// Index patients for search as their user documents change
+	if config.PatientSearchIndexer {
+		go services.NewPatientSearchService(firestoreClient).RunIndexer(ctx)
+	}
+
+	// Periodically queue possible duplicate patients for review
+	if config.DuplicateScanInterval > 0 {
+		go services.NewDuplicateService(firestoreClient, blockchainClient).RunDetector(ctx, config.DuplicateScanInterval)
 	}
 
 	// Set up routes with repository and custom handlers
-	r := routes.NewRepository(authClient, firestoreClient, blockchainClient, ipfsClient)
-	app := fiber.New()
+	r := routes.NewRepository(authClient, firestoreClient, blockchainClient, blobStore, auditClient, config.Encryption.DataKey)
+	r.UploadTimeout = config.Timeouts.Upload
+	app := fiber.New(fiber.Config{
+		// Attachments are streamed through encryption rather than buffered
+		StreamRequestBody: true,
+		BodyLimit:         config.MaxUploadBytes,
+		// Every error becomes problem JSON; internal details stay in the logs
+		ErrorHandler: middleware.ErrorHandler,
+	})
+
+	// Tag each request with an ID that appears in error responses and logs
+	app.Use(middleware.RequestID())
+
+	// Give every request a context bounded by REQUEST_TIMEOUT; streamed uploads
+	// replace it with one bounded by UPLOAD_TIMEOUT
+	app.Use(middleware.RequestContext(config.Timeouts.Request))
 
 	// Create controllers and get handlers
 	authController := controllers.NewAuthController(authClient)
@@ -69,19 +139,69 @@ func main() {
 	doctorController := controllers.NewDoctorController(r)
 	pharmacistController := controllers.NewPharmacistController(r)
 	hospitalController := controllers.NewHospitalController(r)
+	keyController := controllers.NewKeyController(r)
+	accessGrantController := controllers.NewAccessGrantController(r)
+	auditController := controllers.NewAuditController(r)
+	consentController := controllers.NewConsentController(r)
+	careController := controllers.NewCareRelationshipController(r)
+	duplicateController := controllers.NewDuplicateController(r)
 
 	// Define handlers
 	loginHandler := authController.LoginHandler
 	patientProfileHandler := patientController.ProfileHandler
 	patientPrescriptionsHandler := patientController.PrescriptionsHandler
 	patientMedicalHistoryHandler := patientController.MedicalHistoryHandler
+	patientHistoryVersionsHandler := patientController.MedicalHistoryVersionsHandler
+	patientAttachmentHandler := patientController.AttachmentHandler
+	patientKeyEscrowHandler := patientController.KeyEscrowHandler
+	patientEncryptedHistoryHandler := patientController.EncryptedMedicalHistoryHandler
+	patientGrantHistoryAccessHandler := keyController.GrantHistoryAccessHandler
+	patientEraseHistoryHandler := patientController.EraseMedicalHistoryHandler
+	patientEraseAttachmentHandler := patientController.EraseAttachmentHandler
+	patientIssueAccessGrantHandler := accessGrantController.IssuePatientGrantHandler
+	patientAccessLogHandler := patientController.AccessLogHandler
+	patientGrantConsentHandler := consentController.GrantConsentHandler
+	patientListConsentsHandler := consentController.ListConsentsHandler
+	patientRevokeConsentHandler := consentController.RevokeConsentHandler
+	patientCareRelationshipsHandler := careController.PatientRelationshipsHandler
+	patientAllowCareHandler := careController.AllowHandler
+	patientClinicalProfileHandler := patientController.ClinicalProfileHandler
+	patientSaveClinicalProfileHandler := patientController.SaveClinicalProfileHandler
+	patientDeleteClinicalProfileHandler := patientController.DeleteClinicalProfileHandler
+	patientEmergencySummaryHandler := patientController.EmergencySummaryHandler
 	doctorPatientHandler := doctorController.GetPatientHandler
 	doctorPrescriptionHandler := doctorController.CreatePrescriptionHandler
 	doctorMedicalHistoryHandler := doctorController.AddMedicalHistoryHandler
+	doctorHistorySchemasHandler := doctorController.HistorySchemasHandler
+	doctorAmendHistoryHandler := doctorController.AmendMedicalHistoryHandler
+	doctorRetractHistoryHandler := doctorController.RetractMedicalHistoryHandler
+	doctorHistoryVersionsHandler := doctorController.MedicalHistoryVersionsHandler
 	doctorSearchPatientsHandler := doctorController.SearchPatientsHandler
+	doctorMatchPatientsHandler := doctorController.MatchPatientsHandler
+	doctorPatientProfileHandler := doctorController.PatientProfileHandler
+	doctorSavePatientProfileHandler := doctorController.SavePatientProfileHandler
+	doctorAttachmentHandler := doctorController.AddAttachmentHandler
+	doctorEncryptedHistoryHandler := doctorController.EncryptedMedicalHistoryHandler
+	doctorAddEncryptedHistoryHandler := doctorController.AddEncryptedMedicalHistoryHandler
+	doctorIssueAccessGrantHandler := accessGrantController.IssueDoctorGrantHandler
+	doctorEstablishCareHandler := careController.EstablishHandler
+	doctorCareRelationshipsHandler := careController.DoctorRelationshipsHandler
+	endCareRelationshipHandler := careController.EndHandler
 	pharmacistActivePrescriptionsHandler := pharmacistController.ActivePrescriptionsHandler
 	pharmacistDispenseHandler := pharmacistController.DispensePrescriptionHandler
 	hospitalPatientDataHandler := hospitalController.PatientDataHandler
+	hospitalEmergencySummaryHandler := hospitalController.EmergencySummaryHandler
+	hospitalBreakGlassRequestHandler := hospitalController.BreakGlassRequestHandler
+	hospitalBreakGlassShareHandler := hospitalController.BreakGlassShareHandler
+	hospitalBreakGlassApproveHandler := hospitalController.BreakGlassApproveHandler
+	hospitalBreakGlassRedeemHandler := hospitalController.BreakGlassRedeemHandler
+	publishPublicKeyHandler := keyController.PublishPublicKeyHandler
+	getPublicKeyHandler := keyController.GetPublicKeyHandler
+	adminAuditLogHandler := auditController.AuditLogHandler
+	adminListDuplicatesHandler := duplicateController.ListHandler
+	adminScanDuplicatesHandler := duplicateController.ScanHandler
+	adminMergeDuplicateHandler := duplicateController.MergeHandler
+	adminDismissDuplicateHandler := duplicateController.DismissHandler
 
 	// Set up routes with all handlers
 	r.SetupRoutes(app,
@@ -89,13 +209,57 @@ func main() {
 		patientProfileHandler,
 		patientPrescriptionsHandler,
 		patientMedicalHistoryHandler,
+		patientHistoryVersionsHandler,
+		patientAttachmentHandler,
+		patientKeyEscrowHandler,
+		patientEncryptedHistoryHandler,
+		patientGrantHistoryAccessHandler,
+		patientEraseHistoryHandler,
+		patientEraseAttachmentHandler,
+		patientIssueAccessGrantHandler,
+		patientAccessLogHandler,
+		patientGrantConsentHandler,
+		patientListConsentsHandler,
+		patientRevokeConsentHandler,
+		patientCareRelationshipsHandler,
+		patientAllowCareHandler,
+		patientClinicalProfileHandler,
+		patientSaveClinicalProfileHandler,
+		patientDeleteClinicalProfileHandler,
+		patientEmergencySummaryHandler,
 		doctorPatientHandler,
 		doctorPrescriptionHandler,
 		doctorMedicalHistoryHandler,
+		doctorHistorySchemasHandler,
+		doctorAmendHistoryHandler,
+		doctorRetractHistoryHandler,
+		doctorHistoryVersionsHandler,
 		doctorSearchPatientsHandler,
+		doctorMatchPatientsHandler,
+		doctorPatientProfileHandler,
+		doctorSavePatientProfileHandler,
+		doctorAttachmentHandler,
+		doctorEncryptedHistoryHandler,
+		doctorAddEncryptedHistoryHandler,
+		doctorIssueAccessGrantHandler,
+		doctorEstablishCareHandler,
+		doctorCareRelationshipsHandler,
+		endCareRelationshipHandler,
 		pharmacistActivePrescriptionsHandler,
 		pharmacistDispenseHandler,
 		hospitalPatientDataHandler,
+		hospitalEmergencySummaryHandler,
+		hospitalBreakGlassRequestHandler,
+		hospitalBreakGlassShareHandler,
+		hospitalBreakGlassApproveHandler,
+		hospitalBreakGlassRedeemHandler,
+		publishPublicKeyHandler,
+		getPublicKeyHandler,
+		adminAuditLogHandler,
+		adminListDuplicatesHandler,
+		adminScanDuplicatesHandler,
+		adminMergeDuplicateHandler,
+		adminDismissDuplicateHandler,
 	)
 
 	log.Printf("Server starting on :%s", config.ServerPort)
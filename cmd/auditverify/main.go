@@ -0,0 +1,92 @@
+// auditverify proves audit log entries against the roots anchored on Polygon.
+//
+//	auditverify -entry <entry ID>   # prove one entry; prints the proof as JSON
+//	auditverify -all                # check the whole chain and every anchor
+//
+// It exits non-zero if any entry has been altered, removed or reordered, or if
+// an anchored root does not match the chain.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/internals/services"
+	"github.com/Frhnmj2004/hippocard-server/pkg/blockchain"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	firebaseLib "firebase.google.com/go"
+	"google.golang.org/api/option"
+)
+
+func main() {
+	entryID := flag.String("entry", "", "ID of the audit entry to prove")
+	all := flag.Bool("all", false, "verify every entry and anchor")
+	flag.Parse()
+	if (*entryID == "") == !*all {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(".env"); err != nil {
+		log.Fatal("Error loading .env file: ", err)
+	}
+	config, err := configs.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+
+	ctx := context.Background()
+
+	// Read the audit log with its own credentials where they are configured
+	credentials := config.Firebase.AuditCredentialsPath
+	if credentials == "" {
+		credentials = config.Firebase.CredentialsPath
+	}
+	firebaseOptions := append([]option.ClientOption{option.WithCredentialsFile(credentials)},
+		firebase.RPCTimeoutOptions(config.Timeouts.Firestore)...)
+	firebaseApp, err := firebaseLib.NewApp(ctx, nil, firebaseOptions...)
+	if err != nil {
+		log.Fatal("Failed to initialize Firebase app: ", err)
+	}
+	auditClient, err := firebase.NewFirestoreClient(ctx, firebaseApp)
+	if err != nil {
+		log.Fatal("Could not initialize Firestore: ", err)
+	}
+	blockchainClient, err := blockchain.NewClient(ctx, config)
+	if err != nil {
+		log.Fatal("Could not initialize Blockchain client: ", err)
+	}
+
+	anchors := services.NewAuditAnchorService(auditClient, blockchainClient)
+
+	if *all {
+		entries, anchored, err := anchors.VerifyChain(ctx)
+		if err != nil {
+			log.Fatalf("Audit log verification failed after %d entries and %d anchors: %v", entries, anchored, err)
+		}
+		log.Printf("Audit log intact: %d entries, %d anchors verified on chain", entries, anchored)
+		return
+	}
+
+	proof, err := anchors.ProveEntry(ctx, *entryID)
+	if err != nil {
+		log.Fatalf("Audit entry %s failed verification: %v", *entryID, err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(proof); err != nil {
+		log.Fatal("Failed to write proof: ", err)
+	}
+	if !proof.Anchored {
+		log.Printf("Audit entry %s is intact but not anchored yet", *entryID)
+		return
+	}
+	log.Printf("Audit entry %s is intact and anchored in transaction %s", *entryID, proof.Anchor.TxHash)
+}
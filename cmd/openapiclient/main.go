@@ -0,0 +1,487 @@
+// openapiclient generates the Go client in pkg/client from the OpenAPI
+// document served at /api/openapi.json.
+//
+//	openapiclient -spec api/routes/openapi.json -out pkg/client/client.gen.go
+//
+// Each component schema becomes a struct and each operation a method on
+// client.Client named after its operationId. Required parameters are method
+// arguments; optional query and header parameters go in an <Operation>Params
+// struct. It is run by go generate in pkg/client.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ordered is a JSON object that remembers the order of its keys, so the
+// generated code follows the document
+type ordered[T any] struct {
+	keys   []string
+	values map[string]T
+}
+
+func (o *ordered[T]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	o.values = make(map[string]T)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		var value T
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		o.keys = append(o.keys, key)
+		o.values[key] = value
+	}
+	return nil
+}
+
+type document struct {
+	Paths      ordered[ordered[*operation]] `json:"paths"`
+	Components struct {
+		Schemas    ordered[*schema]      `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+type schema struct {
+	Ref                  string           `json:"$ref"`
+	Type                 string           `json:"type"`
+	Format               string           `json:"format"`
+	Description          string           `json:"description"`
+	Required             []string         `json:"required"`
+	Properties           ordered[*schema] `json:"properties"`
+	Items                *schema          `json:"items"`
+	AdditionalProperties *schema          `json:"additionalProperties"`
+	Deprecated           bool             `json:"deprecated"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type mediaTypes map[string]struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *struct {
+		Content mediaTypes `json:"content"`
+	} `json:"requestBody"`
+	Responses ordered[struct {
+		Content mediaTypes `json:"content"`
+	}] `json:"responses"`
+}
+
+func main() {
+	specPath := flag.String("spec", "api/routes/openapi.json", "OpenAPI document to read")
+	out := flag.String("out", "pkg/client/client.gen.go", "Go file to write")
+	pkg := flag.String("package", "client", "package of the generated file")
+	flag.Parse()
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal("Error reading spec: ", err)
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatal("Error parsing spec: ", err)
+	}
+
+	g := &generator{doc: &doc, imports: make(map[string]bool)}
+	for _, name := range doc.Components.Schemas.keys {
+		g.writeSchema(name, doc.Components.Schemas.values[name])
+	}
+	for _, path := range doc.Paths.keys {
+		methods := doc.Paths.values[path]
+		for _, method := range methods.keys {
+			g.writeOperation(strings.ToUpper(method), path, methods.values[method])
+		}
+	}
+	if g.err != nil {
+		log.Fatal(g.err)
+	}
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "// Code generated by openapiclient from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", *specPath, *pkg)
+	for _, path := range slices.Sorted(maps.Keys(g.imports)) {
+		fmt.Fprintf(&file, "%q\n", path)
+	}
+	fmt.Fprintf(&file, ")\n\n")
+	file.Write(g.buf.Bytes())
+
+	source, err := format.Source(file.Bytes())
+	if err != nil {
+		log.Fatal("Error formatting generated code: ", err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatal("Error writing client: ", err)
+	}
+}
+
+type generator struct {
+	doc     *document
+	buf     bytes.Buffer
+	imports map[string]bool // Packages the generated code uses
+	err     error
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) fail(format string, args ...any) {
+	if g.err == nil {
+		g.err = fmt.Errorf(format, args...)
+	}
+}
+
+// writeSchema writes a component schema as a struct, or as a named type when
+// it is not an object
+func (g *generator) writeSchema(name string, s *schema) {
+	if s.Description != "" {
+		g.printf("// %s\n", s.Description)
+	}
+	if s.Type != "object" || len(s.Properties.keys) == 0 {
+		g.printf("type %s %s\n\n", name, g.goType(s, true))
+		return
+	}
+	g.printf("type %s struct {\n", name)
+	for _, prop := range s.Properties.keys {
+		field := s.Properties.values[prop]
+		required := slices.Contains(s.Required, prop)
+		tag := prop
+		if !required {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`", goName(prop), g.goType(field, required), tag)
+		note := field.Description
+		if field.Deprecated {
+			note = "Deprecated: " + note
+		}
+		if note != "" {
+			g.printf(" // %s", note)
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n\n")
+}
+
+// goType returns the Go type of a schema; optional times and structs are
+// pointers so they can be left out
+func (g *generator) goType(s *schema, required bool) string {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		target, ok := g.doc.Components.Schemas.values[name]
+		if !ok {
+			g.fail("unknown schema %s", s.Ref)
+		}
+		if !required && target != nil && target.Type == "object" && len(target.Properties.keys) > 0 {
+			return "*" + name
+		}
+		return name
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			if required {
+				return "time.Time"
+			}
+			return "*time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, true)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, true)
+		}
+		if len(s.Properties.keys) > 0 {
+			g.fail("inline objects are not supported; move it to components/schemas")
+		}
+	}
+	// Free-form values are left for the caller to decode
+	g.imports["encoding/json"] = true
+	return "json.RawMessage"
+}
+
+// writeOperation writes the method for one operation, and its Params struct
+// if it has optional parameters
+func (g *generator) writeOperation(method, path string, op *operation) {
+	name := goName(op.OperationID)
+	g.imports["context"] = true
+	g.imports["net/http"] = true
+	g.imports["net/url"] = true
+	var required, optional []*parameter
+	for _, p := range op.Parameters {
+		p = g.resolve(p)
+		if p.Required || p.In == "path" {
+			required = append(required, p)
+		} else {
+			optional = append(optional, p)
+		}
+	}
+
+	if len(optional) > 0 {
+		g.printf("// %sParams holds the optional parameters of %s; zero values are not sent\n", name, name)
+		g.printf("type %sParams struct {\n", name)
+		for _, p := range optional {
+			g.printf("%s %s", goName(p.Name), g.goType(p.Schema, true))
+			if p.Description != "" {
+				g.printf(" // %s", p.Description)
+			}
+			g.printf("\n")
+		}
+		g.printf("}\n\n")
+	}
+
+	// Arguments: required parameters, then optional ones, then the body
+	args := []string{"ctx context.Context"}
+	for _, p := range required {
+		args = append(args, argName(p.Name)+" "+g.goType(p.Schema, true))
+	}
+	if len(optional) > 0 {
+		args = append(args, "params *"+name+"Params")
+	}
+	var bodyType string
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			bodyType = g.goType(media.Schema, true)
+			args = append(args, "body *"+bodyType)
+		} else {
+			g.imports["io"] = true
+			args = append(args, "contentType string", "body io.Reader")
+		}
+	}
+
+	// Result: the first successful response's JSON, or its body as a stream
+	var result string
+	var binary, otherMedia bool
+	for _, status := range op.Responses.keys {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		for mediaType, media := range op.Responses.values[status].Content {
+			switch {
+			case mediaType == "application/json":
+				result = g.goType(media.Schema, true)
+			case media.Schema != nil && media.Schema.Format == "binary":
+				g.imports["io"] = true
+				binary = true
+			default:
+				otherMedia = true
+			}
+		}
+		break
+	}
+
+	summary := op.Summary
+	if summary != "" {
+		summary = ": " + lowerFirst(summary)
+	}
+	g.printf("// %s calls %s %s%s\n", name, method, path, summary)
+	if otherMedia && result != "" {
+		g.printf("//\n// The response is decoded as JSON, so leave unset any parameter selecting another representation.\n")
+	}
+	switch {
+	case binary:
+		g.printf("//\n// The caller must close the returned body.\n")
+		g.printf("func (c *Client) %s(%s) (io.ReadCloser, error) {\n", name, strings.Join(args, ", "))
+	case result == "":
+		g.printf("func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+	case strings.HasPrefix(result, "[]") || strings.HasPrefix(result, "map[") || result == "json.RawMessage":
+		g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+	default:
+		g.printf("func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(args, ", "), result)
+	}
+
+	g.printf("path := %s\n", g.pathExpr(path, required))
+	g.printf("query := url.Values{}\n")
+	g.printf("header := http.Header{}\n")
+	for _, p := range required {
+		if p.In != "path" {
+			g.printf("%s\n", g.setParam(p, argName(p.Name), g.goType(p.Schema, true)))
+		}
+	}
+	if len(optional) > 0 {
+		g.printf("if params != nil {\n")
+		for _, p := range optional {
+			value := "params." + goName(p.Name)
+			typ := g.goType(p.Schema, true)
+			zero := `""`
+			if typ != "string" {
+				zero = "0"
+			}
+			g.printf("if %s != %s {\n%s\n}\n", value, zero, g.setParam(p, value, typ))
+		}
+		g.printf("}\n")
+	}
+
+	body := "nil"
+	if bodyType != "" {
+		body = "body"
+	}
+	switch {
+	case binary:
+		g.printf("resp, err := c.send(ctx, http.Method%s, path, query, header, \"\", nil)\n", methodName(method))
+		g.printf("if err != nil {\nreturn nil, err\n}\nreturn resp.Body, nil\n}\n\n")
+	case op.RequestBody != nil && bodyType == "":
+		g.printf("resp, err := c.send(ctx, http.Method%s, path, query, header, contentType, body)\n", methodName(method))
+		g.printf("if err != nil {\nreturn nil, err\n}\n")
+		g.printf("var out %s\nreturn &out, decode(resp, &out)\n}\n\n", result)
+	case result == "":
+		g.printf("return c.do(ctx, http.Method%s, path, query, header, %s, nil)\n}\n\n", methodName(method), body)
+	case strings.HasPrefix(result, "[]") || strings.HasPrefix(result, "map[") || result == "json.RawMessage":
+		g.printf("var out %s\n", result)
+		g.printf("if err := c.do(ctx, http.Method%s, path, query, header, %s, &out); err != nil {\nreturn nil, err\n}\nreturn out, nil\n}\n\n", methodName(method), body)
+	default:
+		g.printf("var out %s\n", result)
+		g.printf("if err := c.do(ctx, http.Method%s, path, query, header, %s, &out); err != nil {\nreturn nil, err\n}\nreturn &out, nil\n}\n\n", methodName(method), body)
+	}
+}
+
+// resolve follows a reference to a shared parameter
+func (g *generator) resolve(p *parameter) *parameter {
+	if p.Ref == "" {
+		return p
+	}
+	name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+	target, ok := g.doc.Components.Parameters[name]
+	if !ok {
+		g.fail("unknown parameter %s", p.Ref)
+		return &parameter{Name: name, Schema: &schema{Type: "string"}}
+	}
+	return target
+}
+
+// pathExpr returns a Go expression building path with its parameters escaped
+func (g *generator) pathExpr(path string, params []*parameter) string {
+	var parts []string
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path, '}')
+		name := path[start+1 : end]
+		if !slices.ContainsFunc(params, func(p *parameter) bool { return p.In == "path" && p.Name == name }) {
+			g.fail("path parameter %s of %s is not declared", name, path)
+		}
+		parts = append(parts, fmt.Sprintf("%q", path[:start]), "url.PathEscape("+argName(name)+")")
+		path = path[end+1:]
+	}
+	if path != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", path))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// setParam returns the statement sending one query or header parameter
+func (g *generator) setParam(p *parameter, value, typ string) string {
+	switch typ {
+	case "int":
+		g.imports["strconv"] = true
+		value = "strconv.Itoa(" + value + ")"
+	case "int64":
+		g.imports["strconv"] = true
+		value = "strconv.FormatInt(" + value + ", 10)"
+	}
+	if p.In == "header" {
+		return fmt.Sprintf("header.Set(%q, %s)", p.Name, value)
+	}
+	return fmt.Sprintf("query.Set(%q, %s)", p.Name, value)
+}
+
+// initialisms are written in capitals, as Go names do
+var initialisms = map[string]string{
+	"api": "API", "cid": "CID", "e2e": "E2E", "http": "HTTP", "id": "ID", "ids": "IDs",
+	"ip": "IP", "json": "JSON", "nfc": "NFC", "uid": "UID", "url": "URL",
+}
+
+// words splits a snake_case, kebab-case or camelCase name
+func words(name string) []string {
+	var out []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		start := 0
+		for i, r := range part {
+			if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(part[i-1])) {
+				out = append(out, part[start:i])
+				start = i
+			}
+		}
+		out = append(out, part[start:])
+	}
+	return out
+}
+
+// goName returns the exported Go name of a JSON field, parameter or operation
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// argName returns the unexported Go name of a parameter
+func argName(name string) string {
+	parts := words(name)
+	first := strings.ToLower(parts[0])
+	if first == "x" && len(parts) > 1 {
+		// Drop the X- of custom headers
+		return argName(strings.Join(parts[1:], "-"))
+	}
+	return first + goName(strings.Join(parts[1:], "_"))
+}
+
+func methodName(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
+
+func lowerFirst(s string) string {
+	if len(s) > 1 && unicode.IsUpper(rune(s[1])) {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
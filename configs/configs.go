@@ -1,13 +1,19 @@
 package configs
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
 )
 
 type FirebaseConfig struct {
-	CredentialsPath string
+	CredentialsPath      string
+	AuditCredentialsPath string // Service account limited to the audit log's collections; empty shares CredentialsPath
 }
 
 type BlockchainConfig struct {
@@ -16,44 +22,152 @@ type BlockchainConfig struct {
 }
 
 type IPFSConfig struct {
-	APIKey string
+	APIURL string // HTTP API endpoint, e.g. Pinata or a local Kubo node at http://localhost:5001
+	APIKey string // Pinata credentials; leave empty for an unauthenticated node
 	Secret string
 }
 
+type StorageConfig struct {
+	Backend           string        // "ipfs", "local" or "s3"
+	LocalDir          string        // Root directory for the local backend
+	PinVerifyInterval time.Duration // How often referenced blobs are checked; 0 disables
+	UnpinOrphans      bool          // Release blobs no record references during verification
+	OrphanGrace       time.Duration // Minimum age of an unreferenced blob before it is released
+}
+
+type CacheConfig struct {
+	MemoryBytes int    // In-memory read cache size; 0 disables caching
+	Dir         string // Directory for the on-disk read cache; empty disables it
+	DiskBytes   int    // On-disk read cache size
+}
+
+type S3Config struct {
+	Endpoint  string // Host and port, e.g. localhost:9000 for MinIO
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+}
+
+// EncryptionConfig holds the master key. It wraps each patient's own data key,
+// which seals their medical history, attachments, clinical profile and cached
+// emergency summary; records written before patients had a key are sealed
+// with it directly.
+type EncryptionConfig struct {
+	DataKey []byte // AES-256 key, base64-encoded in DATA_ENCRYPTION_KEY; make one with openssl rand -base64 32
+}
+
+// TimeoutConfig bounds how long a request, and each call it makes to a
+// dependency, may run. Zero disables a timeout.
+type TimeoutConfig struct {
+	Request    time.Duration // Whole request, from the first middleware to the response
+	Upload     time.Duration // Whole request for routes that stream a large body, in place of Request
+	Firestore  time.Duration // Each Firestore RPC
+	Auth       time.Duration // Each Firebase Auth call
+	Storage    time.Duration // Each buffered blob operation; streams are bounded by the request
+	Blockchain time.Duration // Each contract transaction
+}
+
 type Config struct {
-	ServerPort string
-	Firebase   FirebaseConfig
-	Blockchain BlockchainConfig
-	IPFS       IPFSConfig
+	ServerPort               string
+	MaxUploadBytes           int // Largest request body accepted, sized for attachments
+	Timeouts                 TimeoutConfig
+	Encryption               EncryptionConfig
+	AccessGrantSweepInterval time.Duration // How often expired hospital access grants are swept; 0 disables
+	AuditSequenceInterval    time.Duration // How often new audit entries are linked into the hash chain; 0 disables
+	AuditAnchorInterval      time.Duration // How often new audit entries are anchored on chain; 0 disables
+	PatientSearchIndexer     bool          // Keep the patient search index in step with users from this instance
+	DuplicateScanInterval    time.Duration // How often patients are checked for duplicates; 0 disables
+	Firebase                 FirebaseConfig
+	Blockchain               BlockchainConfig
+	Storage                  StorageConfig
+	Cache                    CacheConfig
+	IPFS                     IPFSConfig
+	S3                       S3Config
 }
 
 // LoadConfig retrieves environment variables and returns a validated Config struct
 func LoadConfig() (*Config, error) {
 	// Assume godotenv.Load() is called in main.go, so env vars are already available
 	config := &Config{
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+		ServerPort:               getEnv("SERVER_PORT", "8080"),
+		MaxUploadBytes:           getEnvInt("MAX_UPLOAD_BYTES", 512<<20),
+		AccessGrantSweepInterval: getEnvDuration("ACCESS_GRANT_SWEEP_INTERVAL", time.Minute),
+		AuditSequenceInterval:    getEnvDuration("AUDIT_SEQUENCE_INTERVAL", 5*time.Second),
+		AuditAnchorInterval:      getEnvDuration("AUDIT_ANCHOR_INTERVAL", time.Hour),
+		PatientSearchIndexer:     getEnvBool("PATIENT_SEARCH_INDEXER", true),
+		DuplicateScanInterval:    getEnvDuration("DUPLICATE_SCAN_INTERVAL", 24*time.Hour),
+		Timeouts: TimeoutConfig{
+			Request:    getEnvDuration("REQUEST_TIMEOUT", 2*time.Minute),
+			Upload:     getEnvDuration("UPLOAD_TIMEOUT", 30*time.Minute),
+			Firestore:  getEnvDuration("FIRESTORE_TIMEOUT", 10*time.Second),
+			Auth:       getEnvDuration("AUTH_TIMEOUT", 10*time.Second),
+			Storage:    getEnvDuration("STORAGE_TIMEOUT", 30*time.Second),
+			Blockchain: getEnvDuration("BLOCKCHAIN_TIMEOUT", time.Minute),
+		},
 		Firebase: FirebaseConfig{
-			CredentialsPath: getEnv("FIREBASE_CREDENTIALS_PATH", "configs/firebase-credentials.json"),
+			CredentialsPath:      getEnv("FIREBASE_CREDENTIALS_PATH", "configs/firebase-credentials.json"),
+			AuditCredentialsPath: getEnv("AUDIT_CREDENTIALS_PATH", ""),
 		},
 		Blockchain: BlockchainConfig{
 			RPCURL:          getEnv("POLYGON_RPC", "https://rpc-mumbai.maticvigil.com"),
 			ContractAddress: getEnv("CONTRACT_ADDRESS", ""),
 		},
+		Storage: StorageConfig{
+			Backend:           getEnv("STORAGE_BACKEND", "ipfs"),
+			LocalDir:          getEnv("LOCAL_STORAGE_DIR", "data/blobs"),
+			PinVerifyInterval: getEnvDuration("PIN_VERIFY_INTERVAL", 24*time.Hour),
+			UnpinOrphans:      getEnvBool("UNPIN_ORPHANS", false),
+			OrphanGrace:       getEnvDuration("ORPHAN_GRACE_PERIOD", 24*time.Hour),
+		},
+		Cache: CacheConfig{
+			MemoryBytes: getEnvInt("CACHE_MEMORY_BYTES", 64<<20),
+			Dir:         getEnv("CACHE_DIR", ""),
+			DiskBytes:   getEnvInt("CACHE_DISK_BYTES", 1<<30),
+		},
 		IPFS: IPFSConfig{
+			APIURL: getEnv("IPFS_API_URL", "https://api.pinata.cloud/psa"),
 			APIKey: getEnv("IPFS_API_KEY", ""),
 			Secret: getEnv("IPFS_SECRET", ""),
 		},
+		S3: S3Config{
+			Endpoint:  getEnv("S3_ENDPOINT", ""),
+			Bucket:    getEnv("S3_BUCKET", ""),
+			AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			SecretKey: getEnv("S3_SECRET_KEY", ""),
+			Region:    getEnv("S3_REGION", "us-east-1"),
+			UseSSL:    getEnvBool("S3_USE_SSL", true),
+		},
 	}
 
 	// Validate required fields
+	dataKey, err := crypto.ParseKey(os.Getenv("DATA_ENCRYPTION_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("DATA_ENCRYPTION_KEY must be a base64-encoded 32-byte key: %w", err)
+	}
+	config.Encryption.DataKey = dataKey
 	if config.Firebase.CredentialsPath == "" {
-		return nil, logError("FIREBASE_CREDENTIALS_PATH is required")
+		return nil, errors.New("FIREBASE_CREDENTIALS_PATH is required")
 	}
 	if config.Blockchain.ContractAddress == "" {
-		return nil, logError("CONTRACT_ADDRESS is required")
+		return nil, errors.New("CONTRACT_ADDRESS is required")
 	}
-	if config.IPFS.APIKey == "" || config.IPFS.Secret == "" {
-		return nil, logError("IPFS_API_KEY and IPFS_SECRET are required")
+	switch config.Storage.Backend {
+	case "ipfs":
+		if (config.IPFS.APIKey == "") != (config.IPFS.Secret == "") {
+			return nil, errors.New("IPFS_API_KEY and IPFS_SECRET must be set together")
+		}
+	case "local":
+		if config.Storage.LocalDir == "" {
+			return nil, errors.New("LOCAL_STORAGE_DIR is required for the local backend")
+		}
+	case "s3":
+		if config.S3.Endpoint == "" || config.S3.Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required for the s3 backend")
+		}
+	default:
+		return nil, errors.New("STORAGE_BACKEND must be one of ipfs, local or s3")
 	}
 
 	return config, nil
@@ -67,9 +181,44 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// logError logs and returns an error
-func logError(msg string) error {
-	err := fmt.Errorf(msg)
-	log.Println(err)
-	return err
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s, using default %d: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvBool retrieves a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s, using default %t: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvDuration retrieves a duration environment variable (e.g. "24h") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s, using default %s: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return d
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AccessGrant is a short-lived, single-use permission for one hospital to read
+// one patient's data. It is issued by the patient or by a doctor with the
+// patient on site, and consumed the first time the hospital uses it.
+type AccessGrant struct {
+	ID         string     `json:"id" firestore:"id"`                                       // Firestore document ID (UUID), presented by the hospital
+	PatientID  string     `json:"patient_id" firestore:"patient_id"`                       // Patient’s UID
+	NFCID      string     `json:"nfc_id" firestore:"nfc_id"`                               // Patient’s NFC ID, matched against the hospital's request
+	HospitalID string     `json:"hospital_id" firestore:"hospital_id"`                     // The only hospital that may redeem the grant
+	IssuedBy   string     `json:"issued_by" firestore:"issued_by"`                         // UID of the patient or doctor who issued it
+	IssuerRole string     `json:"issuer_role" firestore:"issuer_role"`                     // "patient" or "doctor"
+	Status     string     `json:"status" firestore:"status"`                               // "active", "redeemed" or "expired"
+	CreatedAt  time.Time  `json:"created_at" firestore:"created_at"`                       // When the grant was issued
+	ExpiresAt  time.Time  `json:"expires_at" firestore:"expires_at"`                       // Redemption deadline
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty" firestore:"redeemed_at,omitempty"` // When the hospital used it
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Attachment represents a large file (scan, DICOM image, PDF) in a patient's record
+type Attachment struct {
+	ID          string    `json:"id" firestore:"id"`                     // Firestore document ID (UUID)
+	UserID      string    `json:"user_id" firestore:"user_id"`           // Patient’s UID
+	DoctorID    string    `json:"doctor_id" firestore:"doctor_id"`       // Uploading doctor’s UID
+	CID         string    `json:"cid" firestore:"cid"`                   // IPFS Content Identifier for the encrypted stream
+	FileName    string    `json:"file_name" firestore:"file_name"`       // Original file name
+	ContentType string    `json:"content_type" firestore:"content_type"` // MIME type of the plaintext
+	CreatedAt   time.Time `json:"created_at" firestore:"created_at"`     // When the attachment was added
+}
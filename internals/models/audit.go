@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+)
+
+// AuditEntry records one request that touched patient data. Entries are never
+// updated or deleted: the sequencer places each one in the hash chain by
+// creating an AuditLink for it, and Seq, PrevHash and Hash are filled in from
+// that link when the entry is read. Each link's hash covers the entry's
+// contents and the previous link's hash, so editing or removing any entry
+// breaks the chain from that point on.
+type AuditEntry struct {
+	ID         string    `json:"id" firestore:"id"`                                     // Firestore document ID, random
+	Seq        int64     `json:"seq" firestore:"-"`                                     // Position in the hash chain, from 1; 0 until linked
+	ActorID    string    `json:"actor_id" firestore:"actor_id"`                         // UID of the caller
+	ActorRole  string    `json:"actor_role" firestore:"actor_role"`                     // "patient", "doctor", "pharmacist", "hospital" or "admin"
+	PatientIDs []string  `json:"patient_ids" firestore:"patient_ids"`                   // Patients whose data was read or changed
+	NFCID      string    `json:"nfc_id,omitempty" firestore:"nfc_id,omitempty"`         // Card scanned to reach the patient, if any
+	Resource   string    `json:"resource" firestore:"resource"`                         // e.g. "profile", "prescriptions", "medical_history"
+	Action     string    `json:"action" firestore:"action"`                             // "read", "write" or "delete"
+	Endpoint   string    `json:"endpoint" firestore:"endpoint"`                         // Method and route template, e.g. "GET /api/doctor/patient/:nfc_id"
+	Purpose    string    `json:"purpose,omitempty" firestore:"purpose,omitempty"`       // Stated reason for access, from X-Access-Purpose
+	Emergency  bool      `json:"emergency,omitempty" firestore:"emergency,omitempty"`   // Access relied on an emergency override of consent
+	Outcome    string    `json:"outcome" firestore:"outcome"`                           // "success", "denied" or "error"
+	StatusCode int       `json:"status_code" firestore:"status_code"`                   // HTTP status returned
+	RequestIP  string    `json:"request_ip,omitempty" firestore:"request_ip,omitempty"` // Caller's address
+	Time       time.Time `json:"time" firestore:"time"`
+	RecordedAt time.Time `json:"-" firestore:"recorded_at,serverTimestamp"` // Commit time, set by Firestore; the order entries are linked in
+	PrevHash   string    `json:"prev_hash" firestore:"-"`                   // Hex SHA-256 of the previous entry; empty for the first
+	Hash       string    `json:"hash" firestore:"-"`                        // Hex SHA-256 over this entry and PrevHash; empty until linked
+}
+
+// AuditLink places an audit entry in the hash chain. Links are only ever
+// created, under their zero-padded Seq, so the chain grows with create-only
+// credentials and two sequencers cannot both take a position; the link with the
+// highest Seq is the head of the chain.
+type AuditLink struct {
+	Seq        int64     `json:"seq" firestore:"seq"`                 // Position in the chain, from 1
+	EntryID    string    `json:"entry_id" firestore:"entry_id"`       // Audit entry at this position
+	PrevHash   string    `json:"prev_hash" firestore:"prev_hash"`     // Hash of the previous link; empty for the first
+	Hash       string    `json:"hash" firestore:"hash"`               // Hex SHA-256 over the entry, Seq and PrevHash
+	RecordedAt time.Time `json:"recorded_at" firestore:"recorded_at"` // The entry's RecordedAt, where the next sequencer run resumes
+}
+
+// AuditQuery filters the audit log; zero fields are ignored. The page's date
+// range and sort apply to the entries' time.
+type AuditQuery struct {
+	PatientID  string
+	ActorID    string
+	ActorRole  string
+	ActorRoles []string // Any of these roles; not combined with ActorRole
+	PageQuery
+}
+
+// AccessLogEntry is an audit entry as shown to the patient whose data was accessed
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	ActorID   string    `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	ActorRole string    `json:"actor_role"` // "doctor", "pharmacist" or "hospital"
+	Resource  string    `json:"resource"`
+	Action    string    `json:"action"`
+	Purpose   string    `json:"purpose,omitempty"`
+	Outcome   string    `json:"outcome"`
+}
+
+// AuditAnchor is a Merkle root over a run of audit entries, published on chain
+type AuditAnchor struct {
+	ID        string    `json:"id" firestore:"id"`             // Firestore document ID, the zero-padded FromSeq
+	FromSeq   int64     `json:"from_seq" firestore:"from_seq"` // First entry covered
+	ToSeq     int64     `json:"to_seq" firestore:"to_seq"`     // Last entry covered
+	Root      string    `json:"root" firestore:"root"`         // Hex Merkle root over the entries' hashes, in order
+	TxHash    string    `json:"tx_hash" firestore:"tx_hash"`   // Polygon transaction carrying the root
+	ChainID   string    `json:"chain_id" firestore:"chain_id"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+// AuditProof shows that an audit entry is intact and, once anchored, that it is
+// covered by a root published on chain
+type AuditProof struct {
+	Entry    *AuditEntry         `json:"entry"`
+	Anchor   *AuditAnchor        `json:"anchor,omitempty"` // Nil until the entry has been anchored
+	Path     []crypto.MerkleStep `json:"path,omitempty"`   // Siblings from the entry's hash up to Anchor.Root
+	Anchored bool                `json:"anchored"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// CareRelationship records that a doctor is treating a patient. A doctor starts
+// one by scanning the patient's NFC card in person; it lapses at ExpiresAt and
+// can be ended earlier by either the doctor or the patient. Once the patient
+// ends it, scanning the card again does not restart it until the patient allows
+// the doctor again.
+type CareRelationship struct {
+	ID        string     `json:"id" firestore:"id"`                                 // Firestore document ID, "<doctor_id>_<patient_id>"
+	DoctorID  string     `json:"doctor_id" firestore:"doctor_id"`                   // Treating doctor’s UID
+	PatientID string     `json:"patient_id" firestore:"patient_id"`                 // Patient’s UID
+	NFCID     string     `json:"-" firestore:"nfc_id"`                              // Card scanned to start the relationship; never returned, so it cannot stand in for a scan
+	Status    string     `json:"status" firestore:"status"`                         // "active" or "ended"
+	StartedAt time.Time  `json:"started_at" firestore:"started_at"`                 // When the card was last scanned
+	ExpiresAt time.Time  `json:"expires_at" firestore:"expires_at"`                 // When the relationship lapses unless renewed by another scan
+	EndedAt   *time.Time `json:"ended_at,omitempty" firestore:"ended_at,omitempty"` // When it was ended
+	EndedBy   string     `json:"ended_by,omitempty" firestore:"ended_by,omitempty"` // UID of the doctor or patient who ended it
+	Blocked   bool       `json:"blocked,omitempty" firestore:"blocked,omitempty"`   // Set when the patient ended it; the doctor cannot restart it until the patient allows them
+}
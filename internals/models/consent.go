@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Consent scopes: the parts of a patient's record a consent can cover
+const (
+	ConsentScopeProfile       = "profile"       // Identity and contact details
+	ConsentScopePrescriptions = "prescriptions" // Prescriptions, issued and active
+	ConsentScopeHistory       = "history"       // Medical history entries and attachments
+)
+
+// Consent is a patient's permission for one practitioner (a doctor or
+// pharmacist) or organization (a hospital) to access parts of their record.
+// There is at most one consent per patient and grantee; granting again replaces it.
+type Consent struct {
+	ID          string     `json:"id" firestore:"id"`                                     // Firestore document ID, "<patient_id>_<grantee_id>"
+	PatientID   string     `json:"patient_id" firestore:"patient_id"`                     // Patient’s UID
+	GranteeID   string     `json:"grantee_id" firestore:"grantee_id"`                     // UID of the practitioner or organization
+	GranteeRole string     `json:"grantee_role" firestore:"grantee_role"`                 // "doctor", "pharmacist" or "hospital"
+	Scopes      []string   `json:"scopes" firestore:"scopes"`                             // Any of the ConsentScope values
+	Status      string     `json:"status" firestore:"status"`                             // "active" or "revoked"
+	CreatedAt   time.Time  `json:"created_at" firestore:"created_at"`                     // When the consent was granted
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" firestore:"expires_at,omitempty"` // Nil means until revoked
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" firestore:"revoked_at,omitempty"` // When the patient revoked it
+}
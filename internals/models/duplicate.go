@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DuplicateCandidate is a pair of patient records that may belong to the same
+// person, queued for an administrator to merge or dismiss
+type DuplicateCandidate struct {
+	ID         string     `json:"id" firestore:"id"`                                       // Firestore document ID, "<patient_id>_<patient_id>" in sorted order
+	PatientIDs []string   `json:"patient_ids" firestore:"patient_ids"`                     // The two patients’ UIDs, sorted
+	Score      float64    `json:"score" firestore:"score"`                                 // Match score from 0 to 1
+	Reasons    []string   `json:"reasons" firestore:"reasons"`                             // name, similar_name, sounds_like, date_of_birth, phone
+	Status     string     `json:"status" firestore:"status"`                               // "pending", "merging", "merged" or "dismissed"
+	DetectedAt time.Time  `json:"detected_at" firestore:"detected_at"`                     // When the pair was flagged
+	ReviewedBy string     `json:"reviewed_by,omitempty" firestore:"reviewed_by,omitempty"` // Administrator who merged or dismissed it
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" firestore:"reviewed_at,omitempty"` // When they did
+	SurvivorID string     `json:"survivor_id,omitempty" firestore:"survivor_id,omitempty"` // Record kept by a merge
+}
+
+// PatientMerge is the tombstone left for a merged-away patient record, keyed by
+// its UID. Its card and UID redirect to the survivor.
+type PatientMerge struct {
+	MergedID         string         `json:"merged_id" firestore:"merged_id"`                                     // Merged-away patient’s UID, also the document ID
+	SurvivorID       string         `json:"survivor_id" firestore:"survivor_id"`                                 // Patient the records moved to
+	NFCID            string         `json:"nfc_id" firestore:"nfc_id"`                                           // Merged-away patient’s card, which now resolves to the survivor
+	CandidateID      string         `json:"candidate_id" firestore:"candidate_id"`                               // Duplicate candidate that was merged
+	MergedBy         string         `json:"merged_by" firestore:"merged_by"`                                     // Administrator’s UID
+	MergedAt         time.Time      `json:"merged_at" firestore:"merged_at"`                                     // When the merge completed
+	Moved            map[string]int `json:"moved" firestore:"moved"`                                             // Collection -> documents moved to the survivor
+	PendingTransfers []string       `json:"pending_transfers,omitempty" firestore:"pending_transfers,omitempty"` // NFT token IDs that could not be moved to the survivor's wallet
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// EmergencySummary is the short view of a patient an emergency department needs
+// first, built from their user document, clinical profile and active
+// prescriptions
+type EmergencySummary struct {
+	PatientID         string              `json:"patient_id"`
+	Name              string              `json:"name"`
+	DateOfBirth       string              `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	BloodType         string              `json:"blood_type,omitempty"`
+	Allergies         []Allergy           `json:"allergies"`
+	Conditions        []string            `json:"conditions"`
+	Medications       []CurrentMedication `json:"medications"` // Active prescriptions; null when the caller may not see prescriptions
+	EmergencyContacts []EmergencyContact  `json:"emergency_contacts"`
+	HasProfile        bool                `json:"has_profile"`  // False when no clinical profile is recorded, so empty lists mean unknown rather than none
+	GeneratedAt       time.Time           `json:"generated_at"` // When the summary was built from the record
+	Compact           string              `json:"compact"`      // The summary as short plain text, for printing or a QR code
+}
+
+// CurrentMedication is an active prescription in an emergency summary
+type CurrentMedication struct {
+	Medication string    `json:"medication"`
+	Dosage     uint64    `json:"dosage"`
+	Since      time.Time `json:"since"` // When it was prescribed
+}
@@ -5,6 +5,8 @@ import "time"
 // HospitalPatientData represents the data returned for one-time hospital access
 type HospitalPatientData struct {
 	Patient        *User                  `json:"patient"`
+	Profile        *PatientProfile        `json:"profile,omitempty"` // Blood type, allergies, contacts and insurance, if recorded
+	Scopes         []string               `json:"scopes"`            // Consented parts of the record included
 	Prescriptions  []*Prescription        `json:"prescriptions"`
 	MedicalHistory []*MedicalHistoryEntry `json:"medical_history"`
 	AccessTime     time.Time              `json:"access_time"`
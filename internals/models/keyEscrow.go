@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// PatientDataKey is a patient's own data key, which seals their server-encrypted
+// records, stored wrapped under the master key. Keyed by patient UID.
+type PatientDataKey struct {
+	PatientID  string            `json:"patient_id" firestore:"patient_id"`   // Patient’s UID (also the document ID)
+	WrappedKey []byte            `json:"-" firestore:"wrapped_key"`           // AES-GCM sealed key
+	MergedKeys map[string][]byte `json:"-" firestore:"merged_keys,omitempty"` // Merged-away patient UID -> their wrapped key, which still seals the records they brought
+	CreatedAt  time.Time         `json:"created_at" firestore:"created_at"`   // When the key was generated
+}
+
+// KeyEscrow records how a patient's data key was split among custodians.
+// Each share is kept wrapped to its custodian's public key, so only that
+// custodian can read it and the server never holds a quorum in the clear.
+type KeyEscrow struct {
+	PatientID         string                `json:"patient_id" firestore:"patient_id"` // Patient’s UID (also the document ID)
+	Threshold         int                   `json:"threshold" firestore:"threshold"`   // Shares needed to rebuild the key
+	Custodians        []string              `json:"custodians" firestore:"custodians"` // UIDs of the custodians holding shares
+	Shares            map[string]WrappedKey `json:"-" firestore:"shares"`              // Custodian UID -> their share, wrapped to their public key
+	ShareFingerprints map[string]string     `json:"-" firestore:"share_fingerprints"`  // Custodian UID -> SHA-256 of their share
+	KeyFingerprint    string                `json:"-" firestore:"key_fingerprint"`     // SHA-256 of the escrowed key
+	CreatedAt         time.Time             `json:"created_at" firestore:"created_at"` // When the escrow was set up
+}
+
+// BreakGlassRequest is an emergency request to rebuild a patient's key from escrow
+type BreakGlassRequest struct {
+	ID          string            `json:"id" firestore:"id"`                     // Firestore document ID (UUID)
+	PatientID   string            `json:"patient_id" firestore:"patient_id"`     // Patient’s UID
+	NFCID       string            `json:"nfc_id" firestore:"nfc_id"`             // Patient’s NFC ID
+	RequesterID string            `json:"requester_id" firestore:"requester_id"` // Hospital UID that opened the request
+	Reason      string            `json:"reason" firestore:"reason"`             // Clinical justification
+	Status      string            `json:"status" firestore:"status"`             // "pending", "approved", "redeemed" or "expired"
+	Approvals   []string          `json:"approvals" firestore:"approvals"`       // Custodians who have submitted shares
+	Shares      map[string][]byte `json:"-" firestore:"shares"`                  // Submitted shares, wiped once redeemed
+	CreatedAt   time.Time         `json:"created_at" firestore:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at" firestore:"expires_at"`
+}
+
+// BreakGlassEvent logs each step of an emergency access for later review
+type BreakGlassEvent struct {
+	ID        string    `json:"id"`         // Firestore document ID (UUID)
+	RequestID string    `json:"request_id"` // Break-glass request this belongs to
+	PatientID string    `json:"patient_id"` // Patient’s UID
+	ActorID   string    `json:"actor_id"`   // Who acted (requester or custodian)
+	Action    string    `json:"action"`     // "requested", "share_collected", "approved", "redeemed" or "rejected"
+	Reason    string    `json:"reason"`     // Justification or failure detail
+	Time      time.Time `json:"time"`
+}
@@ -1,16 +1,75 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Medical history entry types, each with a JSON schema for its data
+const (
+	HistoryTypeDiagnosis    = "diagnosis"    // Condition with an ICD-10 code
+	HistoryTypeProcedure    = "procedure"    // Procedure performed on the patient
+	HistoryTypeLabResult    = "lab_result"   // Lab value with units and reference range
+	HistoryTypeVitalSigns   = "vital_signs"  // Blood pressure, pulse, temperature and the like
+	HistoryTypeImmunization = "immunization" // Vaccine dose
+	HistoryTypeNote         = "note"         // Free text
+)
 
 // MedicalHistory represents a patient’s medical history entry, linked to IPFS
 type MedicalHistory struct {
-	ID        string    `json:"id"`         // Firestore document ID (UUID)
-	UserID    string    `json:"user_id"`    // Patient’s UID
-	CID       string    `json:"cid"`        // IPFS Content Identifier for encrypted data
-	CreatedAt time.Time `json:"created_at"` // When the history was added
+	ID          string                `json:"id" firestore:"id"`                                         // Firestore document ID (UUID)
+	UserID      string                `json:"user_id" firestore:"user_id"`                               // Patient’s UID
+	CID         string                `json:"cid" firestore:"cid"`                                       // IPFS Content Identifier for encrypted data
+	CreatedAt   time.Time             `json:"created_at" firestore:"created_at"`                         // When the history was added
+	Type        string                `json:"type,omitempty" firestore:"type,omitempty"`                 // HistoryType of a server-encrypted entry; empty for free-text entries written before types
+	E2E         bool                  `json:"e2e" firestore:"e2e"`                                       // Encrypted by the client; the server cannot read it
+	AuthorID    string                `json:"author_id,omitempty" firestore:"author_id,omitempty"`       // Doctor who wrote the current version
+	WrappedKeys map[string]WrappedKey `json:"wrapped_keys,omitempty" firestore:"wrapped_keys,omitempty"` // Reader UID -> data key wrapped for them
+	Version     int                   `json:"version,omitempty" firestore:"version,omitempty"`           // Current version from 1; 0 on entries written before versions, which count as 1
+	Reason      string                `json:"reason,omitempty" firestore:"reason,omitempty"`             // Why the current version replaced the previous one
+	Retracted   bool                  `json:"retracted,omitempty" firestore:"retracted,omitempty"`       // The current version withdraws the entry and has no content
+	UpdatedAt   *time.Time            `json:"updated_at,omitempty" firestore:"updated_at,omitempty"`     // When the current version was written, if it is not the first
+	Versions    []HistoryVersion      `json:"versions,omitempty" firestore:"versions,omitempty"`         // Superseded versions, oldest first
+}
+
+// HistoryVersion is a superseded version of a medical history entry. Its blob
+// stays stored so the record can show what was corrected.
+type HistoryVersion struct {
+	Version   int       `json:"version" firestore:"version"`
+	CID       string    `json:"cid" firestore:"cid"`
+	Type      string    `json:"type,omitempty" firestore:"type,omitempty"`
+	AuthorID  string    `json:"author_id,omitempty" firestore:"author_id,omitempty"`
+	Reason    string    `json:"reason,omitempty" firestore:"reason,omitempty"` // Why this version replaced the one before it
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`             // When this version was written
 }
 
+// MedicalHistoryEntry is a decrypted history entry. Data holds the type's fields
+// as described by its schema; entries written before types are notes.
 type MedicalHistoryEntry struct {
-	History   string    `json:"history"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string          `json:"id"`
+	Version   int             `json:"version"`
+	Type      string          `json:"type,omitempty"` // One of the HistoryType values; empty on a retraction
+	Data      json.RawMessage `json:"data,omitempty"`
+	History   string          `json:"history,omitempty"` // Deprecated: the text of a note, kept for older clients
+	AuthorID  string          `json:"author_id,omitempty"`
+	Reason    string          `json:"reason,omitempty"` // Why this version replaced the previous one
+	Retracted bool            `json:"retracted,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`           // When the entry was added; in a version list, when the version was written
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"` // When the current version was written, if it is not the first
+}
+
+// MedicalHistoryVersions is every version of a medical history entry, oldest first
+type MedicalHistoryVersions struct {
+	ID        string                 `json:"id"`
+	PatientID string                 `json:"patient_id"`
+	Versions  []*MedicalHistoryEntry `json:"versions"`
+}
+
+// EncryptedHistoryEntry is an end-to-end entry as relayed to one reader
+type EncryptedHistoryEntry struct {
+	ID         string     `json:"id"`
+	AuthorID   string     `json:"author_id"`
+	Ciphertext []byte     `json:"ciphertext"`  // Content sealed with the data key
+	WrappedKey WrappedKey `json:"wrapped_key"` // Data key wrapped for the requesting reader
+	CreatedAt  time.Time  `json:"created_at"`
 }
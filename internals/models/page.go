@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PageQuery holds the paging, date range and sort options shared by list endpoints
+type PageQuery struct {
+	Cursor string    // NextCursor from the previous page; only valid with the same sort
+	Limit  int       // Items per page; each list has a default and a maximum
+	From   time.Time // Only items dated at or after From, if set
+	To     time.Time // Only items dated before To, if set
+	Sort   string    // A field the list can be sorted by, prefixed with "-" for descending; each list has a default
+}
+
+// Page is one page of a list endpoint's results
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass back as cursor for the next page; empty on the last page
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PatientProfile holds a patient's demographics and the clinical facts needed
+// in an emergency, kept apart from their user account. Blood type, allergies,
+// conditions, emergency contacts and insurance are stored encrypted in Sealed.
+type PatientProfile struct {
+	PatientID         string             `json:"patient_id" firestore:"patient_id"`                                                       // Patient’s UID, also the document ID
+	DateOfBirth       string             `json:"date_of_birth,omitempty" firestore:"date_of_birth" validate:"omitempty,date"`             // YYYY-MM-DD
+	Sex               string             `json:"sex,omitempty" firestore:"sex" validate:"omitempty,oneof=female male other unknown"`      // "female", "male", "other" or "unknown"
+	BloodType         string             `json:"blood_type,omitempty" firestore:"-" validate:"omitempty,oneof=A+ A- B+ B- AB+ AB- O+ O-"` // ABO and Rh, e.g. "O-"
+	Allergies         []Allergy          `json:"allergies" firestore:"-" validate:"max=50"`
+	Conditions        []string           `json:"conditions" firestore:"-" validate:"max=50,dive,required,max=200"` // Chronic conditions, e.g. "Type 1 diabetes"
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts" firestore:"-" validate:"max=5"`
+	Insurance         *Insurance         `json:"insurance,omitempty" firestore:"-"`
+	Sealed            []byte             `json:"-" firestore:"sealed"`              // Encrypted sensitive fields
+	UpdatedAt         time.Time          `json:"updated_at" firestore:"updated_at"` // When the profile was last saved
+	UpdatedBy         string             `json:"updated_by" firestore:"updated_by"` // UID of the patient or doctor who saved it
+}
+
+// Allergy is a substance the patient reacts to
+type Allergy struct {
+	Substance string `json:"substance" validate:"required,max=200"`                              // e.g. "Penicillin"
+	Reaction  string `json:"reaction,omitempty" validate:"max=200"`                              // e.g. "Hives"
+	Severity  string `json:"severity,omitempty" validate:"omitempty,oneof=mild moderate severe"` // "mild", "moderate" or "severe"
+}
+
+// EmergencyContact is someone to call when the patient cannot speak for themselves
+type EmergencyContact struct {
+	Name         string `json:"name" validate:"required,max=200"`
+	Relationship string `json:"relationship,omitempty" validate:"max=200"` // e.g. "Spouse"
+	Phone        string `json:"phone" validate:"required,phone,max=200"`
+}
+
+// Insurance is the patient's health cover
+type Insurance struct {
+	Provider     string `json:"provider" validate:"required,max=200"`
+	PolicyNumber string `json:"policy_number" validate:"required,max=200"`
+	GroupNumber  string `json:"group_number,omitempty" validate:"max=200"`
+	ExpiresOn    string `json:"expires_on,omitempty" validate:"omitempty,date"` // YYYY-MM-DD
+}
+
+// Tidy trims the profile's free text and capitalizes its blood type, so a
+// value of only spaces counts as missing
+func (p *PatientProfile) Tidy() {
+	p.BloodType = strings.ToUpper(strings.TrimSpace(p.BloodType))
+	for i := range p.Allergies {
+		allergy := &p.Allergies[i]
+		allergy.Substance = strings.TrimSpace(allergy.Substance)
+		allergy.Reaction = strings.TrimSpace(allergy.Reaction)
+	}
+	for i, condition := range p.Conditions {
+		p.Conditions[i] = strings.TrimSpace(condition)
+	}
+	for i := range p.EmergencyContacts {
+		contact := &p.EmergencyContacts[i]
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Relationship = strings.TrimSpace(contact.Relationship)
+	}
+	if insurance := p.Insurance; insurance != nil {
+		insurance.Provider = strings.TrimSpace(insurance.Provider)
+		insurance.PolicyNumber = strings.TrimSpace(insurance.PolicyNumber)
+		insurance.GroupNumber = strings.TrimSpace(insurance.GroupNumber)
+	}
+}
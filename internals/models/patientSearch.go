@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PatientSearchEntry is a patient's document in the search index, derived from
+// their user document and rewritten whenever it changes
+type PatientSearchEntry struct {
+	PatientID   string    `json:"patient_id" firestore:"patient_id"`       // Patient’s UID, also the document ID
+	Name        string    `json:"name" firestore:"name"`                   // Name as entered
+	NameKey     string    `json:"name_key" firestore:"name_key"`           // Normalized name, the sort key
+	Tokens      []string  `json:"tokens" firestore:"tokens"`               // Normalized words of the name
+	Prefixes    []string  `json:"prefixes" firestore:"prefixes"`           // Prefixes of each word, for as-you-type lookups
+	Phonetic    []string  `json:"phonetic" firestore:"phonetic"`           // Soundex and Double Metaphone codes of each word
+	DateOfBirth string    `json:"date_of_birth" firestore:"date_of_birth"` // YYYY-MM-DD, empty if unknown
+	NFCID       string    `json:"nfc_id" firestore:"nfc_id"`
+	Phone       string    `json:"phone" firestore:"phone"` // Digits only, empty if unknown
+	UpdatedAt   time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+// PatientSearchQuery selects patients from the search index; empty fields match everything
+type PatientSearchQuery struct {
+	Name        string   // Each word must prefix a word of the patient's name
+	DateOfBirth string   // YYYY-MM-DD
+	NFCID       string   // Exact card ID
+	PatientIDs  []string // Restricts the search to these patients when not nil
+	Cursor      string   // NextCursor from the previous page
+	Limit       int
+}
+
+// PatientMatchQuery looks for patients whose names are spelled or sound like Name,
+// ranked by how closely they match
+type PatientMatchQuery struct {
+	Name        string   // Required
+	DateOfBirth string   // YYYY-MM-DD; raises the score of patients born that day
+	Phone       string   // Raises the score of patients with this number
+	FullName    bool     // Compare whole names, as between two records, rather than a typed query
+	PatientIDs  []string // Restricts the match to these patients when not nil
+	MinScore    float64  // Matches scoring lower are dropped; defaults to 0.6
+	Limit       int
+}
+
+// PatientMatch is a ranked fuzzy match
+type PatientMatch struct {
+	PatientID string   `json:"patient_id"`
+	Patient   *User    `json:"patient,omitempty"`
+	Score     float64  `json:"score"`   // 0 to 1, higher is closer
+	Reasons   []string `json:"reasons"` // name, similar_name, sounds_like, date_of_birth, phone
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PinRecord tracks a stored blob and the record that references it
+type PinRecord struct {
+	CID            string     `json:"cid" firestore:"cid"`                                               // Blob identifier (also the document ID)
+	PatientID      string     `json:"patient_id" firestore:"patient_id"`                                 // Patient’s UID
+	RecordType     string     `json:"record_type" firestore:"record_type"`                               // "medical_history" or "attachment"
+	RecordID       string     `json:"record_id" firestore:"record_id"`                                   // Firestore document ID of the record
+	Status         string     `json:"status" firestore:"status"`                                         // "pinned", "missing", "orphaned" or "unpinned"
+	PinnedAt       time.Time  `json:"pinned_at" firestore:"pinned_at"`                                   // When the blob was first pinned
+	LastVerifiedAt *time.Time `json:"last_verified_at,omitempty" firestore:"last_verified_at,omitempty"` // Last successful verification
+	OrphanedAt     *time.Time `json:"orphaned_at,omitempty" firestore:"orphaned_at,omitempty"`           // When verification first found no record referencing it
+	UnpinnedAt     *time.Time `json:"unpinned_at,omitempty" firestore:"unpinned_at,omitempty"`           // When the blob was released
+}
+
+// PinReport summarises one verification pass over all referenced blobs
+type PinReport struct {
+	ID         string    `json:"id" firestore:"id"`
+	StartedAt  time.Time `json:"started_at" firestore:"started_at"`
+	FinishedAt time.Time `json:"finished_at" firestore:"finished_at"`
+	Checked    int       `json:"checked" firestore:"checked"`   // Referenced blobs checked
+	Repinned   []string  `json:"repinned" firestore:"repinned"` // Referenced blobs found unpinned and pinned again
+	Missing    []string  `json:"missing" firestore:"missing"`   // Referenced blobs that could not be pinned
+	Orphaned   []string  `json:"orphaned" firestore:"orphaned"` // Pinned blobs no record references
+	Unpinned   []string  `json:"unpinned" firestore:"unpinned"` // Orphans released during this pass
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PublicKey is a user's published X25519 key for end-to-end encrypted records
+type PublicKey struct {
+	UserID    string    `json:"user_id" firestore:"user_id"`       // Owner’s UID (also the document ID)
+	Key       []byte    `json:"key" firestore:"key"`               // Raw 32-byte X25519 public key
+	Algorithm string    `json:"algorithm" firestore:"algorithm"`   // Always "x25519" for now
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"` // When the key was last published
+}
+
+// WrappedKey is a record's data key encrypted to one reader's public key
+type WrappedKey struct {
+	EphemeralKey []byte `json:"ephemeral_key" firestore:"ephemeral_key" validate:"required,len=32"` // Sender’s ephemeral X25519 public key
+	Ciphertext   []byte `json:"ciphertext" firestore:"ciphertext" validate:"required"`              // AES-GCM sealed data key
+}
@@ -5,9 +5,10 @@ import "time"
 
 // Transaction logs hospital one-time access events
 type Transaction struct {
-	ID         string    `json:"id"`          // Firestore document ID (UUID)
-	UserID     string    `json:"user_id"`     // Hospital’s UID
-	PatientID  string    `json:"patient_id"`  // Patient’s UID accessed
-	NFCID      string    `json:"nfc_id"`      // Patient’s NFC ID
-	AccessTime time.Time `json:"access_time"` // When access occurred
+	ID         string    `json:"id" firestore:"id"`                                 // Firestore document ID (UUID)
+	UserID     string    `json:"user_id" firestore:"user_id"`                       // Hospital’s UID
+	PatientID  string    `json:"patient_id" firestore:"patient_id"`                 // Patient’s UID accessed
+	NFCID      string    `json:"nfc_id" firestore:"nfc_id"`                         // Patient’s NFC ID
+	GrantID    string    `json:"grant_id,omitempty" firestore:"grant_id,omitempty"` // Access grant that was redeemed
+	AccessTime time.Time `json:"access_time" firestore:"access_time"`               // When access occurred
 }
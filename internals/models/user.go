@@ -5,9 +5,12 @@ import "time"
 // User represents a user in the system (patient, doctor, pharmacist, hospital)
 type User struct {
 	UID           string    `json:"uid"`                      // Firestore document ID (Firebase UID)
-	NFCID         string    `json:"nfc_id"`                   // Unique NFC card identifier
+	NFCID         string    `json:"nfc_id,omitempty"`         // Unique NFC card identifier; withheld from doctors
 	Name          string    `json:"name"`                     // User’s full name
-	Role          string    `json:"role"`                     // Role: "patient", "doctor", "pharmacist", "hospital"
+	DateOfBirth   string    `json:"date_of_birth,omitempty"`  // Patients only, YYYY-MM-DD
+	Phone         string    `json:"phone,omitempty"`          // Contact number as entered
+	Role          string    `json:"role"`                     // Role: "patient", "doctor", "pharmacist", "hospital"; "merged" for a merged-away patient
 	WalletAddress string    `json:"wallet_address,omitempty"` // Optional for NFT interactions
+	MergedInto    string    `json:"merged_into,omitempty"`    // Surviving patient's UID once this record is merged
 	CreatedAt     time.Time `json:"created_at"`               // When the user was registered
 }
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const accessGrantTTL = 5 * time.Minute // How long a hospital has to redeem an access grant
+
+// AccessGrantService issues and redeems single-use hospital access grants
+type AccessGrantService struct {
+	Firestore *firebase.FirestoreClient
+	Consents  *ConsentService
+}
+
+// NewAccessGrantService creates a new AccessGrantService instance
+func NewAccessGrantService(firestore *firebase.FirestoreClient) *AccessGrantService {
+	return &AccessGrantService{
+		Firestore: firestore,
+		Consents:  NewConsentService(firestore),
+	}
+}
+
+// IssuePatientGrant lets a patient allow one hospital a single read of their data
+func (gs *AccessGrantService) IssuePatientGrant(ctx context.Context, patientID, hospitalID string) (*models.AccessGrant, error) {
+	patient, err := firebase.GetUserByUID(ctx, gs.Firestore.Client, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.Role != "patient" {
+		return nil, errs.Invalid("User is not a patient: " + patientID)
+	}
+	return gs.issueGrant(ctx, patient, hospitalID, patientID, "patient")
+}
+
+// IssueDoctorGrant lets a doctor who has scanned the patient's card allow one
+// hospital a single read of the patient's data, e.g. when referring them on
+func (gs *AccessGrantService) IssueDoctorGrant(ctx context.Context, doctorID, nfcID, hospitalID string) (*models.AccessGrant, error) {
+	patient, err := findPatientByNFC(ctx, gs.Firestore, nfcID)
+	if err != nil {
+		return nil, err
+	}
+	return gs.issueGrant(ctx, patient, hospitalID, doctorID, "doctor")
+}
+
+// issueGrant saves a new active grant for a patient and hospital
+func (gs *AccessGrantService) issueGrant(ctx context.Context, patient *models.User, hospitalID, issuerID, issuerRole string) (*models.AccessGrant, error) {
+	// Step 1: Grants are bound to a specific hospital account
+	hospital, err := firebase.GetUserByUID(ctx, gs.Firestore.Client, hospitalID)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if err != nil || hospital.Role != "hospital" {
+		return nil, errs.InvalidFields(errs.FieldError{Field: "hospital_id", Message: "must be an existing hospital"})
+	}
+
+	// Step 2: Save the grant; its random ID is what the hospital presents
+	now := time.Now().UTC()
+	grant := &models.AccessGrant{
+		ID:         uuid.New().String(),
+		PatientID:  patient.UID,
+		NFCID:      patient.NFCID,
+		HospitalID: hospitalID,
+		IssuedBy:   issuerID,
+		IssuerRole: issuerRole,
+		Status:     "active",
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(accessGrantTTL),
+	}
+	if _, err := gs.Firestore.Client.Collection("access_grants").Doc(grant.ID).Create(ctx, grant); err != nil {
+		log.Printf("Failed to save access grant: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Access grant %s issued by %s %s for patient %s to hospital %s", grant.ID, issuerRole, issuerID, patient.UID, hospitalID)
+	return grant, nil
+}
+
+// RedeemGrant consumes a grant for the hospital reading the patient with nfcID.
+// The checks and the status change happen in one transaction, so concurrent
+// requests with the same grant cannot both succeed. A hospital the patient has
+// not consented to is refused before the grant is used up. The access is logged
+// to the transactions collection in the same transaction.
+func (gs *AccessGrantService) RedeemGrant(ctx context.Context, grantID, hospitalID, nfcID string) (*models.AccessGrant, error) {
+	var grant models.AccessGrant
+	ref := gs.Firestore.Client.Collection("access_grants").Doc(grantID)
+	err := gs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errs.NotFound("Access grant not found: " + grantID)
+			}
+			return err
+		}
+		if err := doc.DataTo(&grant); err != nil {
+			log.Printf("Failed to parse access grant: %v", err)
+			return err
+		}
+
+		// Checked before anything is written, so a mismatched request leaves the grant usable
+		if grant.HospitalID != hospitalID {
+			return errs.Forbidden("Access grant was issued to another hospital")
+		}
+		if grant.NFCID != nfcID {
+			return errs.Forbidden("Access grant was issued for another patient")
+		}
+		now := time.Now().UTC()
+		if grant.Status == "active" && now.After(grant.ExpiresAt) {
+			grant.Status = "expired"
+		}
+		if grant.Status != "active" {
+			return errs.Conflict("Access grant is " + grant.Status)
+		}
+		if _, err := gs.Consents.AuthorizedScopes(ctx, hospitalID, grant.PatientID); err != nil {
+			return err
+		}
+
+		grant.Status = "redeemed"
+		grant.RedeemedAt = &now
+		if err := tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: grant.Status},
+			{Path: "redeemed_at", Value: now},
+		}); err != nil {
+			return err
+		}
+
+		transaction := models.Transaction{
+			ID:         uuid.New().String(),
+			UserID:     hospitalID,
+			PatientID:  grant.PatientID,
+			NFCID:      grant.NFCID,
+			GrantID:    grant.ID,
+			AccessTime: now,
+		}
+		return tx.Create(gs.Firestore.Client.Collection("transactions").Doc(transaction.ID), transaction)
+	})
+	if err != nil {
+		log.Printf("Failed to redeem access grant %s: %v", grantID, err)
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// SweepExpiredGrants marks active grants past their deadline as expired and
+// returns how many were swept. Redemption checks the deadline itself, so this
+// only keeps the collection's status accurate; missed sweeps are caught up on
+// the next run, including after a restart.
+func (gs *AccessGrantService) SweepExpiredGrants(ctx context.Context) (int, error) {
+	// Needs a composite index on (status, expires_at)
+	docs, err := gs.Firestore.Client.Collection("access_grants").
+		Where("status", "==", "active").
+		Where("expires_at", "<", time.Now().UTC()).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query expired access grants: %v", err)
+		return 0, err
+	}
+
+	batch := gs.Firestore.Client.Batch()
+	pending := 0
+	for _, doc := range docs {
+		batch.Update(doc.Ref, []firestore.Update{{Path: "status", Value: "expired"}})
+		pending++
+		if pending == writeBatchSize {
+			if _, err := batch.Commit(ctx); err != nil {
+				log.Printf("Failed to expire access grants: %v", err)
+				return 0, err
+			}
+			batch = gs.Firestore.Client.Batch()
+			pending = 0
+		}
+	}
+	if pending > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			log.Printf("Failed to expire access grants: %v", err)
+			return 0, err
+		}
+	}
+
+	if len(docs) > 0 {
+		log.Printf("Expired %d access grants", len(docs))
+	}
+	return len(docs), nil
+}
+
+// RunSweeper sweeps expired grants on startup and then every interval until ctx
+// is cancelled
+func (gs *AccessGrantService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := gs.SweepExpiredGrants(ctx); err != nil {
+			log.Printf("Access grant sweep failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
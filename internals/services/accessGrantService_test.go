@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRedeemGrantNeedsConsent(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	grants := NewAccessGrantService(fs)
+	hospitalID, patientID := testUser(t, fs, "hospital"), testUser(t, fs, "patient")
+
+	grant, err := grants.IssuePatientGrant(ctx, patientID, hospitalID)
+	if err != nil {
+		t.Fatalf("IssuePatientGrant: %v", err)
+	}
+
+	// Without consent the grant is refused but stays usable
+	if _, err := grants.RedeemGrant(ctx, grant.ID, hospitalID, grant.NFCID); !errors.Is(err, ErrConsentRequired) {
+		t.Fatalf("RedeemGrant without consent = %v, want ErrConsentRequired", err)
+	}
+	if _, err := NewConsentService(fs).GrantConsent(ctx, patientID, hospitalID, consentScopes, nil); err != nil {
+		t.Fatalf("GrantConsent: %v", err)
+	}
+	redeemed, err := grants.RedeemGrant(ctx, grant.ID, hospitalID, grant.NFCID)
+	if err != nil {
+		t.Fatalf("RedeemGrant with consent: %v", err)
+	}
+	if redeemed.Status != "redeemed" {
+		t.Errorf("status = %q, want redeemed", redeemed.Status)
+	}
+
+	if _, err := grants.RedeemGrant(ctx, grant.ID, hospitalID, grant.NFCID); err == nil {
+		t.Error("redeemed the same grant twice")
+	}
+}
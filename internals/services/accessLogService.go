@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+)
+
+// accessLogRoles are the practitioners whose accesses patients can review
+var accessLogRoles = []string{"doctor", "pharmacist", "hospital"}
+
+// AccessLogService shows patients who has accessed their data, from the audit log
+type AccessLogService struct {
+	Firestore *firebase.FirestoreClient // Users, for practitioners' names
+	Audit     *AuditService
+}
+
+// NewAccessLogService creates a new AccessLogService instance
+func NewAccessLogService(firestore *firebase.FirestoreClient, audit *AuditService) *AccessLogService {
+	return &AccessLogService{
+		Firestore: firestore,
+		Audit:     audit,
+	}
+}
+
+// GetAccessLog returns a page of the practitioners' accesses to a patient's
+// data, newest first. q's role and page options are honoured; the patient is
+// always patientID and the patient's own requests are left out.
+func (ls *AccessLogService) GetAccessLog(ctx context.Context, patientID string, q models.AuditQuery) (*models.Page[*models.AccessLogEntry], error) {
+	// Step 1: Restrict the search to this patient and to practitioners
+	q.PatientID = patientID
+	q.ActorID = ""
+	if q.ActorRole != "" && !slices.Contains(accessLogRoles, q.ActorRole) {
+		return nil, errs.Invalid("Role must be one of doctor, pharmacist or hospital")
+	}
+	q.ActorRoles = accessLogRoles
+
+	entries, nextCursor, err := ls.Audit.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Name each practitioner once
+	names := make(map[string]string)
+	accesses := &models.Page[*models.AccessLogEntry]{Items: make([]*models.AccessLogEntry, 0, len(entries)), NextCursor: nextCursor}
+	for _, entry := range entries {
+		name, ok := names[entry.ActorID]
+		if !ok {
+			if user, err := firebase.GetUserByUID(ctx, ls.Firestore.Client, entry.ActorID); err == nil {
+				name = user.Name
+			} else {
+				log.Printf("Showing access by %s without a name: %v", entry.ActorID, err)
+			}
+			names[entry.ActorID] = name
+		}
+		accesses.Items = append(accesses.Items, &models.AccessLogEntry{
+			Time:      entry.Time,
+			ActorID:   entry.ActorID,
+			ActorName: name,
+			ActorRole: entry.ActorRole,
+			Resource:  entry.Resource,
+			Action:    entry.Action,
+			Purpose:   entry.Purpose,
+			Outcome:   entry.Outcome,
+		})
+	}
+	return accesses, nil
+}
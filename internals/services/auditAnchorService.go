@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/blockchain"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxAnchorBatch = 10000 // Most entries covered by a single anchor
+	auditPageSize  = 1000  // Entries read per query when walking the chain
+)
+
+// AuditAnchorService publishes Merkle roots over the audit log's hash chain on
+// chain and proves entries against them. Entries are read through their chain
+// links. Anchors are stored under their first sequence number, so two
+// instances anchoring at once cannot both record one.
+type AuditAnchorService struct {
+	Firestore  *firebase.FirestoreClient // The audit log's client
+	Blockchain *blockchain.Client
+}
+
+// NewAuditAnchorService creates a new AuditAnchorService instance
+func NewAuditAnchorService(firestore *firebase.FirestoreClient, blockchain *blockchain.Client) *AuditAnchorService {
+	return &AuditAnchorService{
+		Firestore:  firestore,
+		Blockchain: blockchain,
+	}
+}
+
+// AnchorPending anchors the entries appended since the last anchor, up to
+// maxAnchorBatch of them, and returns the new anchor or nil if there were none.
+// The entries' chain is checked first so a tampered log is never anchored.
+func (as *AuditAnchorService) AnchorPending(ctx context.Context) (*models.AuditAnchor, error) {
+	// Step 1: Pick up after the last anchor
+	last, err := as.lastAnchor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var fromSeq int64 = 1
+	if last != nil {
+		fromSeq = last.ToSeq + 1
+	}
+	entries, err := as.entryRange(ctx, fromSeq, fromSeq+maxAnchorBatch-1)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// Step 2: Check the new entries link onto the anchored ones
+	var prev *models.AuditEntry
+	if fromSeq > 1 {
+		if prev, err = as.entryAt(ctx, fromSeq-1); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkAuditChain(prev, entries); err != nil {
+		return nil, err
+	}
+	if entries[0].Seq != fromSeq {
+		return nil, fmt.Errorf("Audit chain is missing entry %d", fromSeq)
+	}
+
+	// Step 3: Publish the root
+	leaves, err := auditLeaves(entries)
+	if err != nil {
+		return nil, err
+	}
+	root, err := crypto.MerkleRoot(leaves)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := as.Blockchain.AnchorRoot(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Record the anchor
+	anchor := &models.AuditAnchor{
+		ID:        auditSeqID(fromSeq),
+		FromSeq:   fromSeq,
+		ToSeq:     entries[len(entries)-1].Seq,
+		Root:      hex.EncodeToString(root),
+		TxHash:    txHash,
+		ChainID:   as.Blockchain.ChainID.String(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := as.Firestore.Client.Collection("audit_anchors").Doc(anchor.ID).Create(ctx, anchor); err != nil {
+		log.Printf("Failed to save audit anchor for entries %d-%d (transaction %s): %v", anchor.FromSeq, anchor.ToSeq, txHash, err)
+		return nil, err
+	}
+
+	log.Printf("Anchored audit entries %d-%d in transaction %s", anchor.FromSeq, anchor.ToSeq, txHash)
+	return anchor, nil
+}
+
+// RunAnchorer anchors new audit entries on startup and then every interval
+// until ctx is cancelled. A backlog larger than one batch is worked off without
+// waiting for the next tick.
+func (as *AuditAnchorService) RunAnchorer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			anchor, err := as.AnchorPending(ctx)
+			if err != nil {
+				log.Printf("Audit anchoring failed: %v", err)
+			}
+			if anchor == nil || anchor.ToSeq-anchor.FromSeq+1 < maxAnchorBatch {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProveEntry checks that an audit entry matches its hash and links to the entry
+// before it, and, if it has been anchored, that it is included under a root
+// carried by a transaction from this server. The returned proof lets anyone
+// repeat the Merkle check against the on-chain root.
+func (as *AuditAnchorService) ProveEntry(ctx context.Context, entryID string) (*models.AuditProof, error) {
+	// Step 1: The entry must be intact and chained to its predecessor
+	entry, err := as.getEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Seq == 0 {
+		return nil, errs.Conflict("Audit entry " + entryID + " has not been linked into the chain yet")
+	}
+	var prev *models.AuditEntry
+	if entry.Seq > 1 {
+		if prev, err = as.entryAt(ctx, entry.Seq-1); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkAuditChain(prev, []*models.AuditEntry{entry}); err != nil {
+		return nil, err
+	}
+	proof := &models.AuditProof{Entry: entry}
+
+	// Step 2: Find the anchor covering it
+	anchor, err := as.anchorFor(ctx, entry.Seq)
+	if err != nil {
+		return nil, err
+	}
+	if anchor == nil {
+		return proof, nil
+	}
+
+	// Step 3: Rebuild the anchored batch and the entry's path to its root
+	entries, err := as.entryRange(ctx, anchor.FromSeq, anchor.ToSeq)
+	if err != nil {
+		return nil, err
+	}
+	if err := as.checkAnchoredBatch(anchor, entries); err != nil {
+		return nil, err
+	}
+	leaves, err := auditLeaves(entries)
+	if err != nil {
+		return nil, err
+	}
+	path, err := crypto.MerkleProof(leaves, int(entry.Seq-anchor.FromSeq))
+	if err != nil {
+		return nil, err
+	}
+	root, _ := hex.DecodeString(anchor.Root)
+	if !crypto.VerifyMerkleProof(leaves[entry.Seq-anchor.FromSeq], path, root) {
+		return nil, errors.New("Audit entry " + entryID + " is not covered by its anchor's root")
+	}
+
+	// Step 4: The root must be the one published on chain
+	if err := as.Blockchain.VerifyAnchor(ctx, anchor.TxHash, root); err != nil {
+		return nil, err
+	}
+
+	proof.Anchor = anchor
+	proof.Path = path
+	proof.Anchored = true
+	return proof, nil
+}
+
+// VerifyChain walks the whole audit log, checking every entry's hash and link
+// and every anchor's root against the chain. It returns the number of entries
+// and anchors checked.
+func (as *AuditAnchorService) VerifyChain(ctx context.Context) (int, int, error) {
+	anchors, err := as.allAnchors(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var prev *models.AuditEntry
+	var batch []*models.AuditEntry
+	checked, next := 0, 0
+	var last int64
+	for {
+		// Entries not linked yet have no place in the chain to check
+		entries, err := as.entryRange(ctx, last+1, last+auditPageSize)
+		if err != nil {
+			return checked, next, err
+		}
+		if prev == nil && len(entries) > 0 && entries[0].Seq != 1 {
+			return checked, next, errors.New("Audit chain does not start at entry 1")
+		}
+		if err := checkAuditChain(prev, entries); err != nil {
+			return checked, next, err
+		}
+
+		// Close each anchor once its last entry has been read
+		for _, entry := range entries {
+			checked++
+			if next < len(anchors) && entry.Seq >= anchors[next].FromSeq {
+				batch = append(batch, entry)
+				if entry.Seq == anchors[next].ToSeq {
+					if err := as.checkAnchoredBatch(anchors[next], batch); err != nil {
+						return checked, next, err
+					}
+					root, _ := hex.DecodeString(anchors[next].Root)
+					if err := as.Blockchain.VerifyAnchor(ctx, anchors[next].TxHash, root); err != nil {
+						return checked, next, err
+					}
+					batch = nil
+					next++
+				}
+			}
+		}
+
+		if len(entries) == 0 {
+			break
+		}
+		prev = entries[len(entries)-1]
+		last = prev.Seq
+	}
+
+	// A missing link would end the walk early, before the head
+	head, err := as.lastLink(ctx)
+	if err != nil {
+		return checked, next, err
+	}
+	if head.Seq != last {
+		return checked, next, fmt.Errorf("Audit chain is missing links between entries %d and %d", last, head.Seq)
+	}
+	if next < len(anchors) {
+		return checked, next, fmt.Errorf("Audit log ends before anchored entry %d", anchors[next].ToSeq)
+	}
+	return checked, next, nil
+}
+
+// checkAnchoredBatch confirms entries are exactly the run an anchor covers and
+// that their Merkle root is the anchor's
+func (as *AuditAnchorService) checkAnchoredBatch(anchor *models.AuditAnchor, entries []*models.AuditEntry) error {
+	if int64(len(entries)) != anchor.ToSeq-anchor.FromSeq+1 || entries[0].Seq != anchor.FromSeq {
+		return fmt.Errorf("Audit entries %d-%d are incomplete", anchor.FromSeq, anchor.ToSeq)
+	}
+	if err := checkAuditChain(nil, entries); err != nil {
+		return err
+	}
+	leaves, err := auditLeaves(entries)
+	if err != nil {
+		return err
+	}
+	root, err := crypto.MerkleRoot(leaves)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(root) != anchor.Root {
+		return fmt.Errorf("Audit entries %d-%d do not match their anchored root", anchor.FromSeq, anchor.ToSeq)
+	}
+	return nil
+}
+
+// getEntry loads one audit entry by document ID
+func (as *AuditAnchorService) getEntry(ctx context.Context, entryID string) (*models.AuditEntry, error) {
+	doc, err := as.Firestore.Client.Collection("audit_log").Doc(entryID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NotFound("Audit entry not found: " + entryID)
+		}
+		log.Printf("Failed to fetch audit entry %s: %v", entryID, err)
+		return nil, err
+	}
+	var entry models.AuditEntry
+	if err := doc.DataTo(&entry); err != nil {
+		log.Printf("Failed to parse audit entry %s: %v", entryID, err)
+		return nil, err
+	}
+	if err := attachAuditLinks(ctx, as.Firestore.Client, []*models.AuditEntry{&entry}); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// entryAt loads the entry at a position in the chain
+func (as *AuditAnchorService) entryAt(ctx context.Context, seq int64) (*models.AuditEntry, error) {
+	entries, err := as.entryRange(ctx, seq, seq)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("Audit chain has %d entries at position %d", len(entries), seq)
+	}
+	return entries[0], nil
+}
+
+// entryRange loads the entries linked at positions fromSeq to toSeq, in order.
+// A link whose entry is missing means the entry was removed.
+func (as *AuditAnchorService) entryRange(ctx context.Context, fromSeq, toSeq int64) ([]*models.AuditEntry, error) {
+	client := as.Firestore.Client
+	docs, err := client.Collection("audit_chain").
+		Where("seq", ">=", fromSeq).
+		Where("seq", "<=", toSeq).
+		OrderBy("seq", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to read audit links %d-%d: %v", fromSeq, toSeq, err)
+		return nil, err
+	}
+	links := make([]*models.AuditLink, len(docs))
+	refs := make([]*firestore.DocumentRef, len(docs))
+	for i, doc := range docs {
+		links[i] = &models.AuditLink{}
+		if err := doc.DataTo(links[i]); err != nil {
+			log.Printf("Failed to parse audit link %s: %v", doc.Ref.ID, err)
+			return nil, err
+		}
+		refs[i] = client.Collection("audit_log").Doc(links[i].EntryID)
+	}
+
+	entryDocs, err := client.GetAll(ctx, refs)
+	if err != nil {
+		log.Printf("Failed to read audit entries %d-%d: %v", fromSeq, toSeq, err)
+		return nil, err
+	}
+	entries := make([]*models.AuditEntry, len(entryDocs))
+	for i, doc := range entryDocs {
+		if !doc.Exists() {
+			return nil, fmt.Errorf("Audit entry %s at position %d is missing", links[i].EntryID, links[i].Seq)
+		}
+		entries[i] = &models.AuditEntry{}
+		if err := doc.DataTo(entries[i]); err != nil {
+			log.Printf("Failed to parse audit entry %s: %v", doc.Ref.ID, err)
+			return nil, err
+		}
+		entries[i].Seq, entries[i].PrevHash, entries[i].Hash = links[i].Seq, links[i].PrevHash, links[i].Hash
+	}
+	return entries, nil
+}
+
+// lastLink returns the head of the chain, or a zero link if it is empty
+func (as *AuditAnchorService) lastLink(ctx context.Context) (*models.AuditLink, error) {
+	docs, err := as.Firestore.Client.Collection("audit_chain").
+		OrderBy("seq", firestore.Desc).Limit(1).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to read audit chain head: %v", err)
+		return nil, err
+	}
+	head := &models.AuditLink{}
+	if len(docs) > 0 {
+		if err := docs[0].DataTo(head); err != nil {
+			log.Printf("Failed to parse audit link %s: %v", docs[0].Ref.ID, err)
+			return nil, err
+		}
+	}
+	return head, nil
+}
+
+// lastAnchor returns the most recent anchor, or nil if nothing has been anchored
+func (as *AuditAnchorService) lastAnchor(ctx context.Context) (*models.AuditAnchor, error) {
+	docs, err := as.Firestore.Client.Collection("audit_anchors").
+		OrderBy("from_seq", firestore.Desc).Limit(1).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to read audit anchors: %v", err)
+		return nil, err
+	}
+	anchors, err := parseAuditAnchors(docs)
+	if err != nil || len(anchors) == 0 {
+		return nil, err
+	}
+	return anchors[0], nil
+}
+
+// anchorFor returns the anchor covering seq, or nil if it is not anchored yet
+func (as *AuditAnchorService) anchorFor(ctx context.Context, seq int64) (*models.AuditAnchor, error) {
+	docs, err := as.Firestore.Client.Collection("audit_anchors").
+		Where("from_seq", "<=", seq).
+		OrderBy("from_seq", firestore.Desc).Limit(1).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to read audit anchors: %v", err)
+		return nil, err
+	}
+	anchors, err := parseAuditAnchors(docs)
+	if err != nil || len(anchors) == 0 || anchors[0].ToSeq < seq {
+		return nil, err
+	}
+	return anchors[0], nil
+}
+
+// allAnchors returns every anchor in sequence order
+func (as *AuditAnchorService) allAnchors(ctx context.Context) ([]*models.AuditAnchor, error) {
+	docs, err := as.Firestore.Client.Collection("audit_anchors").
+		OrderBy("from_seq", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to read audit anchors: %v", err)
+		return nil, err
+	}
+	return parseAuditAnchors(docs)
+}
+
+// checkAuditChain recomputes each entry's hash and checks it links to the one
+// before; prev may be nil when the first entry's predecessor is not at hand
+func checkAuditChain(prev *models.AuditEntry, entries []*models.AuditEntry) error {
+	for _, entry := range entries {
+		if auditEntryHash(entry) != entry.Hash {
+			return errors.New("Audit entry " + entry.ID + " does not match its hash")
+		}
+		if prev == nil && entry.Seq == 1 && entry.PrevHash != "" {
+			return errors.New("Audit entry " + entry.ID + " claims a predecessor")
+		}
+		if prev != nil && (entry.Seq != prev.Seq+1 || entry.PrevHash != prev.Hash) {
+			return errors.New("Audit chain is broken between entries " + prev.ID + " and " + entry.ID)
+		}
+		prev = entry
+	}
+	return nil
+}
+
+// auditLeaves returns the entries' hashes as Merkle leaves
+func auditLeaves(entries []*models.AuditEntry) ([][]byte, error) {
+	leaves := make([][]byte, len(entries))
+	for i, entry := range entries {
+		leaf, err := hex.DecodeString(entry.Hash)
+		if err != nil {
+			log.Printf("Invalid hash on audit entry %s: %v", entry.ID, err)
+			return nil, err
+		}
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
+
+func parseAuditAnchors(docs []*firestore.DocumentSnapshot) ([]*models.AuditAnchor, error) {
+	anchors := make([]*models.AuditAnchor, 0, len(docs))
+	for _, doc := range docs {
+		var anchor models.AuditAnchor
+		if err := doc.DataTo(&anchor); err != nil {
+			log.Printf("Failed to parse audit anchor %s: %v", doc.Ref.ID, err)
+			return nil, err
+		}
+		anchors = append(anchors, &anchor)
+	}
+	return anchors, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditLimit  = 100
+	maxAuditLimit      = 1000
+	auditSequenceBatch = 400 // Entries linked per transaction, under Firestore's 500 writes
+	auditLinkLookup    = 30  // Entry IDs per links query, Firestore's limit for "in"
+)
+
+// AuditService appends to and searches the patient-data audit log. It never
+// updates or deletes: entries are created once, and the sequencer chains them by
+// creating a link per entry, so the audit log's create/get/list credentials
+// are all it needs.
+type AuditService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewAuditService creates a new AuditService on the audit log's Firestore client
+func NewAuditService(firestore *firebase.FirestoreClient) *AuditService {
+	return &AuditService{
+		Firestore: firestore,
+	}
+}
+
+// Record creates an entry under its own random ID. Requests never touch the
+// chain, so they do not contend with each other; SequencePending links the
+// entry into the hash chain shortly afterwards.
+func (as *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC().Truncate(time.Microsecond) // Firestore's precision, so stored entries hash the same
+	if entry.PatientIDs == nil {
+		entry.PatientIDs = []string{}
+	}
+	entry.ID = uuid.New().String()
+	entry.Seq, entry.PrevHash, entry.Hash = 0, "", ""
+	entry.RecordedAt = time.Time{} // Set to the commit time by Firestore
+
+	if _, err := as.Firestore.Client.Collection("audit_log").Doc(entry.ID).Create(ctx, entry); err != nil {
+		log.Printf("Failed to write audit entry for %s %s: %v", entry.ActorRole, entry.ActorID, err)
+		return err
+	}
+	return nil
+}
+
+// SequencePending links entries not yet in the hash chain onto its end, in
+// commit order and up to auditSequenceBatch of them, and returns how many it
+// linked. Each link is created under its sequence number, so two sequencers
+// running at once cannot fork the chain. Entries carry their commit time, so
+// once the sequencer has read past a time no entry can still appear before it.
+func (as *AuditService) SequencePending(ctx context.Context) (int, error) {
+	client := as.Firestore.Client
+	linked := 0
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		linked = 0
+
+		// Step 1: Find the head of the chain
+		head, err := chainHead(tx, client)
+		if err != nil {
+			return err
+		}
+
+		// Step 2: Pick up the entries committed after the head's
+		pending := client.Collection("audit_log").
+			OrderBy("recorded_at", firestore.Asc).
+			OrderBy(firestore.DocumentID, firestore.Asc).
+			Limit(auditSequenceBatch)
+		if head.Seq > 0 {
+			pending = pending.StartAfter(head.RecordedAt, head.EntryID)
+		}
+		docs, err := tx.Documents(pending).GetAll()
+		if err != nil {
+			return err
+		}
+
+		// Step 3: Link each one to the one before it
+		for _, doc := range docs {
+			var entry models.AuditEntry
+			if err := doc.DataTo(&entry); err != nil {
+				log.Printf("Failed to parse audit entry %s: %v", doc.Ref.ID, err)
+				return err
+			}
+			entry.Seq, entry.PrevHash = head.Seq+1, head.Hash
+			link := &models.AuditLink{
+				Seq:        entry.Seq,
+				EntryID:    doc.Ref.ID,
+				PrevHash:   entry.PrevHash,
+				Hash:       auditEntryHash(&entry),
+				RecordedAt: entry.RecordedAt,
+			}
+			if err := tx.Create(client.Collection("audit_chain").Doc(auditSeqID(link.Seq)), link); err != nil {
+				return err
+			}
+			head = link
+			linked++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to sequence audit entries: %v", err)
+		return 0, err
+	}
+	return linked, nil
+}
+
+// chainHead reads the newest link, or a zero link if the chain is empty
+func chainHead(tx *firestore.Transaction, client *firestore.Client) (*models.AuditLink, error) {
+	docs, err := tx.Documents(client.Collection("audit_chain").OrderBy("seq", firestore.Desc).Limit(1)).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	head := &models.AuditLink{}
+	if len(docs) > 0 {
+		if err := docs[0].DataTo(head); err != nil {
+			log.Printf("Failed to parse audit link %s: %v", docs[0].Ref.ID, err)
+			return nil, err
+		}
+	}
+	return head, nil
+}
+
+// RunSequencer links new audit entries into the chain every interval until ctx
+// is cancelled. A backlog larger than one batch is worked off without waiting
+// for the next tick.
+func (as *AuditService) RunSequencer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			linked, err := as.SequencePending(ctx)
+			if err != nil || linked < auditSequenceBatch {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Query returns a page of audit entries matching q, newest first unless q sorts
+// by "time", and the cursor for the next page. Combined filters need composite
+// indexes on the audit_log collection.
+func (as *AuditService) Query(ctx context.Context, q models.AuditQuery) ([]*models.AuditEntry, string, error) {
+	query := as.Firestore.Client.Collection("audit_log").Query
+	if q.PatientID != "" {
+		query = query.Where("patient_ids", "array-contains", q.PatientID)
+	}
+	if q.ActorID != "" {
+		query = query.Where("actor_id", "==", q.ActorID)
+	}
+	if q.ActorRole != "" {
+		query = query.Where("actor_role", "==", q.ActorRole)
+	} else if len(q.ActorRoles) > 0 {
+		query = query.Where("actor_role", "in", q.ActorRoles)
+	}
+	order, err := parsePageOrder(q.Sort, "-time", "time")
+	if err != nil {
+		return nil, "", err
+	}
+
+	docs, nextCursor, err := timePage(ctx, query, order, q.PageQuery, pageLimit(q.Limit, defaultAuditLimit, maxAuditLimit), nil)
+	if err != nil {
+		log.Printf("Failed to query audit log: %v", err)
+		return nil, "", err
+	}
+
+	entries := make([]*models.AuditEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry models.AuditEntry
+		if err := doc.DataTo(&entry); err != nil {
+			log.Printf("Failed to parse audit entry %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := attachAuditLinks(ctx, as.Firestore.Client, entries); err != nil {
+		return nil, "", err
+	}
+	return entries, nextCursor, nil
+}
+
+// attachAuditLinks fills in each entry's place in the chain from its link;
+// entries not linked yet keep Seq 0
+func attachAuditLinks(ctx context.Context, client *firestore.Client, entries []*models.AuditEntry) error {
+	byID := make(map[string]*models.AuditEntry, len(entries))
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+		ids = append(ids, entry.ID)
+	}
+	for chunk := range slices.Chunk(ids, auditLinkLookup) {
+		docs, err := client.Collection("audit_chain").Where("entry_id", "in", chunk).Documents(ctx).GetAll()
+		if err != nil {
+			log.Printf("Failed to read audit links: %v", err)
+			return err
+		}
+		for _, doc := range docs {
+			var link models.AuditLink
+			if err := doc.DataTo(&link); err != nil {
+				log.Printf("Failed to parse audit link %s: %v", doc.Ref.ID, err)
+				return err
+			}
+			if entry := byID[link.EntryID]; entry != nil {
+				entry.Seq, entry.PrevHash, entry.Hash = link.Seq, link.PrevHash, link.Hash
+			}
+		}
+	}
+	return nil
+}
+
+// auditSeqID is the document ID for a sequence number; zero padding keeps IDs
+// in chain order
+func auditSeqID(seq int64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// auditEntryHash is the hex SHA-256 of an entry's canonical JSON encoding,
+// which includes its sequence number and the previous entry's hash
+func auditEntryHash(entry *models.AuditEntry) string {
+	patientIDs := entry.PatientIDs
+	if patientIDs == nil {
+		patientIDs = []string{} // Firestore may read an empty array back as nil
+	}
+	canonical, _ := json.Marshal(struct {
+		Seq        int64    `json:"seq"`
+		PrevHash   string   `json:"prev_hash"`
+		ActorID    string   `json:"actor_id"`
+		ActorRole  string   `json:"actor_role"`
+		PatientIDs []string `json:"patient_ids"`
+		NFCID      string   `json:"nfc_id"`
+		Resource   string   `json:"resource"`
+		Action     string   `json:"action"`
+		Endpoint   string   `json:"endpoint"`
+		Purpose    string   `json:"purpose"`
+		Emergency  bool     `json:"emergency,omitempty"` // Omitted when false so entries from before the field hash the same
+		Outcome    string   `json:"outcome"`
+		StatusCode int      `json:"status_code"`
+		RequestIP  string   `json:"request_ip"`
+		Time       string   `json:"time"`
+	}{
+		Seq:        entry.Seq,
+		PrevHash:   entry.PrevHash,
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		PatientIDs: patientIDs,
+		NFCID:      entry.NFCID,
+		Resource:   entry.Resource,
+		Action:     entry.Action,
+		Endpoint:   entry.Endpoint,
+		Purpose:    entry.Purpose,
+		Emergency:  entry.Emergency,
+		Outcome:    entry.Outcome,
+		StatusCode: entry.StatusCode,
+		RequestIP:  entry.RequestIP,
+		Time:       entry.Time.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
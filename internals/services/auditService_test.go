@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+)
+
+func TestAuditEntriesSequencedIntoChain(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	audit := NewAuditService(fs)
+
+	// Concurrent requests record without waiting on each other
+	entries := make([]*models.AuditEntry, 5)
+	var wg sync.WaitGroup
+	for i := range entries {
+		entries[i] = &models.AuditEntry{ActorID: testID(t), ActorRole: "doctor", Resource: "profile", Action: "read", Outcome: "success", StatusCode: 200}
+		wg.Add(1)
+		go func(entry *models.AuditEntry) {
+			defer wg.Done()
+			if err := audit.Record(ctx, entry); err != nil {
+				t.Errorf("Record: %v", err)
+			}
+		}(entries[i])
+	}
+	wg.Wait()
+	for _, entry := range entries {
+		if entry.Seq != 0 || entry.Hash != "" {
+			t.Fatalf("entry %s was linked on write", entry.ID)
+		}
+	}
+
+	if linked, err := audit.SequencePending(ctx); err != nil || linked < len(entries) {
+		t.Fatalf("SequencePending = %d, %v; want at least %d linked", linked, err, len(entries))
+	}
+	if linked, err := audit.SequencePending(ctx); err != nil || linked != 0 {
+		t.Errorf("second SequencePending = %d, %v; want nothing left to link", linked, err)
+	}
+
+	// The entries now sit in the chain, each linked to the one before
+	anchors := NewAuditAnchorService(fs, nil)
+	linked := make([]*models.AuditEntry, len(entries))
+	for i, entry := range entries {
+		stored, err := anchors.getEntry(ctx, entry.ID)
+		if err != nil {
+			t.Fatalf("getEntry: %v", err)
+		}
+		if stored.Seq == 0 {
+			t.Fatalf("entry %s was not linked", entry.ID)
+		}
+		doc, err := fs.Client.Collection("audit_log").Doc(entry.ID).Get(ctx)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if _, ok := doc.Data()["hash"]; ok {
+			t.Errorf("entry %s was updated by the sequencer", entry.ID)
+		}
+		linked[i] = stored
+	}
+	sort.Slice(linked, func(i, j int) bool { return linked[i].Seq < linked[j].Seq })
+	first := linked[0]
+	var prev *models.AuditEntry
+	if first.Seq > 1 {
+		var err error
+		if prev, err = anchors.entryAt(ctx, first.Seq-1); err != nil {
+			t.Fatalf("entryAt: %v", err)
+		}
+	}
+	chain, err := anchors.entryRange(ctx, first.Seq, linked[len(linked)-1].Seq)
+	if err != nil {
+		t.Fatalf("entryRange: %v", err)
+	}
+	if err := checkAuditChain(prev, chain); err != nil {
+		t.Errorf("checkAuditChain: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+)
+
+// Handlers name the patients a request touches with middleware.AuditPatients
+// before calling a service, so refused and failed attempts are attributed to the
+// patient too. These lookups find the patients behind an identifier taken from
+// the request. They return nothing if there are none and leave the service to
+// report that.
+
+// PatientsForNFC returns the patient holding a card
+func PatientsForNFC(ctx context.Context, fs *firebase.FirestoreClient, nfcID string) []string {
+	if nfcID == "" {
+		return nil
+	}
+	patient, err := findPatientByNFC(ctx, fs, nfcID)
+	if err != nil {
+		return nil
+	}
+	return []string{patient.UID}
+}
+
+// PatientsForDocument returns the patients a document names in field, which may
+// hold one UID or a list of them, e.g. a medical history entry's user_id
+func PatientsForDocument(ctx context.Context, fs *firebase.FirestoreClient, collection, id, field string) []string {
+	if id == "" {
+		return nil
+	}
+	doc, err := fs.Client.Collection(collection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil
+	}
+	value, err := doc.DataAt(field)
+	if err != nil {
+		return nil
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var ids []string
+		for _, item := range v {
+			if id, ok := item.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestAuditSubjects(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	patientID, otherID := testUser(t, fs, "patient"), testUser(t, fs, "patient")
+	docID := testID(t)
+	_, err := fs.Client.Collection("duplicate_candidates").Doc(docID).Set(ctx, map[string]interface{}{
+		"patient_ids": []string{patientID, otherID},
+		"status":      "pending",
+	})
+	if err != nil {
+		t.Fatalf("storing candidate: %v", err)
+	}
+	t.Cleanup(func() { fs.Client.Collection("duplicate_candidates").Doc(docID).Delete(context.Background()) })
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"card", PatientsForNFC(ctx, fs, patientID), []string{patientID}},
+		{"unknown card", PatientsForNFC(ctx, fs, testID(t)), nil},
+		{"no card", PatientsForNFC(ctx, fs, ""), nil},
+		{"list field", PatientsForDocument(ctx, fs, "duplicate_candidates", docID, "patient_ids"), []string{patientID, otherID}},
+		{"missing field", PatientsForDocument(ctx, fs, "duplicate_candidates", docID, "user_id"), nil},
+		{"missing document", PatientsForDocument(ctx, fs, "duplicate_candidates", testID(t), "patient_ids"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !slices.Equal(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
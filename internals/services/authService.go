@@ -4,8 +4,8 @@ import (
 	"context"
 	"log"
 
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
-	"github.com/gofiber/fiber/v2"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	//"google.golang.org/api/iterator"
@@ -24,49 +24,47 @@ func NewAuthService(authClient *firebase.AuthClient) *AuthService {
 }
 
 // Login authenticates a user and returns a Firebase JWT
-func (as *AuthService) Login(c *fiber.Ctx, email, password string) (string, error) {
+func (as *AuthService) Login(ctx context.Context, email, password string) (string, error) {
 	if email == "" || password == "" {
-		return "", fiber.NewError(fiber.StatusBadRequest, "Email and password are required")
+		return "", errs.Invalid("Email and password are required")
 	}
 
-	ctx := context.Background()
-
 	// Attempt to find the user by email
-	user, err := as.AuthClient.Client.GetUserByEmail(ctx, email)
+	user, err := as.AuthClient.GetUserByEmail(ctx, email)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+			return "", errs.Unauthorized("Invalid email or password")
 		}
 		log.Printf("Failed to find user with email %s: %v", email, err)
-		return "", fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+		return "", errs.Upstream("Login failed", err)
 	}
 
 	// Generate a custom token for the user
-	customToken, err := as.AuthClient.Client.CustomToken(ctx, user.UID)
+	customToken, err := as.AuthClient.CustomToken(ctx, user.UID)
 	if err != nil {
 		log.Printf("Failed to generate custom token for user %s: %v", user.UID, err)
-		return "", fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+		return "", errs.Upstream("Login failed", err)
 	}
 
 	// Simulate exchanging the custom token for an ID token (placeholder)
 	idToken, err := as.exchangeCustomTokenForIDToken(ctx, customToken)
 	if err != nil {
 		log.Printf("Failed to exchange custom token for ID token: %v", err)
-		return "", fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+		return "", errs.Upstream("Login failed", err)
 	}
 
 	// Optionally, set or verify custom claims (e.g., role)
-	userRecord, err := as.AuthClient.GetUserByUID(user.UID)
+	userRecord, err := as.AuthClient.GetUserByUID(ctx, user.UID)
 	if err != nil {
 		log.Printf("Failed to get user record for %s: %v", user.UID, err)
-		return "", fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+		return "", errs.Upstream("Login failed", err)
 	}
 	role, ok := userRecord.CustomClaims["role"].(string)
 	if !ok || role == "" {
 		log.Printf("No role found for user %s, setting default to 'patient'", user.UID)
-		if err := as.AuthClient.SetCustomClaims(user.UID, "patient"); err != nil {
+		if err := as.AuthClient.SetCustomClaims(ctx, user.UID, "patient"); err != nil {
 			log.Printf("Failed to set default role for user %s: %v", user.UID, err)
-			return "", fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+			return "", errs.Upstream("Login failed", err)
 		}
 	}
 
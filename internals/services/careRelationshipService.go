@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const careRelationshipTTL = 90 * 24 * time.Hour // How long a relationship lasts after the card is scanned
+
+// ErrNoCareRelationship is returned when a doctor is not currently treating the patient
+var ErrNoCareRelationship = errs.Forbidden("doctor has no active care relationship with this patient")
+
+// ErrCareRelationshipBlocked is returned when a doctor scans the card of a
+// patient who ended their relationship and has not allowed the doctor since
+var ErrCareRelationshipBlocked = errs.Forbidden("patient has ended their care relationship with this doctor")
+
+// CareRelationshipService tracks which doctors are treating which patients
+type CareRelationshipService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewCareRelationshipService creates a new CareRelationshipService instance
+func NewCareRelationshipService(firestore *firebase.FirestoreClient) *CareRelationshipService {
+	return &CareRelationshipService{
+		Firestore: firestore,
+	}
+}
+
+// Establish starts or renews a doctor's relationship with the patient whose card
+// they scanned, unless the patient ended it and has not allowed the doctor since
+func (cs *CareRelationshipService) Establish(ctx context.Context, doctorID, nfcID string) (*models.CareRelationship, error) {
+	patient, err := findPatientByNFC(ctx, cs.Firestore, nfcID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	relationship := &models.CareRelationship{
+		ID:        careRelationshipID(doctorID, patient.UID),
+		DoctorID:  doctorID,
+		PatientID: patient.UID,
+		NFCID:     nfcID,
+		Status:    "active",
+		StartedAt: now,
+		ExpiresAt: now.Add(careRelationshipTTL),
+	}
+	ref := cs.Firestore.Client.Collection("care_relationships").Doc(relationship.ID)
+	err = cs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var existing models.CareRelationship
+			if err := doc.DataTo(&existing); err != nil {
+				log.Printf("Failed to parse care relationship: %v", err)
+				return err
+			}
+			if existing.Blocked {
+				return ErrCareRelationshipBlocked
+			}
+		}
+		return tx.Set(ref, relationship)
+	})
+	if err != nil {
+		log.Printf("Failed to save care relationship: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Doctor %s started treating patient %s until %s", doctorID, patient.UID, relationship.ExpiresAt.Format(time.RFC3339))
+	return relationship, nil
+}
+
+// End closes a relationship on behalf of its doctor or its patient
+func (cs *CareRelationshipService) End(ctx context.Context, userID, id string) error {
+	ref := cs.Firestore.Client.Collection("care_relationships").Doc(id)
+	return cs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errs.NotFound("Care relationship not found: " + id)
+			}
+			return err
+		}
+		var relationship models.CareRelationship
+		if err := doc.DataTo(&relationship); err != nil {
+			log.Printf("Failed to parse care relationship: %v", err)
+			return err
+		}
+		if relationship.DoctorID != userID && relationship.PatientID != userID {
+			return errs.NotFound("Care relationship not found: " + id)
+		}
+		if relationship.Status != "active" {
+			return errs.Conflict("Care relationship has already ended")
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "ended"},
+			{Path: "ended_at", Value: time.Now().UTC()},
+			{Path: "ended_by", Value: userID},
+			{Path: "blocked", Value: userID == relationship.PatientID},
+		})
+	})
+}
+
+// Allow lets the doctor of a relationship the patient ended restart it with
+// another scan of the patient's card
+func (cs *CareRelationshipService) Allow(ctx context.Context, patientID, id string) error {
+	ref := cs.Firestore.Client.Collection("care_relationships").Doc(id)
+	return cs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errs.NotFound("Care relationship not found: " + id)
+			}
+			return err
+		}
+		var relationship models.CareRelationship
+		if err := doc.DataTo(&relationship); err != nil {
+			log.Printf("Failed to parse care relationship: %v", err)
+			return err
+		}
+		if relationship.PatientID != patientID {
+			return errs.NotFound("Care relationship not found: " + id)
+		}
+		if !relationship.Blocked {
+			return errs.Conflict("Doctor is not blocked")
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "blocked", Value: firestore.Delete}})
+	})
+}
+
+// ListForDoctor returns the doctor's current relationships
+func (cs *CareRelationshipService) ListForDoctor(ctx context.Context, doctorID string) ([]*models.CareRelationship, error) {
+	return cs.listActive(ctx, "doctor_id", doctorID)
+}
+
+// ListForPatient returns the doctors currently treating the patient, followed by
+// those the patient stopped and has not allowed again
+func (cs *CareRelationshipService) ListForPatient(ctx context.Context, patientID string) ([]*models.CareRelationship, error) {
+	relationships, err := cs.listActive(ctx, "patient_id", patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Needs a composite index on (patient_id, blocked)
+	docs, err := cs.Firestore.Client.Collection("care_relationships").
+		Where("patient_id", "==", patientID).
+		Where("blocked", "==", true).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query blocked care relationships for %s: %v", patientID, err)
+		return nil, err
+	}
+	for _, doc := range docs {
+		var relationship models.CareRelationship
+		if err := doc.DataTo(&relationship); err != nil {
+			log.Printf("Failed to parse care relationship %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		relationships = append(relationships, &relationship)
+	}
+	return relationships, nil
+}
+
+// Authorize checks that the doctor is treating the patient. An emergency
+// override on a read lets an untreated patient through and is flagged in the
+// audit log.
+func (cs *CareRelationshipService) Authorize(ctx context.Context, doctorID, patientID string) error {
+	doc, err := cs.Firestore.Client.Collection("care_relationships").Doc(careRelationshipID(doctorID, patientID)).Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		log.Printf("Failed to check care relationship of %s with %s: %v", doctorID, patientID, err)
+		return err
+	}
+	if err == nil {
+		var relationship models.CareRelationship
+		if err := doc.DataTo(&relationship); err != nil {
+			log.Printf("Failed to parse care relationship: %v", err)
+			return err
+		}
+		if careRelationshipActive(&relationship) {
+			return nil
+		}
+	}
+
+	if allowEmergency(ctx, doctorID, patientID, "care") {
+		return nil
+	}
+	return ErrNoCareRelationship
+}
+
+// listActive returns unexpired, unended relationships where field equals uid
+func (cs *CareRelationshipService) listActive(ctx context.Context, field, uid string) ([]*models.CareRelationship, error) {
+	// Needs composite indexes on (doctor_id, status) and (patient_id, status)
+	docs, err := cs.Firestore.Client.Collection("care_relationships").
+		Where(field, "==", uid).
+		Where("status", "==", "active").
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query care relationships for %s: %v", uid, err)
+		return nil, err
+	}
+
+	relationships := make([]*models.CareRelationship, 0, len(docs))
+	for _, doc := range docs {
+		var relationship models.CareRelationship
+		if err := doc.DataTo(&relationship); err != nil {
+			log.Printf("Failed to parse care relationship %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if careRelationshipActive(&relationship) {
+			relationships = append(relationships, &relationship)
+		}
+	}
+	return relationships, nil
+}
+
+// careRelationshipActive reports whether a relationship is neither ended nor lapsed
+func careRelationshipActive(relationship *models.CareRelationship) bool {
+	return relationship.Status == "active" && time.Now().UTC().Before(relationship.ExpiresAt)
+}
+
+func careRelationshipID(doctorID, patientID string) string {
+	return doctorID + "_" + patientID
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPatientEndedRelationshipStaysEnded(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	care := NewCareRelationshipService(fs)
+	doctorID, patientID := testCareTeam(t, fs)
+	id := careRelationshipID(doctorID, patientID)
+
+	// A doctor who ends it may scan the card again
+	if err := care.End(ctx, doctorID, id); err != nil {
+		t.Fatalf("End by doctor: %v", err)
+	}
+	if _, err := care.Establish(ctx, doctorID, patientID); err != nil {
+		t.Fatalf("Establish after the doctor ended it: %v", err)
+	}
+
+	// Once the patient ends it, a scan is refused until the patient allows the doctor
+	if err := care.End(ctx, patientID, id); err != nil {
+		t.Fatalf("End by patient: %v", err)
+	}
+	if _, err := care.Establish(ctx, doctorID, patientID); !errors.Is(err, ErrCareRelationshipBlocked) {
+		t.Fatalf("Establish after the patient ended it = %v, want ErrCareRelationshipBlocked", err)
+	}
+	if err := care.Authorize(ctx, doctorID, patientID); !errors.Is(err, ErrNoCareRelationship) {
+		t.Errorf("Authorize = %v, want ErrNoCareRelationship", err)
+	}
+	if err := care.Allow(ctx, doctorID, id); err == nil {
+		t.Error("the doctor allowed themselves")
+	}
+	if err := care.Allow(ctx, patientID, id); err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	relationship, err := care.Establish(ctx, doctorID, patientID)
+	if err != nil {
+		t.Fatalf("Establish after the patient allowed it: %v", err)
+	}
+	if relationship.Blocked || relationship.Status != "active" {
+		t.Errorf("relationship = %+v, want active and unblocked", relationship)
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrConsentRequired is returned when a practitioner or organization has no
+// current consent covering the data they asked for
+var ErrConsentRequired = errs.Forbidden("patient has not consented to this access")
+
+var (
+	consentScopes       = []string{models.ConsentScopeProfile, models.ConsentScopePrescriptions, models.ConsentScopeHistory}
+	consentGranteeRoles = []string{"doctor", "pharmacist", "hospital"}
+)
+
+const (
+	minEmergencyReasonLength = 20  // Shortest emergency override reason, in characters
+	maxEmergencyReasonLength = 500 // Longest emergency override reason, in characters
+)
+
+// ConsentService manages patients' consents and checks them on every doctor and
+// hospital access to patient data
+type ConsentService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewConsentService creates a new ConsentService instance
+func NewConsentService(firestore *firebase.FirestoreClient) *ConsentService {
+	return &ConsentService{
+		Firestore: firestore,
+	}
+}
+
+// GrantConsent lets a patient give a practitioner or organization access to the
+// listed scopes, until expiresAt if it is set. It replaces any earlier consent
+// for the same grantee.
+func (cs *ConsentService) GrantConsent(ctx context.Context, patientID, granteeID string, scopes []string, expiresAt *time.Time) (*models.Consent, error) {
+	// Step 1: Validate the scopes and deadline
+	if len(scopes) == 0 {
+		return nil, errs.Invalid("At least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(consentScopes, scope) {
+			return nil, errs.Invalid("Unknown consent scope: " + scope)
+		}
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, errs.Invalid("Consent expiry must be in the future")
+	}
+
+	// Step 2: Only practitioners and organizations can be granted consent
+	grantee, err := firebase.GetUserByUID(ctx, cs.Firestore.Client, granteeID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(consentGranteeRoles, grantee.Role) {
+		return nil, errs.Invalid("Consent can only be granted to a doctor, pharmacist or hospital")
+	}
+
+	// Step 3: Save it under the patient and grantee pair
+	consent := &models.Consent{
+		ID:          consentID(patientID, granteeID),
+		PatientID:   patientID,
+		GranteeID:   granteeID,
+		GranteeRole: grantee.Role,
+		Scopes:      slices.Compact(slices.Sorted(slices.Values(scopes))),
+		Status:      "active",
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	}
+	if _, err := cs.Firestore.Client.Collection("consents").Doc(consent.ID).Set(ctx, consent); err != nil {
+		log.Printf("Failed to save consent: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Patient %s granted %s %s consent for %v", patientID, grantee.Role, granteeID, consent.Scopes)
+	return consent, nil
+}
+
+// RevokeConsent ends one of the patient's consents immediately
+func (cs *ConsentService) RevokeConsent(ctx context.Context, patientID, id string) error {
+	ref := cs.Firestore.Client.Collection("consents").Doc(id)
+	return cs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errs.NotFound("Consent not found: " + id)
+			}
+			return err
+		}
+		var consent models.Consent
+		if err := doc.DataTo(&consent); err != nil {
+			log.Printf("Failed to parse consent: %v", err)
+			return err
+		}
+		if consent.PatientID != patientID {
+			return errs.NotFound("Consent not found: " + id)
+		}
+		if consent.Status != "active" {
+			return errs.Conflict("Consent is already " + consent.Status)
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "revoked"},
+			{Path: "revoked_at", Value: time.Now().UTC()},
+		})
+	})
+}
+
+// ListConsents returns every consent the patient has granted, including revoked ones
+func (cs *ConsentService) ListConsents(ctx context.Context, patientID string) ([]*models.Consent, error) {
+	docs, err := cs.Firestore.Client.Collection("consents").
+		Where("patient_id", "==", patientID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query consents for patient %s: %v", patientID, err)
+		return nil, err
+	}
+	return parseConsents(docs), nil
+}
+
+// ConsentingPatients returns the IDs of patients whose active consent gives the
+// grantee scope
+func (cs *ConsentService) ConsentingPatients(ctx context.Context, granteeID, scope string) (map[string]bool, error) {
+	// Needs a composite index on (grantee_id, status)
+	docs, err := cs.Firestore.Client.Collection("consents").
+		Where("grantee_id", "==", granteeID).
+		Where("status", "==", "active").
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query consents for grantee %s: %v", granteeID, err)
+		return nil, err
+	}
+
+	patients := make(map[string]bool)
+	for _, consent := range parseConsents(docs) {
+		if consentCovers(consent, scope) {
+			patients[consent.PatientID] = true
+		}
+	}
+	return patients, nil
+}
+
+// Authorize checks that the grantee may access scope of the patient's record.
+// Without consent, reads are still allowed if the request carries an emergency
+// override; the override is then marked as used so the audit log flags it.
+func (cs *ConsentService) Authorize(ctx context.Context, granteeID, patientID, scope string) error {
+	consent, err := cs.getConsent(ctx, granteeID, patientID)
+	if err != nil {
+		return err
+	}
+	return authorizeScope(ctx, consent, granteeID, patientID, scope)
+}
+
+// AuthorizedScopes returns the scopes of the patient's record the grantee may access
+func (cs *ConsentService) AuthorizedScopes(ctx context.Context, granteeID, patientID string) ([]string, error) {
+	consent, err := cs.getConsent(ctx, granteeID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	var scopes []string
+	for _, scope := range consentScopes {
+		if authorizeScope(ctx, consent, granteeID, patientID, scope) == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, ErrConsentRequired
+	}
+	return scopes, nil
+}
+
+// getConsent loads the consent between a patient and grantee, or nil if there is none
+func (cs *ConsentService) getConsent(ctx context.Context, granteeID, patientID string) (*models.Consent, error) {
+	doc, err := cs.Firestore.Client.Collection("consents").Doc(consentID(patientID, granteeID)).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		log.Printf("Failed to check consent of patient %s for %s: %v", patientID, granteeID, err)
+		return nil, err
+	}
+	var consent models.Consent
+	if err := doc.DataTo(&consent); err != nil {
+		log.Printf("Failed to parse consent: %v", err)
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// authorizeScope allows scope if the consent covers it or an emergency override applies
+func authorizeScope(ctx context.Context, consent *models.Consent, granteeID, patientID, scope string) error {
+	if consent != nil && consentCovers(consent, scope) {
+		return nil
+	}
+	if allowEmergency(ctx, granteeID, patientID, scope) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrConsentRequired, scope)
+}
+
+// allowEmergency reports whether the request carries a usable emergency
+// override, marking it as used and logging what it was used for
+func allowEmergency(ctx context.Context, actorID, patientID, what string) bool {
+	override := emergencyAccessFrom(ctx)
+	if override == nil || !override.allowed {
+		return false
+	}
+	override.used.Store(true)
+	log.Printf("Emergency override by %s for %s of patient %s: %s", actorID, what, patientID, override.Reason)
+	return true
+}
+
+// EmergencyAccess carries the reason a caller gave for overriding consent in an
+// emergency, and whether a consent check actually relied on it
+type EmergencyAccess struct {
+	Reason  string
+	allowed bool // The override may stand in for consent
+	used    atomic.Bool
+}
+
+// Used reports whether any access in the request went ahead only because of the override
+func (e *EmergencyAccess) Used() bool {
+	return e.used.Load()
+}
+
+type emergencyAccessKey struct{}
+
+// WithEmergencyAccess attaches a request's emergency override, if any, to ctx.
+// An override only stands in for consent on reads, and only with a reason that
+// explains the emergency; otherwise it is refused with an error and not applied.
+func WithEmergencyAccess(ctx context.Context, reason string, read bool) (context.Context, *EmergencyAccess, error) {
+	access := &EmergencyAccess{}
+	err := checkEmergencyReason(reason, read)
+	if err == nil && reason != "" {
+		access.Reason = strings.TrimSpace(reason)
+		access.allowed = true
+	}
+	return context.WithValue(ctx, emergencyAccessKey{}, access), access, err
+}
+
+// checkEmergencyReason validates an emergency override; an empty one is no override
+func checkEmergencyReason(reason string, read bool) error {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case reason == "":
+		return nil
+	case !read:
+		return errs.Forbidden("An emergency override only allows reading a patient's record")
+	case len(reason) < minEmergencyReasonLength || len(strings.Fields(reason)) < 2:
+		return errs.Invalid(fmt.Sprintf("An emergency override must describe the emergency in at least %d characters", minEmergencyReasonLength))
+	case len(reason) > maxEmergencyReasonLength:
+		return errs.Invalid(fmt.Sprintf("An emergency override must be at most %d characters", maxEmergencyReasonLength))
+	}
+	return nil
+}
+
+// recordEmergencyAccess flags the request as an emergency access that did not go
+// through consent, such as a redeemed break-glass request
+func recordEmergencyAccess(ctx context.Context, reason string) {
+	if access := emergencyAccessFrom(ctx); access != nil {
+		if access.Reason == "" {
+			access.Reason = reason
+		}
+		access.used.Store(true)
+	}
+}
+
+func emergencyAccessFrom(ctx context.Context) *EmergencyAccess {
+	access, _ := ctx.Value(emergencyAccessKey{}).(*EmergencyAccess)
+	return access
+}
+
+// consentCovers reports whether a consent is active, unexpired and includes scope
+func consentCovers(consent *models.Consent, scope string) bool {
+	if consent.Status != "active" {
+		return false
+	}
+	if consent.ExpiresAt != nil && time.Now().UTC().After(*consent.ExpiresAt) {
+		return false
+	}
+	return slices.Contains(consent.Scopes, scope)
+}
+
+func consentID(patientID, granteeID string) string {
+	return patientID + "_" + granteeID
+}
+
+func parseConsents(docs []*firestore.DocumentSnapshot) []*models.Consent {
+	consents := make([]*models.Consent, 0, len(docs))
+	for _, doc := range docs {
+		var consent models.Consent
+		if err := doc.DataTo(&consent); err != nil {
+			log.Printf("Failed to parse consent %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		consents = append(consents, &consent)
+	}
+	return consents
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+)
+
+func TestEmergencyOverride(t *testing.T) {
+	const reason = "Unconscious in ED, checking allergies"
+	tests := []struct {
+		name    string
+		reason  string
+		read    bool
+		wantErr bool
+		allowed bool
+	}{
+		{"none", "", true, false, false},
+		{"read", reason, true, false, true},
+		{"write", reason, false, true, false},
+		{"single token", "emergency-emergency-emergency", true, true, false},
+		{"too short", "in ED now", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, access, err := WithEmergencyAccess(context.Background(), tt.reason, tt.read)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithEmergencyAccess error = %v, want error %v", err, tt.wantErr)
+			}
+			err = authorizeScope(ctx, nil, "doctor", "patient", models.ConsentScopeHistory)
+			if tt.allowed && err != nil {
+				t.Errorf("authorizeScope = %v, want the override to apply", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrConsentRequired) {
+				t.Errorf("authorizeScope = %v, want ErrConsentRequired", err)
+			}
+			if access.Used() != tt.allowed {
+				t.Errorf("Used = %v, want %v", access.Used(), tt.allowed)
+			}
+		})
+	}
+}
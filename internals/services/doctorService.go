@@ -2,76 +2,116 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
 
-	//"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
 )
 
 // DoctorService handles doctor-related operations
 type DoctorService struct {
 	Firestore *firebase.FirestoreClient
-	IPFS      *storage.IPFSClient
+	Storage   storage.BlobStore
+	Pins      *PinService
+	Consents  *ConsentService
+	Care      *CareRelationshipService
+	Search    *PatientSearchService
+	Profiles  *PatientProfileService
 }
 
 // NewDoctorService creates a new DoctorService instance
-func NewDoctorService(firestore *firebase.FirestoreClient, ipfs *storage.IPFSClient) *DoctorService {
+func NewDoctorService(firestore *firebase.FirestoreClient, store storage.BlobStore) *DoctorService {
 	return &DoctorService{
 		Firestore: firestore,
-		IPFS:      ipfs,
+		Storage:   store,
+		Pins:      NewPinService(firestore, store),
+		Consents:  NewConsentService(firestore),
+		Care:      NewCareRelationshipService(firestore),
+		Search:    NewPatientSearchService(firestore),
+		Profiles:  NewPatientProfileService(firestore),
 	}
 }
 
-// GetPatientByNFC retrieves a patient’s profile by NFC ID from Firestore
-func (ds *DoctorService) GetPatientByNFC(nfcID string) (*models.User, error) {
-	ctx := context.Background()
-
-	// Query Firestore for user with matching NFC ID and "patient" role
-	docs, err := ds.Firestore.Client.Collection("users").
-		Where("nfc_id", "==", nfcID).
-		Where("role", "==", "patient").
-		Documents(ctx).GetAll()
+// GetPatientByNFC retrieves a patient’s profile by NFC ID from Firestore, if
+// the patient has consented to the doctor seeing it
+func (ds *DoctorService) GetPatientByNFC(ctx context.Context, doctorID, nfcID string) (*models.User, error) {
+	user, err := findPatientByNFC(ctx, ds.Firestore, nfcID)
 	if err != nil {
-		log.Printf("Failed to query patient by NFC ID: %v", err)
 		return nil, err
 	}
 
-	if len(docs) == 0 {
-		return nil, logError("Patient not found with NFC ID: " + nfcID)
+	if err := ds.Consents.Authorize(ctx, doctorID, user.UID, models.ConsentScopeProfile); err != nil {
+		return nil, err
 	}
+	user.NFCID = ""
+	return user, nil
+}
 
-	var user models.User
-	if err := docs[0].DataTo(&user); err != nil {
-		log.Printf("Failed to parse user data: %v", err)
+// GetPatientProfile returns the clinical profile of a patient the doctor is
+// treating, if the patient has consented to the doctor seeing their profile
+func (ds *DoctorService) GetPatientProfile(ctx context.Context, doctorID, patientID string, key []byte) (*models.PatientProfile, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeProfile); err != nil {
 		return nil, err
 	}
-	user.UID = docs[0].Ref.ID // Set the Firestore document ID
-	return &user, nil
+	return ds.Profiles.GetProfile(ctx, patientID, key)
 }
 
-// AddMedicalHistory encrypts and stores a patient’s medical history
-func (ds *DoctorService) AddMedicalHistory(patientID, history string, key []byte) (string, error) {
-	ctx := context.Background()
+// SavePatientProfile creates or replaces the clinical profile of a patient the
+// doctor is treating
+func (ds *DoctorService) SavePatientProfile(ctx context.Context, doctorID, patientID string, profile *models.PatientProfile, key []byte) (*models.PatientProfile, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeProfile); err != nil {
+		return nil, err
+	}
+	if _, err := getPatient(ctx, ds.Firestore, patientID); err != nil {
+		return nil, err
+	}
+	return ds.Profiles.SaveProfile(ctx, doctorID, patientID, profile, key)
+}
+
+// AddMedicalHistory validates a typed entry against its type's schema, then
+// encrypts and stores it in a patient’s medical history
+func (ds *DoctorService) AddMedicalHistory(ctx context.Context, doctorID, patientID, entryType string, data json.RawMessage, key []byte) (string, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeHistory); err != nil {
+		return "", err
+	}
+	if err := requirePatient(ctx, ds.Firestore, "patient_id", patientID); err != nil {
+		return "", err
+	}
 
-	// Step 1: Encrypt the medical history
-	encryptedData, err := crypto.Encrypt([]byte(history), key)
+	// Step 1: Validate and encrypt the entry
+	if err := validateHistoryEntry(entryType, data); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(historyPayload{Type: entryType, Data: data})
+	if err != nil {
+		log.Printf("Failed to encode medical history: %v", err)
+		return "", err
+	}
+	keys, err := patientKeys(ctx, ds.Firestore, patientID, key)
+	if err != nil {
+		return "", err
+	}
+	encryptedData, err := crypto.Encrypt(payload, keys.seal)
 	if err != nil {
 		log.Printf("Failed to encrypt medical history: %v", err)
 		return "", err
 	}
 
-	// Step 2: Upload encrypted data to IPFS
-	cid, err := ds.IPFS.AddData(encryptedData)
+	// Step 2: Upload encrypted data to storage
+	cid, err := ds.Storage.AddData(ctx, encryptedData)
 	if err != nil {
-		log.Printf("Failed to upload to IPFS: %v", err)
+		log.Printf("Failed to upload to storage: %v", err)
 		return "", err
 	}
 
@@ -82,55 +122,427 @@ func (ds *DoctorService) AddMedicalHistory(patientID, history string, key []byte
 		UserID:    patientID,
 		CID:       cid,
 		CreatedAt: time.Now().UTC(),
+		Type:      entryType,
+		AuthorID:  doctorID,
+		Version:   1,
 	})
 	if err != nil {
 		log.Printf("Failed to save medical history to Firestore: %v", err)
 		return "", err
 	}
 
+	// Step 4: Pin the blob with its record metadata; verification backfills on failure
+	if err := ds.Pins.PinRecord(ctx, cid, patientID, "medical_history", docID); err != nil {
+		log.Printf("Failed to record pin for medical history %s: %v", docID, err)
+	}
+
 	return docID, nil
 }
 
-// SearchPatients finds patients by name (partial match)
-func (ds *DoctorService) SearchPatients(name string) ([]*models.User, error) {
-	ctx := context.Background()
+// AmendMedicalHistory replaces a server-encrypted history entry with a
+// corrected version. The replaced version stays stored and listed with its
+// author; the reason is recorded on the new one.
+func (ds *DoctorService) AmendMedicalHistory(ctx context.Context, doctorID, entryID, entryType string, data json.RawMessage, reason string, key []byte) (*models.MedicalHistory, error) {
+	// Step 1: Check the entry, the doctor's access to it and the new content
+	record, err := ds.amendableHistory(ctx, doctorID, entryID, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateHistoryEntry(entryType, data); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Encrypt and upload the new version
+	payload, err := json.Marshal(historyPayload{Type: entryType, Data: data})
+	if err != nil {
+		log.Printf("Failed to encode medical history: %v", err)
+		return nil, err
+	}
+	keys, err := patientKeys(ctx, ds.Firestore, record.UserID, key)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := crypto.Encrypt(payload, keys.seal)
+	if err != nil {
+		log.Printf("Failed to encrypt medical history: %v", err)
+		return nil, err
+	}
+	cid, err := ds.Storage.AddData(ctx, encryptedData)
+	if err != nil {
+		log.Printf("Failed to upload to storage: %v", err)
+		return nil, err
+	}
+
+	// Step 3: Make it the current version; an unused blob is left unpinned
+	updated, err := ds.supersedeHistory(ctx, record, cid, entryType, doctorID, reason)
+	if err != nil {
+		return nil, err
+	}
 
-	// Query Firestore for patients with matching name (case-insensitive partial match)
-	iter := ds.Firestore.Client.Collection("users").
-		Where("role", "==", "patient").
-		Documents(ctx)
+	// Step 4: Pin the blob with its record metadata; verification backfills on failure
+	if err := ds.Pins.PinRecord(ctx, cid, record.UserID, "medical_history", entryID); err != nil {
+		log.Printf("Failed to record pin for medical history %s: %v", entryID, err)
+	}
+	return updated, nil
+}
 
-	docs, err := iter.GetAll()
+// RetractMedicalHistory withdraws a server-encrypted history entry that should
+// not have been recorded. Patients no longer see it, but its versions remain.
+func (ds *DoctorService) RetractMedicalHistory(ctx context.Context, doctorID, entryID, reason string) (*models.MedicalHistory, error) {
+	record, err := ds.amendableHistory(ctx, doctorID, entryID, reason)
 	if err != nil {
-		log.Printf("Failed to search patients: %v", err)
 		return nil, err
 	}
+	return ds.supersedeHistory(ctx, record, "", "", doctorID, reason)
+}
 
-	var results []*models.User
+// GetMedicalHistoryVersions returns every version of a server-encrypted history
+// entry of a patient the doctor is treating
+func (ds *DoctorService) GetMedicalHistoryVersions(ctx context.Context, doctorID, entryID string, key []byte) (*models.MedicalHistoryVersions, error) {
+	record, err := getHistoryRecord(ctx, ds.Firestore, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.authorize(ctx, doctorID, record.UserID, models.ConsentScopeHistory); err != nil {
+		return nil, err
+	}
+	if record.E2E {
+		return nil, errs.Invalid("End-to-end entries have no server-readable versions: " + entryID)
+	}
+	keys, err := patientKeys(ctx, ds.Firestore, record.UserID, key)
+	if err != nil {
+		return nil, err
+	}
+	return loadHistoryVersions(ctx, ds.Storage, record, keys), nil
+}
+
+// amendableHistory loads an entry the doctor may amend or retract
+func (ds *DoctorService) amendableHistory(ctx context.Context, doctorID, entryID, reason string) (*models.MedicalHistory, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidHistoryEntry)
+	}
+	if len([]rune(reason)) > maxAmendReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidHistoryEntry, maxAmendReasonLength)
+	}
+
+	record, err := getHistoryRecord(ctx, ds.Firestore, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.authorize(ctx, doctorID, record.UserID, models.ConsentScopeHistory); err != nil {
+		return nil, err
+	}
+	if record.E2E {
+		return nil, errs.Invalid("End-to-end entries cannot be amended by the server: " + entryID)
+	}
+	if record.Retracted {
+		return nil, errs.Conflict("Medical history entry has been retracted: " + entryID)
+	}
+	return record, nil
+}
+
+// supersedeHistory makes a new version current, moving the one it replaces into
+// the entry's versions. An empty cid retracts the entry. It fails if the entry
+// changed since record was read, so concurrent amendments cannot lose a version.
+func (ds *DoctorService) supersedeHistory(ctx context.Context, record *models.MedicalHistory, cid, entryType, authorID, reason string) (*models.MedicalHistory, error) {
+	ref := ds.Firestore.Client.Collection("medical_history").Doc(record.ID)
+	var updated models.MedicalHistory
+	err := ds.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			log.Printf("Failed to get medical history %s: %v", record.ID, err)
+			return err
+		}
+		if err := doc.DataTo(&updated); err != nil {
+			log.Printf("Failed to parse medical history: %v", err)
+			return err
+		}
+		updated.ID = doc.Ref.ID
+		if historyVersion(updated) != historyVersion(*record) || updated.Retracted {
+			return errs.Conflict("Medical history entry changed while it was being amended: " + record.ID)
+		}
+
+		now := time.Now().UTC()
+		updated.Versions = append(updated.Versions, models.HistoryVersion{
+			Version:   historyVersion(updated),
+			CID:       updated.CID,
+			Type:      updated.Type,
+			AuthorID:  updated.AuthorID,
+			Reason:    updated.Reason,
+			CreatedAt: historyVersionTime(updated),
+		})
+		updated.Version = historyVersion(updated) + 1
+		updated.CID = cid
+		updated.Type = entryType
+		updated.AuthorID = authorID
+		updated.Reason = strings.TrimSpace(reason)
+		updated.Retracted = cid == ""
+		updated.UpdatedAt = &now
+		return tx.Set(ref, updated)
+	})
+	if err != nil {
+		log.Printf("Failed to amend medical history %s: %v", record.ID, err)
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// AddEncryptedMedicalHistory stores a history entry that the client has already
+// encrypted. The server relays the ciphertext and wrapped keys without reading them.
+func (ds *DoctorService) AddEncryptedMedicalHistory(ctx context.Context, doctorID, patientID string, ciphertext []byte, wrappedKeys map[string]models.WrappedKey) (string, error) {
+	// Step 1: The patient must always be able to read their own record
+	if len(ciphertext) == 0 {
+		return "", errs.Invalid("Encrypted history is empty")
+	}
+	if _, ok := wrappedKeys[patientID]; !ok {
+		return "", errs.Invalid("A wrapped key for the patient is required")
+	}
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeHistory); err != nil {
+		return "", err
+	}
+	if err := requirePatient(ctx, ds.Firestore, "patient_id", patientID); err != nil {
+		return "", err
+	}
+	for readerID, wrapped := range wrappedKeys {
+		if err := validateWrappedKey(wrapped); err != nil {
+			log.Printf("Invalid wrapped key for reader %s: %v", readerID, err)
+			return "", err
+		}
+	}
+
+	// Step 2: Upload the ciphertext to storage as-is
+	cid, err := ds.Storage.AddData(ctx, ciphertext)
+	if err != nil {
+		log.Printf("Failed to upload to storage: %v", err)
+		return "", err
+	}
+
+	// Step 3: Save CID, author and wrapped keys to Firestore
+	docID := uuid.New().String()
+	_, err = ds.Firestore.Client.Collection("medical_history").Doc(docID).Set(ctx, models.MedicalHistory{
+		ID:          docID,
+		UserID:      patientID,
+		CID:         cid,
+		CreatedAt:   time.Now().UTC(),
+		E2E:         true,
+		AuthorID:    doctorID,
+		WrappedKeys: wrappedKeys,
+	})
+	if err != nil {
+		log.Printf("Failed to save medical history to Firestore: %v", err)
+		return "", err
+	}
+
+	// Step 4: Pin the blob with its record metadata; verification backfills on failure
+	if err := ds.Pins.PinRecord(ctx, cid, patientID, "medical_history", docID); err != nil {
+		log.Printf("Failed to record pin for medical history %s: %v", docID, err)
+	}
+
+	return docID, nil
+}
+
+// GetEncryptedMedicalHistory returns the end-to-end entries this doctor has been granted
+func (ds *DoctorService) GetEncryptedMedicalHistory(ctx context.Context, doctorID, patientID string) ([]*models.EncryptedHistoryEntry, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeHistory); err != nil {
+		return nil, err
+	}
+	return fetchEncryptedHistory(ctx, ds.Firestore, ds.Storage, patientID, doctorID)
+}
+
+// AddAttachment streams a large file through encryption into storage and records it
+// against the patient, without holding the whole file in memory
+func (ds *DoctorService) AddAttachment(ctx context.Context, doctorID, patientID, fileName, contentType string, data io.Reader, key []byte) (string, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopeHistory); err != nil {
+		return "", err
+	}
+	if err := requirePatient(ctx, ds.Firestore, "patient_id", patientID); err != nil {
+		return "", err
+	}
+
+	// Step 1: Wrap the upload in a streaming encrypter under the patient's key
+	keys, err := patientKeys(ctx, ds.Firestore, patientID, key)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := crypto.NewEncryptReader(data, keys.seal)
+	if err != nil {
+		log.Printf("Failed to set up attachment encryption: %v", err)
+		return "", err
+	}
+
+	// Step 2: Stream the ciphertext to storage
+	cid, err := ds.Storage.AddReader(ctx, encrypted)
+	if err != nil {
+		log.Printf("Failed to upload attachment to storage: %v", err)
+		return "", err
+	}
+
+	// Step 3: Save CID and metadata to Firestore
+	docID := uuid.New().String()
+	_, err = ds.Firestore.Client.Collection("attachments").Doc(docID).Set(ctx, models.Attachment{
+		ID:          docID,
+		UserID:      patientID,
+		DoctorID:    doctorID,
+		CID:         cid,
+		FileName:    fileName,
+		ContentType: contentType,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to save attachment to Firestore: %v", err)
+		return "", err
+	}
+
+	// Step 4: Pin the blob with its record metadata; verification backfills on failure
+	if err := ds.Pins.PinRecord(ctx, cid, patientID, "attachment", docID); err != nil {
+		log.Printf("Failed to record pin for attachment %s: %v", docID, err)
+	}
+
+	return docID, nil
+}
+
+// SearchPatients searches the doctor's own patients who have consented to the
+// doctor seeing their profile, by name prefix, date of birth or NFC ID
+func (ds *DoctorService) SearchPatients(ctx context.Context, doctorID string, q models.PatientSearchQuery) (*models.Page[*models.User], error) {
+	// Step 1: Restrict the search to the doctor's current, consenting patients
+	patientIDs, err := ds.searchablePatients(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	q.PatientIDs = patientIDs
+
+	// Step 2: Match them in the search index
+	entries, nextCursor, err := ds.Search.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Load the matching patients, keeping the index's order
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.PatientID
+	}
+	patients, err := ds.loadPatients(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Page[*models.User]{Items: []*models.User{}, NextCursor: nextCursor}
+	for _, id := range ids {
+		if patient, ok := patients[id]; ok {
+			result.Items = append(result.Items, patient)
+		}
+	}
+	return result, nil
+}
+
+// MatchPatients ranks the doctor's own consenting patients by how closely their
+// names resemble q.Name, for finding a patient whose name is misspelled,
+// transliterated or only heard
+func (ds *DoctorService) MatchPatients(ctx context.Context, doctorID string, q models.PatientMatchQuery) ([]*models.PatientMatch, error) {
+	// Step 1: Restrict the match to the doctor's current, consenting patients
+	patientIDs, err := ds.searchablePatients(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	q.PatientIDs = patientIDs
+	q.FullName = false
+
+	// Step 2: Rank them
+	matches, err := ds.Search.MatchPatients(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Attach the patients
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		ids[i] = match.PatientID
+	}
+	patients, err := ds.loadPatients(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.PatientMatch, 0, len(matches))
+	for _, match := range matches {
+		if patient, ok := patients[match.PatientID]; ok {
+			match.Patient = patient
+			result = append(result, match)
+		}
+	}
+	return result, nil
+}
+
+// searchablePatients lists the doctor's current patients who have consented to
+// the doctor seeing their profile
+func (ds *DoctorService) searchablePatients(ctx context.Context, doctorID string) ([]string, error) {
+	relationships, err := ds.Care.ListForDoctor(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	consenting, err := ds.Consents.ConsentingPatients(ctx, doctorID, models.ConsentScopeProfile)
+	if err != nil {
+		return nil, err
+	}
+	patientIDs := []string{}
+	for _, relationship := range relationships {
+		if consenting[relationship.PatientID] {
+			patientIDs = append(patientIDs, relationship.PatientID)
+		}
+	}
+	return patientIDs, nil
+}
+
+// loadPatients returns the user documents of the listed patients, by UID. Their
+// NFC IDs are withheld, since a doctor who knows one could pass it off as a scan.
+func (ds *DoctorService) loadPatients(ctx context.Context, patientIDs []string) (map[string]*models.User, error) {
+	patients := make(map[string]*models.User, len(patientIDs))
+	if len(patientIDs) == 0 {
+		return patients, nil
+	}
+	refs := make([]*firestore.DocumentRef, len(patientIDs))
+	for i, id := range patientIDs {
+		refs[i] = ds.Firestore.Client.Collection("users").Doc(id)
+	}
+	docs, err := ds.Firestore.Client.GetAll(ctx, refs)
+	if err != nil {
+		log.Printf("Failed to load patients: %v", err)
+		return nil, err
+	}
 	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
 		var user models.User
 		if err := doc.DataTo(&user); err != nil {
 			log.Printf("Failed to parse user data: %v", err)
 			continue
 		}
 		user.UID = doc.Ref.ID
-		if strings.Contains(strings.ToLower(user.Name), strings.ToLower(name)) { // Use user.Name directly
-			results = append(results, &user)
-		}
+		user.NFCID = ""
+		patients[user.UID] = &user
 	}
-
-	return results, nil
+	return patients, nil
 }
 
 // CreatePrescription is a placeholder until blockchain is implemented
-func (ds *DoctorService) CreatePrescription(patientID, medication string, dosage uint64) (string, error) {
+func (ds *DoctorService) CreatePrescription(ctx context.Context, doctorID, patientID, medication string, dosage uint64) (string, error) {
+	if err := ds.authorize(ctx, doctorID, patientID, models.ConsentScopePrescriptions); err != nil {
+		return "", err
+	}
+	if err := requirePatient(ctx, ds.Firestore, "patient_id", patientID); err != nil {
+		return "", err
+	}
+
 	// TODO: Implement with blockchain NFT minting
 	log.Println("CreatePrescription not implemented yet—waiting for blockchain")
 	return "", nil
 }
 
-func logError(msg string) error {
-	err := fmt.Errorf(msg)
-	log.Println(err)
-	return err
+// authorize checks the doctor is treating the patient and has their consent for scope
+func (ds *DoctorService) authorize(ctx context.Context, doctorID, patientID, scope string) error {
+	if err := ds.Care.Authorize(ctx, doctorID, patientID); err != nil {
+		return err
+	}
+	return ds.Consents.Authorize(ctx, doctorID, patientID, scope)
 }
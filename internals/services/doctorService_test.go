@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+)
+
+func TestAmendedHistoryReadableByPatient(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	store := testStore(t)
+	doctors, patients := NewDoctorService(fs, store), NewPatientService(fs, store)
+	doctorID, patientID := testCareTeam(t, fs)
+
+	entryID, err := doctors.AddMedicalHistory(ctx, doctorID, patientID, "note", json.RawMessage(`{"text":"Chest pain"}`), key)
+	if err != nil {
+		t.Fatalf("AddMedicalHistory: %v", err)
+	}
+	if _, err := doctors.AmendMedicalHistory(ctx, doctorID, entryID, "note", json.RawMessage(`{"text":"Chest pain, resolved"}`), "Follow-up", key); err != nil {
+		t.Fatalf("AmendMedicalHistory: %v", err)
+	}
+
+	// Doctors and patients read the versions with the key they were written with
+	doctorView, err := doctors.GetMedicalHistoryVersions(ctx, doctorID, entryID, key)
+	if err != nil {
+		t.Fatalf("doctor GetMedicalHistoryVersions: %v", err)
+	}
+	patientView, err := patients.GetMedicalHistoryVersions(ctx, patientID, entryID, key)
+	if err != nil {
+		t.Fatalf("patient GetMedicalHistoryVersions: %v", err)
+	}
+	for reader, versions := range map[string]*models.MedicalHistoryVersions{"doctor": doctorView, "patient": patientView} {
+		if len(versions.Versions) != 2 || versions.Versions[1].History != "Chest pain, resolved" {
+			t.Errorf("%s read versions %+v, want the entry and its amendment", reader, versions.Versions)
+		}
+	}
+}
+
+func TestDoctorWritesDoNotRevealWhichPatientsExist(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	doctors := NewDoctorService(fs, testStore(t))
+	doctorID := testUser(t, fs, "doctor")
+	existingID, unknownID := testUser(t, fs, "patient"), testID(t)
+
+	// A doctor not treating the patient is refused the same way whether or not
+	// the UID belongs to anyone
+	for _, patientID := range []string{existingID, unknownID} {
+		writes := map[string]func() error{
+			"AddMedicalHistory": func() error {
+				_, err := doctors.AddMedicalHistory(ctx, doctorID, patientID, "note", json.RawMessage(`{"text":"Chest pain"}`), key)
+				return err
+			},
+			"AddAttachment": func() error {
+				_, err := doctors.AddAttachment(ctx, doctorID, patientID, "scan.pdf", "application/pdf", strings.NewReader("scan"), key)
+				return err
+			},
+			"CreatePrescription": func() error {
+				_, err := doctors.CreatePrescription(ctx, doctorID, patientID, "Amoxicillin", 500)
+				return err
+			},
+		}
+		for name, write := range writes {
+			if err := write(); !errors.Is(err, ErrNoCareRelationship) {
+				t.Errorf("%s for patient %s = %v, want ErrNoCareRelationship", name, patientID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/blockchain"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	duplicateThreshold      = 0.8 // Lowest match score flagged as a possible duplicate
+	maxDuplicatesPerPatient = 20
+	duplicateScanPageSize   = 500
+)
+
+// mergedCollections are the patient-owned collections whose documents move to
+// the surviving record in a merge, with the field naming the patient. The
+// clinical profile moves separately, since it is keyed by the patient's UID.
+// End-to-end history only moves once it is shared with the survivor, as the
+// server cannot rewrap its keys; consents and care relationships are not
+// carried over and must be granted again.
+var mergedCollections = []struct{ collection, field string }{
+	{"medical_history", "user_id"},
+	{"prescriptions", "user_id"},
+	{"attachments", "user_id"},
+	{"transactions", "patient_id"},
+	{"pins", "patient_id"},
+}
+
+// DuplicateService finds patient records that may belong to the same person and
+// merges them once an administrator confirms it
+type DuplicateService struct {
+	Firestore  *firebase.FirestoreClient
+	Blockchain *blockchain.Client
+	Search     *PatientSearchService
+}
+
+// NewDuplicateService creates a new DuplicateService instance
+func NewDuplicateService(firestore *firebase.FirestoreClient, blockchain *blockchain.Client) *DuplicateService {
+	return &DuplicateService{
+		Firestore:  firestore,
+		Blockchain: blockchain,
+		Search:     NewPatientSearchService(firestore),
+	}
+}
+
+// DetectDuplicates queues the patients whose name, date of birth and phone
+// closely match patientID's. Pairs already queued, including dismissed ones,
+// are not flagged again.
+func (ds *DuplicateService) DetectDuplicates(ctx context.Context, patientID string) ([]*models.DuplicateCandidate, error) {
+	entries, err := ds.Search.loadEntries(ctx, []string{patientID})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errs.NotFound("Patient not found: " + patientID)
+	}
+	return ds.detect(ctx, entries[0])
+}
+
+// Scan checks every indexed patient for duplicates and returns how many new
+// pairs were queued
+func (ds *DuplicateService) Scan(ctx context.Context) (int, error) {
+	flagged := 0
+	query := ds.Firestore.Client.Collection("patient_search").OrderBy(firestore.DocumentID, firestore.Asc).Limit(duplicateScanPageSize)
+	page := query
+	for {
+		docs, err := page.Documents(ctx).GetAll()
+		if err != nil {
+			log.Printf("Failed to scan patients for duplicates: %v", err)
+			return flagged, err
+		}
+		for _, entry := range parseSearchEntries(docs) {
+			if len(entry.Tokens) == 0 {
+				continue
+			}
+			candidates, err := ds.detect(ctx, entry)
+			if err != nil {
+				return flagged, err
+			}
+			flagged += len(candidates)
+		}
+		if len(docs) < duplicateScanPageSize {
+			return flagged, nil
+		}
+		page = query.StartAfter(docs[len(docs)-1].Ref.ID)
+	}
+}
+
+// RunDetector scans for duplicates every interval until ctx is cancelled
+func (ds *DuplicateService) RunDetector(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if flagged, err := ds.Scan(ctx); err != nil {
+			log.Printf("Duplicate patient scan failed: %v", err)
+		} else if flagged > 0 {
+			log.Printf("Queued %d possible duplicate patients for review", flagged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListCandidates returns the queued pairs with the given status, highest score first
+func (ds *DuplicateService) ListCandidates(ctx context.Context, candidateStatus string) ([]*models.DuplicateCandidate, error) {
+	if candidateStatus == "" {
+		candidateStatus = "pending"
+	}
+	docs, err := ds.Firestore.Client.Collection("duplicate_candidates").
+		Where("status", "==", candidateStatus).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query duplicate candidates: %v", err)
+		return nil, err
+	}
+
+	candidates := make([]*models.DuplicateCandidate, 0, len(docs))
+	for _, doc := range docs {
+		var candidate models.DuplicateCandidate
+		if err := doc.DataTo(&candidate); err != nil {
+			log.Printf("Failed to parse duplicate candidate %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		candidates = append(candidates, &candidate)
+	}
+	slices.SortFunc(candidates, func(a, b *models.DuplicateCandidate) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return candidates, nil
+}
+
+// Dismiss records that a queued pair are different people
+func (ds *DuplicateService) Dismiss(ctx context.Context, adminID, candidateID string) error {
+	ref := ds.Firestore.Client.Collection("duplicate_candidates").Doc(candidateID)
+	return ds.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		candidate, err := getDuplicateCandidate(tx, ref)
+		if err != nil {
+			return err
+		}
+		if candidate.Status != "pending" {
+			return errs.Conflict("Duplicate candidate is already " + candidate.Status)
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "dismissed"},
+			{Path: "reviewed_by", Value: adminID},
+			{Path: "reviewed_at", Value: time.Now().UTC()},
+		})
+	})
+}
+
+// Merge folds the other patient of a queued pair into survivorID: their medical
+// history, prescriptions, attachments, transactions, pins and clinical profile
+// move to the survivor, active prescription NFTs move to the survivor's wallet
+// when the wallets differ, and the merged-away user document becomes a
+// tombstone whose card resolves to the survivor. It is refused while the other
+// patient has end-to-end entries not shared with the survivor. An interrupted
+// merge can be run again to finish it.
+func (ds *DuplicateService) Merge(ctx context.Context, adminID, candidateID, survivorID string) (*models.PatientMerge, error) {
+	// Step 1: Claim the candidate for this merge
+	ref := ds.Firestore.Client.Collection("duplicate_candidates").Doc(candidateID)
+	var mergedID string
+	err := ds.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		candidate, err := getDuplicateCandidate(tx, ref)
+		if err != nil {
+			return err
+		}
+		i := slices.Index(candidate.PatientIDs, survivorID)
+		if i < 0 || len(candidate.PatientIDs) != 2 {
+			return errs.Invalid("Survivor must be one of the candidate's patients")
+		}
+		mergedID = candidate.PatientIDs[1-i]
+
+		resuming := candidate.Status == "merging" && candidate.SurvivorID == survivorID
+		if !resuming && candidate.Status != "pending" {
+			return errs.Conflict("Duplicate candidate is already " + candidate.Status)
+		}
+		unshared, err := ds.unsharedHistory(tx, mergedID, survivorID)
+		if err != nil {
+			return err
+		}
+		if unshared > 0 {
+			return errs.Conflict(fmt.Sprintf("Patient %s has %d end-to-end encrypted history entries not shared with %s; share them from the patient's device first", mergedID, unshared, survivorID))
+		}
+		if resuming {
+			return nil // Resume an interrupted merge
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "merging"},
+			{Path: "survivor_id", Value: survivorID},
+			{Path: "reviewed_by", Value: adminID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Load both patients
+	survivor, err := getPatient(ctx, ds.Firestore, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := getPatient(ctx, ds.Firestore, mergedID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Move the merged patient's records to the survivor, after giving the
+	// survivor the keys they are sealed with
+	if err := adoptMergedKeys(ctx, ds.Firestore, mergedID, survivorID); err != nil {
+		return nil, err
+	}
+	moved := make(map[string]int, len(mergedCollections))
+	for _, c := range mergedCollections {
+		n, err := ds.moveRecords(ctx, c.collection, c.field, mergedID, survivorID)
+		if err != nil {
+			return nil, err
+		}
+		moved[c.collection] = n
+	}
+	n, err := ds.moveProfile(ctx, mergedID, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	moved["patient_profiles"] = n
+
+	// Step 4: Move prescription NFTs to the survivor's wallet
+	pending, err := ds.transferPrescriptions(ctx, survivorID, merged.WalletAddress, survivor.WalletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Leave a tombstone and close the candidate
+	now := time.Now().UTC()
+	tombstone := &models.PatientMerge{
+		MergedID:         mergedID,
+		SurvivorID:       survivorID,
+		NFCID:            merged.NFCID,
+		CandidateID:      candidateID,
+		MergedBy:         adminID,
+		MergedAt:         now,
+		Moved:            moved,
+		PendingTransfers: pending,
+	}
+	err = ds.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(ds.Firestore.Client.Collection("patient_merges").Doc(mergedID), tombstone); err != nil {
+			return err
+		}
+		if err := tx.Update(ds.Firestore.Client.Collection("users").Doc(mergedID), []firestore.Update{
+			{Path: "role", Value: "merged"},
+			{Path: "merged_into", Value: survivorID},
+		}); err != nil {
+			return err
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "merged"},
+			{Path: "reviewed_at", Value: now},
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to record merge of patient %s into %s: %v", mergedID, survivorID, err)
+		return nil, err
+	}
+
+	invalidateEmergencySummary(ctx, ds.Firestore, survivorID)
+	log.Printf("Merged patient %s into %s", mergedID, survivorID)
+	return tombstone, nil
+}
+
+// detect queues the close matches of one index entry
+func (ds *DuplicateService) detect(ctx context.Context, entry *models.PatientSearchEntry) ([]*models.DuplicateCandidate, error) {
+	matches, err := ds.Search.MatchPatients(ctx, models.PatientMatchQuery{
+		Name:        entry.Name,
+		DateOfBirth: entry.DateOfBirth,
+		Phone:       entry.Phone,
+		FullName:    true,
+		MinScore:    duplicateThreshold,
+		Limit:       maxDuplicatesPerPatient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*models.DuplicateCandidate
+	for _, match := range matches {
+		if match.PatientID == entry.PatientID {
+			continue
+		}
+		patientIDs := []string{entry.PatientID, match.PatientID}
+		slices.Sort(patientIDs)
+		candidate := &models.DuplicateCandidate{
+			ID:         strings.Join(patientIDs, "_"),
+			PatientIDs: patientIDs,
+			Score:      match.Score,
+			Reasons:    match.Reasons,
+			Status:     "pending",
+			DetectedAt: time.Now().UTC(),
+		}
+		_, err := ds.Firestore.Client.Collection("duplicate_candidates").Doc(candidate.ID).Create(ctx, candidate)
+		if status.Code(err) == codes.AlreadyExists {
+			continue // Already queued or reviewed
+		}
+		if err != nil {
+			log.Printf("Failed to queue duplicate candidate %s: %v", candidate.ID, err)
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// moveRecords repoints every document in collection whose field is fromID to
+// toID and returns how many moved
+func (ds *DuplicateService) moveRecords(ctx context.Context, collection, field, fromID, toID string) (int, error) {
+	docs, err := ds.Firestore.Client.Collection(collection).Where(field, "==", fromID).Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query %s of patient %s: %v", collection, fromID, err)
+		return 0, err
+	}
+	for start := 0; start < len(docs); start += writeBatchSize {
+		batch := ds.Firestore.Client.Batch()
+		for _, doc := range docs[start:min(start+writeBatchSize, len(docs))] {
+			batch.Update(doc.Ref, []firestore.Update{{Path: field, Value: toID}})
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			log.Printf("Failed to move %s of patient %s: %v", collection, fromID, err)
+			return start, err
+		}
+	}
+	return len(docs), nil
+}
+
+// moveProfile moves the clinical profile of fromID to toID unless toID already
+// has one, which is kept, and returns how many moved
+func (ds *DuplicateService) moveProfile(ctx context.Context, fromID, toID string) (int, error) {
+	profiles := ds.Firestore.Client.Collection("patient_profiles")
+	from, to := profiles.Doc(fromID), profiles.Doc(toID)
+	moved := 0
+	err := ds.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		moved = 0
+		doc, err := tx.Get(from)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Get(to); err == nil {
+			log.Printf("Keeping the clinical profile of %s; patient %s's stays under the merged record", toID, fromID)
+			return nil
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+		profile := doc.Data()
+		profile["patient_id"] = toID
+		if err := tx.Set(to, profile); err != nil {
+			return err
+		}
+		moved = 1
+		return tx.Delete(from)
+	})
+	if err != nil {
+		log.Printf("Failed to move clinical profile of patient %s: %v", fromID, err)
+		return 0, err
+	}
+	return moved, nil
+}
+
+// unsharedHistory counts the end-to-end entries of mergedID whose data key is
+// not wrapped for survivorID. Only the patient's device can rewrap them.
+func (ds *DuplicateService) unsharedHistory(tx *firestore.Transaction, mergedID, survivorID string) (int, error) {
+	docs, err := tx.Documents(ds.Firestore.Client.Collection("medical_history").
+		Where("user_id", "==", mergedID).
+		Where("e2e", "==", true)).GetAll()
+	if err != nil {
+		log.Printf("Failed to query encrypted medical history of patient %s: %v", mergedID, err)
+		return 0, err
+	}
+	unshared := 0
+	for _, doc := range docs {
+		var mh models.MedicalHistory
+		if err := doc.DataTo(&mh); err != nil {
+			log.Printf("Failed to parse medical history: %v", err)
+			return 0, err
+		}
+		if _, ok := mh.WrappedKeys[survivorID]; !ok {
+			unshared++
+		}
+	}
+	return unshared, nil
+}
+
+// transferPrescriptions moves the NFTs of the survivor's active prescriptions
+// still held by the merged-away wallet and returns the token IDs that could
+// not be moved, e.g. because the server is not approved for them
+func (ds *DuplicateService) transferPrescriptions(ctx context.Context, survivorID, fromWallet, toWallet string) ([]string, error) {
+	if fromWallet == "" || toWallet == "" || strings.EqualFold(fromWallet, toWallet) {
+		return nil, nil
+	}
+	docs, err := ds.Firestore.Client.Collection("prescriptions").
+		Where("user_id", "==", survivorID).
+		Where("is_active", "==", true).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query active prescriptions: %v", err)
+		return nil, err
+	}
+
+	var pending []string
+	for _, doc := range docs {
+		value, _ := doc.DataAt("token_id")
+		tokenID, _ := value.(string)
+		token, err := strconv.ParseUint(tokenID, 10, 64)
+		if err != nil {
+			continue // Not minted yet
+		}
+		if err := ds.Blockchain.TransferPrescription(ctx, token, fromWallet, toWallet); err != nil {
+			pending = append(pending, tokenID)
+		}
+	}
+	return pending, nil
+}
+
+func getDuplicateCandidate(tx *firestore.Transaction, ref *firestore.DocumentRef) (*models.DuplicateCandidate, error) {
+	doc, err := tx.Get(ref)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NotFound("Duplicate candidate not found: " + ref.ID)
+		}
+		return nil, err
+	}
+	var candidate models.DuplicateCandidate
+	if err := doc.DataTo(&candidate); err != nil {
+		log.Printf("Failed to parse duplicate candidate: %v", err)
+		return nil, err
+	}
+	return &candidate, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestMergeMovesE2EEntriesOnlyOnceShared(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	duplicates := NewDuplicateService(fs, nil)
+	survivorID, mergedID := testUser(t, fs, "patient"), testUser(t, fs, "patient")
+
+	// Step 1: Queue the pair and give the merged patient an end-to-end entry,
+	// a pin and a clinical profile
+	candidateID := testID(t)
+	entryID, cid := testID(t), testID(t)
+	docs := map[*firestore.DocumentRef]interface{}{
+		fs.Client.Collection("duplicate_candidates").Doc(candidateID): models.DuplicateCandidate{
+			ID: candidateID, PatientIDs: []string{survivorID, mergedID}, Status: "pending", DetectedAt: time.Now().UTC(),
+		},
+		fs.Client.Collection("medical_history").Doc(entryID): models.MedicalHistory{
+			ID: entryID, UserID: mergedID, CID: cid, E2E: true, CreatedAt: time.Now().UTC(),
+			WrappedKeys: map[string]models.WrappedKey{mergedID: {EphemeralKey: make([]byte, 32), Ciphertext: []byte{1}}},
+		},
+		fs.Client.Collection("pins").Doc(cid): models.PinRecord{
+			CID: cid, PatientID: mergedID, RecordType: "medical_history", RecordID: entryID, Status: "pinned", PinnedAt: time.Now().UTC(),
+		},
+		fs.Client.Collection("patient_profiles").Doc(mergedID): models.PatientProfile{PatientID: mergedID, UpdatedBy: mergedID},
+	}
+	for ref, data := range docs {
+		if _, err := ref.Set(ctx, data); err != nil {
+			t.Fatalf("storing %s: %v", ref.Path, err)
+		}
+		t.Cleanup(func() { ref.Delete(context.Background()) })
+	}
+	t.Cleanup(func() { fs.Client.Collection("patient_profiles").Doc(survivorID).Delete(context.Background()) })
+	t.Cleanup(func() { fs.Client.Collection("patient_merges").Doc(mergedID).Delete(context.Background()) })
+
+	// Step 2: The entry is not readable by the survivor, so the merge is refused
+	if _, err := duplicates.Merge(ctx, "admin", candidateID, survivorID); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("Merge with an unshared entry = %v, want a conflict", err)
+	}
+
+	// Step 3: Once the patient shares it, everything moves
+	_, err := fs.Client.Collection("medical_history").Doc(entryID).Update(ctx, []firestore.Update{
+		{FieldPath: firestore.FieldPath{"wrapped_keys", survivorID}, Value: models.WrappedKey{EphemeralKey: make([]byte, 32), Ciphertext: []byte{2}}},
+	})
+	if err != nil {
+		t.Fatalf("sharing the entry: %v", err)
+	}
+	merge, err := duplicates.Merge(ctx, "admin", candidateID, survivorID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if merge.Moved["medical_history"] != 1 || merge.Moved["pins"] != 1 || merge.Moved["patient_profiles"] != 1 {
+		t.Errorf("moved = %v, want the entry, pin and profile", merge.Moved)
+	}
+	pin, err := fs.Client.Collection("pins").Doc(cid).Get(ctx)
+	if err != nil {
+		t.Fatalf("getting pin: %v", err)
+	}
+	if owner, _ := pin.DataAt("patient_id"); owner != survivorID {
+		t.Errorf("pin belongs to %v, want %s", owner, survivorID)
+	}
+	profile, err := fs.Client.Collection("patient_profiles").Doc(survivorID).Get(ctx)
+	if err != nil {
+		t.Fatalf("survivor has no profile: %v", err)
+	}
+	if owner, _ := profile.DataAt("patient_id"); owner != survivorID {
+		t.Errorf("profile belongs to %v, want %s", owner, survivorID)
+	}
+	if _, err := fs.Client.Collection("patient_profiles").Doc(mergedID).Get(ctx); err == nil {
+		t.Error("merged patient's profile was left behind")
+	}
+}
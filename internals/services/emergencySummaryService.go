@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	emergencySummaryTTL = 24 * time.Hour // Longest a cached summary is served; saves through this server refresh it sooner
+	compactListItems    = 8              // Items per list in the compact format
+	compactValueLength  = 40             // Longest value in the compact format, in characters
+)
+
+// cachedSummary is a stored emergency summary, encrypted like the profile it
+// comes from
+type cachedSummary struct {
+	PatientID   string    `firestore:"patient_id"`
+	Sealed      []byte    `firestore:"sealed"`
+	GeneratedAt time.Time `firestore:"generated_at"`
+	ExpiresAt   time.Time `firestore:"expires_at"`
+}
+
+// EmergencySummaryService builds patients' emergency summaries and caches them
+// in Firestore, so an emergency department gets one document read instead of
+// several
+type EmergencySummaryService struct {
+	Firestore *firebase.FirestoreClient
+	Profiles  *PatientProfileService
+	group     singleflight.Group
+}
+
+// NewEmergencySummaryService creates a new EmergencySummaryService instance
+func NewEmergencySummaryService(firestore *firebase.FirestoreClient) *EmergencySummaryService {
+	return &EmergencySummaryService{
+		Firestore: firestore,
+		Profiles:  NewPatientProfileService(firestore),
+	}
+}
+
+// GetSummary returns a patient's emergency summary from the cache, rebuilding it
+// if it is missing or stale. Concurrent rebuilds for the same patient share one,
+// made with the context of whichever caller arrived first.
+func (es *EmergencySummaryService) GetSummary(ctx context.Context, patientID string, key []byte) (*models.EmergencySummary, error) {
+	keys, err := patientKeys(ctx, es.Firestore, patientID, key)
+	if err != nil {
+		return nil, err
+	}
+	if summary := es.cached(ctx, patientID, keys); summary != nil {
+		summary.Compact = compactSummary(summary)
+		return summary, nil
+	}
+
+	result, err, _ := es.group.Do(patientID, func() (interface{}, error) {
+		return es.rebuild(ctx, patientID, keys)
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary := *result.(*models.EmergencySummary) // Callers may trim their copy
+	summary.Compact = compactSummary(&summary)
+	return &summary, nil
+}
+
+// cached returns the stored summary if it is fresh, otherwise nil
+func (es *EmergencySummaryService) cached(ctx context.Context, patientID string, keys dataKeys) *models.EmergencySummary {
+	doc, err := es.Firestore.Client.Collection("emergency_summaries").Doc(patientID).Get(ctx)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			log.Printf("Failed to read cached emergency summary of %s: %v", patientID, err)
+		}
+		return nil
+	}
+	var cached cachedSummary
+	if err := doc.DataTo(&cached); err != nil {
+		log.Printf("Failed to parse cached emergency summary: %v", err)
+		return nil
+	}
+	if !time.Now().UTC().Before(cached.ExpiresAt) {
+		return nil
+	}
+	plaintext, err := keys.decrypt(cached.Sealed)
+	if err != nil {
+		return nil
+	}
+	var summary models.EmergencySummary
+	if err := json.Unmarshal(plaintext, &summary); err != nil {
+		log.Printf("Failed to decode cached emergency summary: %v", err)
+		return nil
+	}
+	return &summary
+}
+
+// rebuild builds a patient's summary from their record and caches it
+func (es *EmergencySummaryService) rebuild(ctx context.Context, patientID string, keys dataKeys) (*models.EmergencySummary, error) {
+	// Step 1: Gather the patient, their profile and their active prescriptions
+	patient, err := getPatient(ctx, es.Firestore, patientID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := es.Profiles.findProfile(ctx, patientID, keys)
+	if err != nil {
+		return nil, err
+	}
+	prescriptions, err := activePrescriptions(ctx, es.Firestore, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Condense them
+	summary := &models.EmergencySummary{
+		PatientID:         patientID,
+		Name:              patient.Name,
+		DateOfBirth:       patient.DateOfBirth,
+		Allergies:         []models.Allergy{},
+		Conditions:        []string{},
+		Medications:       []models.CurrentMedication{},
+		EmergencyContacts: []models.EmergencyContact{},
+		HasProfile:        profile != nil,
+		GeneratedAt:       time.Now().UTC(),
+	}
+	if profile != nil {
+		if profile.DateOfBirth != "" {
+			summary.DateOfBirth = profile.DateOfBirth
+		}
+		summary.BloodType = profile.BloodType
+		summary.Allergies = profile.Allergies
+		summary.Conditions = profile.Conditions
+		summary.EmergencyContacts = profile.EmergencyContacts
+	}
+	// The most severe allergies come first
+	slices.SortStableFunc(summary.Allergies, func(a, b models.Allergy) int {
+		return slices.Index(allergySeverities, b.Severity) - slices.Index(allergySeverities, a.Severity)
+	})
+	for _, prescription := range prescriptions {
+		summary.Medications = append(summary.Medications, models.CurrentMedication{
+			Medication: prescription.Medication,
+			Dosage:     prescription.Dosage,
+			Since:      prescription.CreatedAt,
+		})
+	}
+
+	// Step 3: Cache it; a failure only costs the next caller a rebuild
+	es.store(ctx, summary, keys.seal)
+	return summary, nil
+}
+
+// store caches a summary, sealed with the patient's key. Failures are only logged.
+func (es *EmergencySummaryService) store(ctx context.Context, summary *models.EmergencySummary, key []byte) {
+	plaintext, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("Failed to encode emergency summary: %v", err)
+		return
+	}
+	sealed, err := crypto.Encrypt(plaintext, key)
+	if err != nil {
+		log.Printf("Failed to seal emergency summary of %s: %v", summary.PatientID, err)
+		return
+	}
+	_, err = es.Firestore.Client.Collection("emergency_summaries").Doc(summary.PatientID).Set(ctx, cachedSummary{
+		PatientID:   summary.PatientID,
+		Sealed:      sealed,
+		GeneratedAt: summary.GeneratedAt,
+		ExpiresAt:   summary.GeneratedAt.Add(emergencySummaryTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to cache emergency summary of %s: %v", summary.PatientID, err)
+	}
+}
+
+// invalidateEmergencySummary drops a patient's cached summary after their record
+// changes. Failures are only logged, since the cache also expires.
+func invalidateEmergencySummary(ctx context.Context, fs *firebase.FirestoreClient, patientID string) {
+	if _, err := fs.Client.Collection("emergency_summaries").Doc(patientID).Delete(ctx); err != nil {
+		log.Printf("Failed to invalidate emergency summary of %s: %v", patientID, err)
+	}
+}
+
+// activePrescriptions returns a patient's undispensed prescriptions
+func activePrescriptions(ctx context.Context, fs *firebase.FirestoreClient, patientID string) ([]*models.Prescription, error) {
+	docs, err := fs.Client.Collection("prescriptions").
+		Where("user_id", "==", patientID).
+		Where("is_active", "==", true).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query active prescriptions: %v", err)
+		return nil, err
+	}
+	return parsePrescriptions(docs), nil
+}
+
+// parsePrescriptions reads prescription documents, skipping any that fail to parse
+func parsePrescriptions(docs []*firestore.DocumentSnapshot) []*models.Prescription {
+	prescriptions := []*models.Prescription{}
+	for _, doc := range docs {
+		var p models.Prescription
+		if err := doc.DataTo(&p); err != nil {
+			log.Printf("Failed to parse prescription data: %v", err)
+			continue
+		}
+		p.ID = doc.Ref.ID
+		prescriptions = append(prescriptions, &p)
+	}
+	return prescriptions
+}
+
+// compactSummary renders a summary as short plain text for a printed card or a
+// QR code, one field per line:
+//
+//	HCES1
+//	N:Jane Doe
+//	D:1980-02-03
+//	B:O-
+//	A:Penicillin!severe;Peanuts
+//	C:Asthma
+//	M:Salbutamol 100
+//	E:John Doe (Spouse) +44 20 7946 0958
+//	T:2026-10-19T08:30Z
+//
+// Lists are separated by semicolons and end with "+N" when items were left out;
+// "none" means nothing is recorded and "?" that no clinical profile exists.
+// Values are cut to 40 characters, keeping the text well within a QR code.
+func compactSummary(summary *models.EmergencySummary) string {
+	var b strings.Builder
+	line := func(tag, value string) {
+		if value != "" {
+			b.WriteString(tag + ":" + value + "\n")
+		}
+	}
+	list := func(tag string, items []string) {
+		switch {
+		case len(items) > 0:
+			shown := items[:min(len(items), compactListItems)]
+			value := strings.Join(shown, ";")
+			if len(items) > len(shown) {
+				value += fmt.Sprintf(";+%d", len(items)-len(shown))
+			}
+			line(tag, value)
+		case summary.HasProfile || tag == "M":
+			line(tag, "none")
+		default:
+			line(tag, "?")
+		}
+	}
+
+	b.WriteString("HCES1\n")
+	line("N", compactValue(summary.Name))
+	line("D", summary.DateOfBirth)
+	line("B", summary.BloodType)
+
+	allergies := make([]string, len(summary.Allergies))
+	for i, allergy := range summary.Allergies {
+		allergies[i] = compactValue(allergy.Substance)
+		if allergy.Severity != "" {
+			allergies[i] += "!" + allergy.Severity
+		}
+	}
+	list("A", allergies)
+
+	conditions := make([]string, len(summary.Conditions))
+	for i, condition := range summary.Conditions {
+		conditions[i] = compactValue(condition)
+	}
+	list("C", conditions)
+
+	if summary.Medications != nil {
+		medications := make([]string, len(summary.Medications))
+		for i, medication := range summary.Medications {
+			medications[i] = fmt.Sprintf("%s %d", compactValue(medication.Medication), medication.Dosage)
+		}
+		list("M", medications)
+	}
+
+	contacts := make([]string, len(summary.EmergencyContacts))
+	for i, contact := range summary.EmergencyContacts {
+		contacts[i] = compactValue(contact.Name)
+		if contact.Relationship != "" {
+			contacts[i] += " (" + compactValue(contact.Relationship) + ")"
+		}
+		contacts[i] += " " + compactValue(contact.Phone)
+	}
+	list("E", contacts)
+
+	line("T", summary.GeneratedAt.UTC().Format("2006-01-02T15:04Z"))
+	return b.String()
+}
+
+// compactValue strips the compact format's separators from a value and shortens it
+func compactValue(value string) string {
+	value = strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ';' || r == '\n' || r == '\r'
+	}), ",")
+	if runes := []rune(strings.TrimSpace(value)); len(runes) > compactValueLength {
+		return string(runes[:compactValueLength-1]) + "…"
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+)
+
+func TestGetSummary(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	service := NewEmergencySummaryService(fs)
+
+	newPatient := func(t *testing.T) string {
+		patientID := testID(t)
+		_, err := fs.Client.Collection("users").Doc(patientID).Set(ctx, models.User{
+			Name: "Ann Lee", Role: "patient", DateOfBirth: "1980-02-29", CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return patientID
+	}
+
+	t.Run("without a profile", func(t *testing.T) {
+		patientID := newPatient(t)
+		summary, err := service.GetSummary(ctx, patientID, key)
+		if err != nil {
+			t.Fatalf("GetSummary: %v", err)
+		}
+		if summary.HasProfile || summary.Name != "Ann Lee" || !strings.Contains(summary.Compact, "A:?") {
+			t.Errorf("GetSummary = %+v, want an unknown-profile summary", summary)
+		}
+	})
+
+	t.Run("with a profile, then from the cache", func(t *testing.T) {
+		patientID := newPatient(t)
+		if _, err := service.Profiles.SaveProfile(ctx, patientID, patientID, testProfile(), key); err != nil {
+			t.Fatal(err)
+		}
+		for _, source := range []string{"rebuilt", "cached"} {
+			summary, err := service.GetSummary(ctx, patientID, key)
+			if err != nil {
+				t.Fatalf("%s GetSummary: %v", source, err)
+			}
+			if !summary.HasProfile || summary.BloodType != "O-" || !strings.Contains(summary.Compact, "A:Penicillin!severe") {
+				t.Errorf("%s GetSummary = %+v, want the profile's facts", source, summary)
+			}
+		}
+	})
+
+	t.Run("cache write fails", func(t *testing.T) {
+		// A key the cache cannot seal with must not stop the summary being served
+		patientID := newPatient(t)
+		if _, err := service.rebuild(ctx, patientID, dataKeys{seal: key[:31]}); err != nil {
+			t.Errorf("rebuild: %v", err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
+)
+
+// testFirestore connects to the Firestore emulator, skipping the test when
+// FIRESTORE_EMULATOR_HOST is not set
+func testFirestore(t *testing.T) *firebase.FirestoreClient {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set; start the Firestore emulator to run this test")
+	}
+	client, err := firestore.NewClient(context.Background(), "hippocard-test")
+	if err != nil {
+		t.Fatalf("connecting to the Firestore emulator: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return &firebase.FirestoreClient{Client: client}
+}
+
+// testID returns a document ID no other test run uses
+func testID(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+// testUser stores a user with role and returns their UID, which is also their
+// NFC ID
+func testUser(t *testing.T, fs *firebase.FirestoreClient, role string) string {
+	t.Helper()
+	uid := testID(t) + "-" + role
+	_, err := fs.Client.Collection("users").Doc(uid).Set(context.Background(), models.User{
+		NFCID: uid, Name: "Test " + role, Role: role, CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("storing %s: %v", role, err)
+	}
+	return uid
+}
+
+// testCareTeam stores a doctor treating a patient who consented to every scope
+func testCareTeam(t *testing.T, fs *firebase.FirestoreClient) (doctorID, patientID string) {
+	t.Helper()
+	ctx := context.Background()
+	doctorID, patientID = testUser(t, fs, "doctor"), testUser(t, fs, "patient")
+	if _, err := NewConsentService(fs).GrantConsent(ctx, patientID, doctorID, consentScopes, nil); err != nil {
+		t.Fatalf("GrantConsent: %v", err)
+	}
+	if _, err := NewCareRelationshipService(fs).Establish(ctx, doctorID, patientID); err != nil {
+		t.Fatalf("Establish: %v", err)
+	}
+	return doctorID, patientID
+}
+
+// testStore returns local blob storage in a temporary directory
+func testStore(t *testing.T) storage.BlobStore {
+	t.Helper()
+	store, err := storage.NewLocalStore(&configs.Config{Storage: configs.StorageConfig{LocalDir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const historyFetchWorkers = 8 // Concurrent blob fetches per history request
+
+// loadMedicalHistory fetches and decrypts the current version of all of a
+// patient's server-encrypted history, newest first; retracted entries are left
+// out. Blobs are fetched concurrently; entries that fail to load are skipped.
+func loadMedicalHistory(ctx context.Context, fs *firebase.FirestoreClient, store storage.BlobStore, userID string, keys dataKeys) ([]*models.MedicalHistoryEntry, error) {
+	docs, err := fs.Client.Collection("medical_history").
+		Where("user_id", "==", userID).
+		OrderBy("created_at", firestore.Desc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query medical history: %v", err)
+		return nil, err
+	}
+	return openMedicalHistory(ctx, store, docs, keys), nil
+}
+
+// pageMedicalHistory is loadMedicalHistory a page at a time, filtered and sorted
+// by created_at (newest first by default)
+func pageMedicalHistory(ctx context.Context, fs *firebase.FirestoreClient, store storage.BlobStore, userID string, q models.PageQuery, keys dataKeys) (*models.Page[*models.MedicalHistoryEntry], error) {
+	order, err := parsePageOrder(q.Sort, "-created_at", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	query := fs.Client.Collection("medical_history").Where("user_id", "==", userID)
+	docs, nextCursor, err := timePage(ctx, query, order, q, pageLimit(q.Limit, defaultPageLimit, maxPageLimit), serverReadableHistory)
+	if err != nil {
+		log.Printf("Failed to query medical history: %v", err)
+		return nil, err
+	}
+	return &models.Page[*models.MedicalHistoryEntry]{
+		Items:      openMedicalHistory(ctx, store, docs, keys),
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// serverReadableHistory reports whether an entry has current content the server
+// can decrypt. End-to-end entries can only be decrypted by their readers'
+// devices, and retracted entries have no current content.
+func serverReadableHistory(doc *firestore.DocumentSnapshot) bool {
+	e2e, _ := doc.DataAt("e2e")
+	retracted, _ := doc.DataAt("retracted")
+	return e2e != true && retracted != true
+}
+
+// openMedicalHistory decrypts the current version of each server-readable entry,
+// keeping their order
+func openMedicalHistory(ctx context.Context, store storage.BlobStore, docs []*firestore.DocumentSnapshot, keys dataKeys) []*models.MedicalHistoryEntry {
+	// Step 1: Parse the entries
+	var records []models.MedicalHistory
+	for _, doc := range docs {
+		if !serverReadableHistory(doc) {
+			continue
+		}
+		var mh models.MedicalHistory
+		if err := doc.DataTo(&mh); err != nil {
+			log.Printf("Failed to parse medical history: %v", err)
+			continue
+		}
+		mh.ID = doc.Ref.ID
+		records = append(records, mh)
+	}
+
+	// Step 2: Fetch encrypted data from storage in parallel
+	cids := make([]string, len(records))
+	for i, mh := range records {
+		cids[i] = mh.CID
+	}
+	blobs := storage.GetMany(ctx, store, cids, historyFetchWorkers)
+
+	// Step 3: Decrypt and decode in the original order
+	history := []*models.MedicalHistoryEntry{}
+	for i, mh := range records {
+		entry, err := openHistoryBlob(blobs[i], mh.ID, mh.CID, mh.Type, keys)
+		if err != nil {
+			continue
+		}
+		entry.Version = historyVersion(mh)
+		entry.AuthorID = mh.AuthorID
+		entry.Reason = mh.Reason
+		entry.CreatedAt = mh.CreatedAt
+		entry.UpdatedAt = mh.UpdatedAt
+		history = append(history, entry)
+	}
+	return history
+}
+
+// loadHistoryVersions fetches and decrypts every version of a server-encrypted
+// entry, oldest first. Versions that fail to load are skipped.
+func loadHistoryVersions(ctx context.Context, store storage.BlobStore, mh *models.MedicalHistory, keys dataKeys) *models.MedicalHistoryVersions {
+	// Step 1: List the versions, the current one last
+	versions := append([]models.HistoryVersion{}, mh.Versions...)
+	versions = append(versions, models.HistoryVersion{
+		Version:   historyVersion(*mh),
+		CID:       mh.CID,
+		Type:      mh.Type,
+		AuthorID:  mh.AuthorID,
+		Reason:    mh.Reason,
+		CreatedAt: historyVersionTime(*mh),
+	})
+
+	// Step 2: Fetch their blobs in parallel; a retraction has none
+	cids := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if v.CID != "" {
+			cids = append(cids, v.CID)
+		}
+	}
+	blobs := storage.GetMany(ctx, store, cids, historyFetchWorkers)
+
+	// Step 3: Decrypt and decode in order
+	result := &models.MedicalHistoryVersions{
+		ID:        mh.ID,
+		PatientID: mh.UserID,
+		Versions:  []*models.MedicalHistoryEntry{},
+	}
+	next := 0
+	for _, v := range versions {
+		entry := &models.MedicalHistoryEntry{ID: mh.ID, Retracted: true}
+		if v.CID != "" {
+			var err error
+			entry, err = openHistoryBlob(blobs[next], mh.ID, v.CID, v.Type, keys)
+			next++
+			if err != nil {
+				continue
+			}
+		}
+		entry.Version = v.Version
+		entry.AuthorID = v.AuthorID
+		entry.Reason = v.Reason
+		entry.CreatedAt = v.CreatedAt
+		result.Versions = append(result.Versions, entry)
+	}
+	return result
+}
+
+// openHistoryBlob decrypts and decodes one fetched history blob
+func openHistoryBlob(blob storage.BlobResult, entryID, cid, storedType string, keys dataKeys) (*models.MedicalHistoryEntry, error) {
+	if blob.Err != nil {
+		log.Printf("Failed to fetch from storage for CID %s: %v", cid, blob.Err)
+		return nil, blob.Err
+	}
+	decryptedData, err := keys.decrypt(blob.Data)
+	if err != nil {
+		log.Printf("Failed to decrypt history for CID %s: %v", cid, err)
+		return nil, err
+	}
+	return decodeHistoryEntry(entryID, storedType, decryptedData)
+}
+
+// historyVersion returns the current version number of an entry
+func historyVersion(mh models.MedicalHistory) int {
+	return max(mh.Version, 1)
+}
+
+// historyVersionTime returns when the current version of an entry was written
+func historyVersionTime(mh models.MedicalHistory) time.Time {
+	if mh.UpdatedAt != nil {
+		return *mh.UpdatedAt
+	}
+	return mh.CreatedAt
+}
+
+// getHistoryRecord loads the metadata of a medical history entry
+func getHistoryRecord(ctx context.Context, fs *firebase.FirestoreClient, entryID string) (*models.MedicalHistory, error) {
+	doc, err := fs.Client.Collection("medical_history").Doc(entryID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NotFound("Medical history entry not found: " + entryID)
+		}
+		log.Printf("Failed to get medical history %s: %v", entryID, err)
+		return nil, err
+	}
+	var mh models.MedicalHistory
+	if err := doc.DataTo(&mh); err != nil {
+		log.Printf("Failed to parse medical history: %v", err)
+		return nil, err
+	}
+	mh.ID = doc.Ref.ID
+	return &mh, nil
+}
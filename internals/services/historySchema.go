@@ -0,0 +1,121 @@
+package services
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/schema"
+)
+
+const maxAmendReasonLength = 500 // Longest reason for amending or retracting an entry, in characters
+
+// ErrInvalidHistoryEntry is returned when a history entry does not match its type's schema
+var ErrInvalidHistoryEntry = errs.Invalid("invalid medical history entry")
+
+// historySchemaFiles holds one JSON schema per history type, named after the type
+//
+//go:embed schemas/history/*.json
+var historySchemaFiles embed.FS
+
+var (
+	historyTypes = []string{
+		models.HistoryTypeDiagnosis,
+		models.HistoryTypeProcedure,
+		models.HistoryTypeLabResult,
+		models.HistoryTypeVitalSigns,
+		models.HistoryTypeImmunization,
+		models.HistoryTypeNote,
+	}
+	historySchemas = loadHistorySchemas()
+)
+
+// historyPayload is what a server-encrypted history blob holds
+type historyPayload struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// historyNote is the data of a note entry
+type historyNote struct {
+	Text string `json:"text"`
+}
+
+// HistorySchemas returns the JSON schema of every history type, by type
+func HistorySchemas() map[string]json.RawMessage {
+	schemas := make(map[string]json.RawMessage, len(historyTypes))
+	for _, entryType := range historyTypes {
+		data, _ := historySchemaFiles.ReadFile(historySchemaPath(entryType))
+		schemas[entryType] = data
+	}
+	return schemas
+}
+
+// validateHistoryEntry checks an entry's data against the schema of its type
+func validateHistoryEntry(entryType string, data json.RawMessage) error {
+	s, ok := historySchemas[entryType]
+	if !ok {
+		return fmt.Errorf("%w: type must be one of %s", ErrInvalidHistoryEntry, strings.Join(historyTypes, ", "))
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%w: data is required", ErrInvalidHistoryEntry)
+	}
+	if err := s.Validate(data); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidHistoryEntry, entryType, err)
+	}
+	return nil
+}
+
+// decodeHistoryEntry turns a decrypted blob stored with storedType back into an
+// entry's type and data. Blobs written before types are plain text and read as notes.
+func decodeHistoryEntry(entryID, storedType string, plaintext []byte) (*models.MedicalHistoryEntry, error) {
+	entry := &models.MedicalHistoryEntry{ID: entryID}
+	if storedType == "" {
+		entry.Type = models.HistoryTypeNote
+		entry.History = string(plaintext)
+		data, err := json.Marshal(historyNote{Text: entry.History})
+		if err != nil {
+			return nil, err
+		}
+		entry.Data = data
+		return entry, nil
+	}
+
+	var payload historyPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		log.Printf("Failed to decode medical history %s: %v", entryID, err)
+		return nil, err
+	}
+	entry.Type = payload.Type
+	entry.Data = payload.Data
+	if entry.Type == models.HistoryTypeNote {
+		var note historyNote
+		if err := json.Unmarshal(payload.Data, &note); err == nil {
+			entry.History = note.Text
+		}
+	}
+	return entry, nil
+}
+
+// loadHistorySchemas parses the embedded schemas; a broken one stops the server
+func loadHistorySchemas() map[string]*schema.Schema {
+	schemas := make(map[string]*schema.Schema, len(historyTypes))
+	for _, entryType := range historyTypes {
+		data, err := historySchemaFiles.ReadFile(historySchemaPath(entryType))
+		if err != nil {
+			log.Fatalf("Missing schema for history type %s: %v", entryType, err)
+		}
+		schemas[entryType] = schema.MustParse(data)
+	}
+	return schemas
+}
+
+// historySchemaPath is where the schema of a history type is embedded
+func historySchemaPath(entryType string) string {
+	return path.Join("schemas", "history", entryType+".json")
+}
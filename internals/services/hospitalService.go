@@ -1,71 +1,208 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"log"
+	"slices"
 	"time"
 
 	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
 
-	//"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const breakGlassTTL = time.Hour // How long custodians have to approve an emergency request
+
 type HospitalService struct {
 	Firestore *firebase.FirestoreClient
-	IPFS      *storage.IPFSClient
+	Storage   storage.BlobStore
+	Consents  *ConsentService
+	Profiles  *PatientProfileService
+	Summaries *EmergencySummaryService
 }
 
-func NewHospitalService(firestore *firebase.FirestoreClient, ipfs *storage.IPFSClient) *HospitalService {
+func NewHospitalService(firestore *firebase.FirestoreClient, store storage.BlobStore) *HospitalService {
 	return &HospitalService{
 		Firestore: firestore,
-		IPFS:      ipfs,
+		Storage:   store,
+		Consents:  NewConsentService(firestore),
+		Profiles:  NewPatientProfileService(firestore),
+		Summaries: NewEmergencySummaryService(firestore),
 	}
 }
 
-func (hs *HospitalService) GetPatientData(nfcID string, key []byte) (*models.HospitalPatientData, error) {
-	ctx := context.Background()
-
+// GetPatientData returns the parts of a patient's record the hospital has consent for
+func (hs *HospitalService) GetPatientData(ctx context.Context, hospitalID, nfcID string, key []byte) (*models.HospitalPatientData, error) {
 	// Step 1: Find patient by NFC ID
-	userDocs, err := hs.Firestore.Client.Collection("users").
+	patient, err := findPatientByNFC(ctx, hs.Firestore, nfcID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := hs.Consents.AuthorizedScopes(ctx, hospitalID, patient.UID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := patientKeys(ctx, hs.Firestore, patient.UID, key)
+	if err != nil {
+		return nil, err
+	}
+	return hs.collectPatientData(ctx, patient, scopes, keys)
+}
+
+// GetEmergencySummary returns the short emergency view of a patient: allergies,
+// blood type, conditions, contacts and, with prescription consent, current
+// medications. It needs the patient's profile consent or an emergency override.
+func (hs *HospitalService) GetEmergencySummary(ctx context.Context, hospitalID, nfcID string, key []byte) (*models.EmergencySummary, error) {
+	patient, err := findPatientByNFC(ctx, hs.Firestore, nfcID)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := hs.Consents.AuthorizedScopes(ctx, hospitalID, patient.UID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(scopes, models.ConsentScopeProfile) {
+		return nil, ErrConsentRequired
+	}
+
+	summary, err := hs.Summaries.GetSummary(ctx, patient.UID, key)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(scopes, models.ConsentScopePrescriptions) {
+		summary.Medications = nil
+		summary.Compact = compactSummary(summary)
+	}
+	return summary, nil
+}
+
+// findPatientByNFC looks up a patient by the NFC ID on their card. The card of
+// a patient merged into another record resolves to the surviving patient.
+func findPatientByNFC(ctx context.Context, fs *firebase.FirestoreClient, nfcID string) (*models.User, error) {
+	userDocs, err := fs.Client.Collection("users").
 		Where("nfc_id", "==", nfcID).
-		Where("role", "==", "patient").
+		Where("role", "in", []string{"patient", "merged"}).
 		Documents(ctx).GetAll()
 	if err != nil {
 		log.Printf("Failed to query patient by NFC ID: %v", err)
 		return nil, err
 	}
 	if len(userDocs) == 0 {
-		return nil, logError("Patient not found with NFC ID: " + nfcID)
+		return nil, errs.NotFound("Patient not found with NFC ID: " + nfcID)
+	}
+
+	// Prefer a live record over a tombstone carrying the same card
+	doc := userDocs[0]
+	for _, userDoc := range userDocs {
+		if role, _ := userDoc.DataAt("role"); role == "patient" {
+			doc = userDoc
+			break
+		}
+	}
+	if role, _ := doc.DataAt("role"); role == "merged" {
+		survivorID, _ := doc.DataAt("merged_into")
+		id, _ := survivorID.(string)
+		if id == "" {
+			return nil, errs.NotFound("Patient not found with NFC ID: " + nfcID)
+		}
+		return getPatient(ctx, fs, id)
 	}
 
 	var patient models.User
-	if err := userDocs[0].DataTo(&patient); err != nil {
+	if err := doc.DataTo(&patient); err != nil {
 		log.Printf("Failed to parse patient data: %v", err)
 		return nil, err
 	}
-	patient.UID = userDocs[0].Ref.ID
+	patient.UID = doc.Ref.ID
+	return &patient, nil
+}
 
-	// Step 2: Fetch prescriptions (placeholder until blockchain)
-	prescriptions, err := hs.getPrescriptions(patient.UID)
+// getPatient loads a live patient's user document by UID
+func getPatient(ctx context.Context, fs *firebase.FirestoreClient, uid string) (*models.User, error) {
+	doc, err := fs.Client.Collection("users").Doc(uid).Get(ctx)
 	if err != nil {
-		log.Printf("Failed to fetch prescriptions: %v", err)
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NotFound("Patient not found: " + uid)
+		}
+		log.Printf("Failed to get patient %s: %v", uid, err)
 		return nil, err
 	}
+	if role, _ := doc.DataAt("role"); role != "patient" {
+		return nil, errs.NotFound("Patient not found: " + uid)
+	}
+	var patient models.User
+	if err := doc.DataTo(&patient); err != nil {
+		log.Printf("Failed to parse patient data: %v", err)
+		return nil, err
+	}
+	patient.UID = doc.Ref.ID
+	return &patient, nil
+}
+
+// requirePatient checks that a patient referenced by a request field exists and
+// has the patient role, reporting it against the field when it does not
+func requirePatient(ctx context.Context, fs *firebase.FirestoreClient, field, uid string) error {
+	if _, err := getPatient(ctx, fs, uid); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return errs.InvalidFields(errs.FieldError{Field: field, Message: "must be an existing patient"})
+		}
+		return err
+	}
+	return nil
+}
+
+// collectPatientData gathers the consented parts of a patient's record:
+// profile, clinical profile, prescriptions and decrypted history
+func (hs *HospitalService) collectPatientData(ctx context.Context, patient *models.User, scopes []string, keys dataKeys) (*models.HospitalPatientData, error) {
+	// Without profile consent only the identifiers the hospital already holds are returned
+	var profile *models.PatientProfile
+	if slices.Contains(scopes, models.ConsentScopeProfile) {
+		var err error
+		if profile, err = hs.Profiles.findProfile(ctx, patient.UID, keys); err != nil {
+			log.Printf("Failed to fetch patient profile: %v", err)
+			return nil, err
+		}
+	} else {
+		patient = &models.User{UID: patient.UID, NFCID: patient.NFCID, Role: patient.Role}
+	}
+
+	// Step 2: Fetch prescriptions (placeholder until blockchain)
+	var prescriptions []*models.Prescription
+	var err error
+	if slices.Contains(scopes, models.ConsentScopePrescriptions) {
+		prescriptions, err = hs.getPrescriptions(ctx, patient.UID)
+		if err != nil {
+			log.Printf("Failed to fetch prescriptions: %v", err)
+			return nil, err
+		}
+	}
 
 	// Step 3: Fetch medical history
-	medicalHistory, err := hs.getMedicalHistory(patient.UID, key)
-	if err != nil {
-		log.Printf("Failed to fetch medical history: %v", err)
-		return nil, err
+	var medicalHistory []*models.MedicalHistoryEntry
+	if slices.Contains(scopes, models.ConsentScopeHistory) {
+		medicalHistory, err = hs.getMedicalHistory(ctx, patient.UID, keys)
+		if err != nil {
+			log.Printf("Failed to fetch medical history: %v", err)
+			return nil, err
+		}
 	}
 
 	// Step 4: Prepare response for one-time access
 	result := &models.HospitalPatientData{
-		Patient:        &patient,
+		Patient:        patient,
+		Profile:        profile,
+		Scopes:         scopes,
 		Prescriptions:  prescriptions,
 		MedicalHistory: medicalHistory,
 		AccessTime:     time.Now().UTC(),
@@ -73,54 +210,265 @@ func (hs *HospitalService) GetPatientData(nfcID string, key []byte) (*models.Hos
 	}
 
 	// Step 5: Simulate one-time access by logging
-	log.Println("One-time access granted for patient:", nfcID)
+	log.Println("One-time access granted for patient:", patient.NFCID)
 
 	return result, nil
 }
 
-func (hs *HospitalService) getPrescriptions(userID string) ([]*models.Prescription, error) {
+func (hs *HospitalService) getPrescriptions(ctx context.Context, userID string) ([]*models.Prescription, error) {
 	// TODO: Implement with blockchain NFT data
 	log.Println("GetPrescriptions not implemented yet—waiting for blockchain")
 	return nil, nil
 }
 
-func (hs *HospitalService) getMedicalHistory(userID string, key []byte) ([]*models.MedicalHistoryEntry, error) {
-	ctx := context.Background()
+func (hs *HospitalService) getMedicalHistory(ctx context.Context, userID string, keys dataKeys) ([]*models.MedicalHistoryEntry, error) {
+	return loadMedicalHistory(ctx, hs.Firestore, hs.Storage, userID, keys)
+}
 
-	docs, err := hs.Firestore.Client.Collection("medical_history").
-		Where("user_id", "==", userID).
-		Documents(ctx).GetAll()
+// RequestBreakGlass opens an emergency request to rebuild a patient's escrowed key
+func (hs *HospitalService) RequestBreakGlass(ctx context.Context, hospitalID, nfcID, reason string) (*models.BreakGlassRequest, error) {
+	if reason == "" {
+		return nil, errs.Invalid("A reason is required for emergency access")
+	}
+
+	// Step 1: Find the patient and make sure their key is escrowed
+	patient, err := findPatientByNFC(ctx, hs.Firestore, nfcID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := hs.getKeyEscrow(ctx, patient.UID); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Save the pending request
+	now := time.Now().UTC()
+	request := &models.BreakGlassRequest{
+		ID:          uuid.New().String(),
+		PatientID:   patient.UID,
+		NFCID:       nfcID,
+		RequesterID: hospitalID,
+		Reason:      reason,
+		Status:      "pending",
+		Approvals:   []string{},
+		Shares:      map[string][]byte{},
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(breakGlassTTL),
+	}
+	_, err = hs.Firestore.Client.Collection("break_glass_requests").Doc(request.ID).Set(ctx, request)
+	if err != nil {
+		log.Printf("Failed to save break-glass request: %v", err)
+		return nil, err
+	}
+
+	if err := hs.logBreakGlassEvent(ctx, request, hospitalID, "requested", reason); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// GetEscrowShare returns a custodian's share of the patient's key for an open
+// request, still wrapped to the custodian's public key. The custodian unwraps
+// it on their own device and submits it with ApproveBreakGlass.
+func (hs *HospitalService) GetEscrowShare(ctx context.Context, requestID, custodianID string) (*models.WrappedKey, error) {
+	var request models.BreakGlassRequest
+	ref := hs.Firestore.Client.Collection("break_glass_requests").Doc(requestID)
+	err := hs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return getBreakGlassRequest(tx, ref, &request)
+	}, firestore.ReadOnly)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errs.NotFound("Break-glass request not found: " + requestID)
+		}
+		return nil, err
+	}
+	if request.Status != "pending" && request.Status != "approved" {
+		return nil, errs.Conflict("Break-glass request is " + request.Status)
+	}
+
+	escrow, err := hs.getKeyEscrow(ctx, request.PatientID)
 	if err != nil {
-		log.Printf("Failed to query medical history: %v", err)
 		return nil, err
 	}
+	share, ok := escrow.Shares[custodianID]
+	if !ok {
+		return nil, errs.Forbidden("Not a custodian for this patient: " + custodianID)
+	}
+	if err := hs.logBreakGlassEvent(ctx, &request, custodianID, "share_collected", ""); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ApproveBreakGlass records a custodian's share against a pending request. Once
+// enough custodians have approved, the request can be redeemed by its requester.
+func (hs *HospitalService) ApproveBreakGlass(ctx context.Context, requestID, custodianID, encodedShare string) (*models.BreakGlassRequest, error) {
+	share, err := base64.StdEncoding.DecodeString(encodedShare)
+	if err != nil {
+		return nil, errs.Invalid("Share is not valid base64")
+	}
 
-	var history []*models.MedicalHistoryEntry
-	for _, doc := range docs {
-		var mh models.MedicalHistory
-		if err := doc.DataTo(&mh); err != nil {
-			log.Printf("Failed to parse medical history: %v", err)
-			continue
+	var request models.BreakGlassRequest
+	ref := hs.Firestore.Client.Collection("break_glass_requests").Doc(requestID)
+	err = hs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := getBreakGlassRequest(tx, ref, &request); err != nil {
+			return err
+		}
+		if request.Status != "pending" && request.Status != "approved" {
+			return errs.Conflict("Break-glass request is " + request.Status)
 		}
-		mh.ID = doc.Ref.ID
 
-		encryptedData, err := hs.IPFS.GetData(mh.CID)
+		escrow, err := hs.getKeyEscrow(ctx, request.PatientID)
 		if err != nil {
-			log.Printf("Failed to fetch from IPFS for CID %s: %v", mh.CID, err)
-			continue
+			return err
+		}
+		fingerprint, ok := escrow.ShareFingerprints[custodianID]
+		if !ok {
+			return errs.Forbidden("Not a custodian for this patient: " + custodianID)
+		}
+		if !crypto.FingerprintMatches(share, fingerprint) {
+			return errs.Forbidden("Share does not match custodian record: " + custodianID)
+		}
+		if _, done := request.Shares[custodianID]; done {
+			return errs.Conflict("Custodian has already approved: " + custodianID)
 		}
 
-		decryptedData, err := crypto.Decrypt(encryptedData, key)
+		if request.Shares == nil {
+			request.Shares = map[string][]byte{}
+		}
+		request.Shares[custodianID] = share
+		request.Approvals = append(request.Approvals, custodianID)
+		if len(request.Approvals) >= escrow.Threshold {
+			request.Status = "approved"
+		}
+		return tx.Set(ref, request)
+	})
+	if err != nil {
+		log.Printf("Failed to approve break-glass request %s: %v", requestID, err)
+		return nil, err
+	}
+
+	if err := hs.logBreakGlassEvent(ctx, &request, custodianID, "approved", ""); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// RedeemBreakGlass rebuilds the patient's key from an approved request and returns
+// their data. A request can be redeemed once; the shares are wiped afterwards.
+// The master key only opens what the patient's own key cannot: records merged in
+// from another patient and records written before the patient had a key.
+func (hs *HospitalService) RedeemBreakGlass(ctx context.Context, requestID, hospitalID string, key []byte) (*models.HospitalPatientData, error) {
+	var request models.BreakGlassRequest
+	var keys dataKeys
+	ref := hs.Firestore.Client.Collection("break_glass_requests").Doc(requestID)
+	err := hs.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := getBreakGlassRequest(tx, ref, &request); err != nil {
+			return err
+		}
+		if request.RequesterID != hospitalID {
+			return errs.Forbidden("Break-glass request belongs to another requester")
+		}
+		if request.Status != "approved" {
+			return errs.Conflict("Break-glass request is " + request.Status)
+		}
+
+		escrow, err := hs.getKeyEscrow(ctx, request.PatientID)
 		if err != nil {
-			log.Printf("Failed to decrypt history for CID %s: %v", mh.CID, err)
-			continue
+			return err
 		}
 
-		history = append(history, &models.MedicalHistoryEntry{
-			History:   string(decryptedData),
-			CreatedAt: mh.CreatedAt,
+		shares := make([][]byte, 0, len(request.Shares))
+		for _, share := range request.Shares {
+			shares = append(shares, share)
+		}
+		rebuilt, err := crypto.CombineShares(shares)
+		if err != nil {
+			return errs.Conflict("Approved shares could not be combined into a key")
+		}
+		if !crypto.FingerprintMatches(rebuilt, escrow.KeyFingerprint) {
+			return errs.Conflict("Reconstructed key does not match escrow record")
+		}
+		if keys, err = patientKeys(ctx, hs.Firestore, request.PatientID, key); err != nil {
+			return err
+		}
+		if !bytes.Equal(rebuilt, keys.seal) {
+			return errs.Conflict("Escrowed key is no longer the patient's data key")
+		}
+
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: "redeemed"},
+			{Path: "shares", Value: firestore.Delete},
 		})
+	})
+	if err != nil {
+		log.Printf("Failed to redeem break-glass request %s: %v", requestID, err)
+		if request.ID != "" {
+			hs.logBreakGlassEvent(ctx, &request, hospitalID, "rejected", err.Error())
+		}
+		return nil, err
+	}
+
+	if err := hs.logBreakGlassEvent(ctx, &request, hospitalID, "redeemed", request.Reason); err != nil {
+		return nil, err
 	}
 
-	return history, nil
+	// A quorum of custodians stands in for consent; the audit log flags the access
+	recordEmergencyAccess(ctx, request.Reason)
+	patient, err := findPatientByNFC(ctx, hs.Firestore, request.NFCID)
+	if err != nil {
+		return nil, err
+	}
+	return hs.collectPatientData(ctx, patient, consentScopes, keys)
+}
+
+// getBreakGlassRequest reads a request inside a transaction, expiring stale ones
+func getBreakGlassRequest(tx *firestore.Transaction, ref *firestore.DocumentRef, request *models.BreakGlassRequest) error {
+	doc, err := tx.Get(ref)
+	if err != nil {
+		log.Printf("Failed to get break-glass request: %v", err)
+		return err
+	}
+	if err := doc.DataTo(request); err != nil {
+		log.Printf("Failed to parse break-glass request: %v", err)
+		return err
+	}
+	if request.Status != "redeemed" && time.Now().UTC().After(request.ExpiresAt) {
+		request.Status = "expired"
+	}
+	return nil
+}
+
+// getKeyEscrow loads the escrow record for a patient
+func (hs *HospitalService) getKeyEscrow(ctx context.Context, patientID string) (*models.KeyEscrow, error) {
+	doc, err := hs.Firestore.Client.Collection("key_escrows").Doc(patientID).Get(ctx)
+	if err != nil {
+		log.Printf("No key escrow for patient %s: %v", patientID, err)
+		return nil, err
+	}
+	var escrow models.KeyEscrow
+	if err := doc.DataTo(&escrow); err != nil {
+		log.Printf("Failed to parse key escrow: %v", err)
+		return nil, err
+	}
+	return &escrow, nil
+}
+
+// logBreakGlassEvent appends a step of an emergency access to the event log
+func (hs *HospitalService) logBreakGlassEvent(ctx context.Context, request *models.BreakGlassRequest, actorID, action, reason string) error {
+	event := models.BreakGlassEvent{
+		ID:        uuid.New().String(),
+		RequestID: request.ID,
+		PatientID: request.PatientID,
+		ActorID:   actorID,
+		Action:    action,
+		Reason:    reason,
+		Time:      time.Now().UTC(),
+	}
+	_, err := hs.Firestore.Client.Collection("break_glass_events").Doc(event.ID).Set(ctx, event)
+	if err != nil {
+		log.Printf("Failed to log break-glass event for request %s: %v", request.ID, err)
+		return err
+	}
+	log.Printf("Break-glass %s for patient %s by %s (request %s)", action, request.PatientID, actorID, request.ID)
+	return nil
 }
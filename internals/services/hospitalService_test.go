@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+)
+
+func TestBreakGlass(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	master, _ := crypto.GenerateKey()
+	hospitals := NewHospitalService(fs, testStore(t))
+	patients := NewPatientService(fs, testStore(t))
+	patientID := testUser(t, fs, "patient")
+	requesterID := testUser(t, fs, "hospital")
+	t.Cleanup(func() {
+		for _, collection := range []string{"data_keys", "key_escrows", "patient_profiles", "emergency_summaries"} {
+			fs.Client.Collection(collection).Doc(patientID).Delete(context.Background())
+		}
+	})
+	if _, err := hospitals.Profiles.SaveProfile(ctx, patientID, patientID, testProfile(), master); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+
+	// Step 1: Escrow the patient's key with three custodians, two needed
+	custodians := make([]string, 3)
+	privateKeys := map[string][]byte{}
+	for i := range custodians {
+		id := testUser(t, fs, "hospital")
+		private, public, _ := crypto.GenerateKeyPair()
+		if err := patients.Keys.PublishPublicKey(ctx, id, public); err != nil {
+			t.Fatalf("PublishPublicKey: %v", err)
+		}
+		custodians[i], privateKeys[id] = id, private
+	}
+	escrow, err := patients.EscrowKey(ctx, patientID, master, custodians, 2)
+	if err != nil {
+		t.Fatalf("EscrowKey: %v", err)
+	}
+	body, _ := json.Marshal(escrow)
+	if strings.Contains(string(body), "share") || strings.Contains(string(body), "fingerprint") {
+		t.Errorf("escrow response %s exposes shares", body)
+	}
+	keys, _ := patientKeys(ctx, fs, patientID, master)
+	if !crypto.FingerprintMatches(keys.seal, escrow.KeyFingerprint) || crypto.FingerprintMatches(master, escrow.KeyFingerprint) {
+		t.Fatal("escrow is not of the patient's own key")
+	}
+
+	// Step 2: Open a request; only custodians can collect a share
+	request, err := hospitals.RequestBreakGlass(ctx, requesterID, patientID, "Unconscious in ED")
+	if err != nil {
+		t.Fatalf("RequestBreakGlass: %v", err)
+	}
+	if _, err := hospitals.GetEscrowShare(ctx, request.ID, requesterID); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("GetEscrowShare by the requester = %v, want forbidden", err)
+	}
+	if _, err := hospitals.RedeemBreakGlass(ctx, request.ID, requesterID, master); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("RedeemBreakGlass before approval = %v, want a conflict", err)
+	}
+
+	// Step 3: Two custodians unwrap their shares and approve
+	for _, id := range custodians[:2] {
+		wrapped, err := hospitals.GetEscrowShare(ctx, request.ID, id)
+		if err != nil {
+			t.Fatalf("GetEscrowShare: %v", err)
+		}
+		share, err := crypto.UnwrapKey(privateKeys[id], wrapped.EphemeralKey, wrapped.Ciphertext)
+		if err != nil {
+			t.Fatalf("unwrapping share of %s: %v", id, err)
+		}
+		if _, err := hospitals.ApproveBreakGlass(ctx, request.ID, id, base64.StdEncoding.EncodeToString(share)); err != nil {
+			t.Fatalf("ApproveBreakGlass: %v", err)
+		}
+	}
+
+	// Step 4: The requester redeems it once
+	data, err := hospitals.RedeemBreakGlass(ctx, request.ID, requesterID, master)
+	if err != nil {
+		t.Fatalf("RedeemBreakGlass: %v", err)
+	}
+	if data.Profile == nil || data.Profile.BloodType != "O-" {
+		t.Errorf("RedeemBreakGlass profile = %+v, want the sealed profile", data.Profile)
+	}
+	if _, err := hospitals.RedeemBreakGlass(ctx, request.ID, requesterID, master); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("second RedeemBreakGlass = %v, want a conflict", err)
+	}
+	if _, err := hospitals.GetEscrowShare(ctx, request.ID, custodians[2]); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("GetEscrowShare after redemption = %v, want a conflict", err)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeyService manages public keys and access grants for end-to-end encrypted records
+type KeyService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewKeyService creates a new KeyService instance
+func NewKeyService(firestore *firebase.FirestoreClient) *KeyService {
+	return &KeyService{
+		Firestore: firestore,
+	}
+}
+
+// PublishPublicKey stores or replaces a user's X25519 public key
+func (ks *KeyService) PublishPublicKey(ctx context.Context, userID string, key []byte) error {
+	if _, err := crypto.ParsePublicKey(key); err != nil {
+		log.Printf("Rejected public key for user %s: %v", userID, err)
+		return errs.Invalid("Public key must be a 32-byte X25519 key")
+	}
+
+	_, err := ks.Firestore.Client.Collection("public_keys").Doc(userID).Set(ctx, models.PublicKey{
+		UserID:    userID,
+		Key:       key,
+		Algorithm: "x25519",
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to save public key for user %s: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// GetPublicKey fetches a user's published public key
+func (ks *KeyService) GetPublicKey(ctx context.Context, userID string) (*models.PublicKey, error) {
+	doc, err := ks.Firestore.Client.Collection("public_keys").Doc(userID).Get(ctx)
+	if err != nil {
+		log.Printf("Failed to get public key for user %s: %v", userID, err)
+		return nil, err
+	}
+
+	var key models.PublicKey
+	if err := doc.DataTo(&key); err != nil {
+		log.Printf("Failed to parse public key: %v", err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GrantHistoryAccess adds a data key re-wrapped by the patient for another reader.
+// The re-wrapping happens on the patient's device; the server only stores the result.
+func (ks *KeyService) GrantHistoryAccess(ctx context.Context, patientID, entryID, recipientID string, wrapped models.WrappedKey) error {
+	if err := validateWrappedKey(wrapped); err != nil {
+		return err
+	}
+	if _, err := ks.GetPublicKey(ctx, recipientID); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.Invalid("Recipient has not published a public key: " + recipientID)
+		}
+		return err
+	}
+
+	ref := ks.Firestore.Client.Collection("medical_history").Doc(entryID)
+	return ks.Firestore.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			log.Printf("Failed to get medical history %s: %v", entryID, err)
+			return err
+		}
+		var mh models.MedicalHistory
+		if err := doc.DataTo(&mh); err != nil {
+			log.Printf("Failed to parse medical history: %v", err)
+			return err
+		}
+		if mh.UserID != patientID || !mh.E2E {
+			return errs.NotFound("No end-to-end entry " + entryID + " for patient " + patientID)
+		}
+		return tx.Update(ref, []firestore.Update{
+			{FieldPath: firestore.FieldPath{"wrapped_keys", recipientID}, Value: wrapped},
+		})
+	})
+}
+
+// validateWrappedKey checks the shape of a wrapped key; its contents stay opaque
+func validateWrappedKey(wrapped models.WrappedKey) error {
+	if _, err := crypto.ParsePublicKey(wrapped.EphemeralKey); err != nil {
+		return errs.Invalid("Wrapped key ephemeral_key must be a 32-byte X25519 key")
+	}
+	if len(wrapped.Ciphertext) == 0 {
+		return errs.Invalid("Wrapped key ciphertext is empty")
+	}
+	return nil
+}
+
+// fetchEncryptedHistory returns a patient's end-to-end entries readable by readerID,
+// each with the data key wrapped for that reader
+func fetchEncryptedHistory(ctx context.Context, fs *firebase.FirestoreClient, store storage.BlobStore, patientID, readerID string) ([]*models.EncryptedHistoryEntry, error) {
+	docs, err := fs.Client.Collection("medical_history").
+		Where("user_id", "==", patientID).
+		Where("e2e", "==", true).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Failed to query encrypted medical history: %v", err)
+		return nil, err
+	}
+
+	var entries []*models.EncryptedHistoryEntry
+	for _, doc := range docs {
+		var mh models.MedicalHistory
+		if err := doc.DataTo(&mh); err != nil {
+			log.Printf("Failed to parse medical history: %v", err)
+			continue
+		}
+		wrapped, ok := mh.WrappedKeys[readerID]
+		if !ok {
+			continue
+		}
+
+		ciphertext, err := store.GetData(ctx, mh.CID)
+		if err != nil {
+			log.Printf("Failed to fetch from storage for CID %s: %v", mh.CID, err)
+			continue
+		}
+
+		entries = append(entries, &models.EncryptedHistoryEntry{
+			ID:         doc.Ref.ID,
+			AuthorID:   mh.AuthorID,
+			Ciphertext: ciphertext,
+			WrappedKey: wrapped,
+			CreatedAt:  mh.CreatedAt,
+		})
+	}
+	return entries, nil
+}
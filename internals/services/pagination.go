@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageOrder is a list's sort: a field and a direction, with document IDs
+// breaking ties in the same direction
+type pageOrder struct {
+	Field string
+	Dir   firestore.Direction
+}
+
+// String returns the order in the form clients pass as sort
+func (o pageOrder) String() string {
+	if o.Dir == firestore.Desc {
+		return "-" + o.Field
+	}
+	return o.Field
+}
+
+// parsePageOrder reads a sort option, "field" for ascending or "-field" for
+// descending, allowing only the given fields; an empty option means fallback
+func parsePageOrder(sort, fallback string, fields ...string) (pageOrder, error) {
+	if sort == "" {
+		sort = fallback
+	}
+	order := pageOrder{Field: sort, Dir: firestore.Asc}
+	if field, ok := strings.CutPrefix(sort, "-"); ok {
+		order = pageOrder{Field: field, Dir: firestore.Desc}
+	}
+	if !slices.Contains(fields, order.Field) {
+		return order, errs.Invalid("sort must be one of " + strings.Join(fields, ", ") + ", optionally prefixed with -")
+	}
+	return order, nil
+}
+
+// pageLimit clamps a requested page size to [1, max], using fallback when unset
+func pageLimit(limit, fallback, max int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	return min(limit, max)
+}
+
+// pageCursor is the sort position of the last item on a page. Clients treat it
+// as opaque; it is only valid with the sort it was made for.
+type pageCursor struct {
+	Sort string    `json:"s"`
+	Key  string    `json:"k,omitempty"` // Sort value of lists ordered by a string
+	At   time.Time `json:"t,omitzero"`  // Sort value of lists ordered by a time
+	ID   string    `json:"i"`           // Document ID
+}
+
+func (c *pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageCursor reads a cursor made for sort; an empty string means the first page
+func decodePageCursor(s, sort string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errs.Invalid("Invalid cursor")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == "" {
+		return nil, errs.Invalid("Invalid cursor")
+	}
+	if cursor.Sort != sort {
+		return nil, errs.Invalid("Cursor was made for a different sort")
+	}
+	return &cursor, nil
+}
+
+// timePage returns up to limit documents of query, ordered by order's time field
+// and then document ID, dated within q's range and starting after q's cursor,
+// with the cursor for the next page (empty on the last). Documents keep rejects
+// are skipped without counting, so filling a page may take several reads. The
+// query needs a composite index on its filters followed by the time field.
+func timePage(ctx context.Context, query firestore.Query, order pageOrder, q models.PageQuery, limit int, keep func(*firestore.DocumentSnapshot) bool) ([]*firestore.DocumentSnapshot, string, error) {
+	after, err := decodePageCursor(q.Cursor, order.String())
+	if err != nil {
+		return nil, "", err
+	}
+	if !q.From.IsZero() {
+		query = query.Where(order.Field, ">=", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where(order.Field, "<", q.To)
+	}
+	query = query.OrderBy(order.Field, order.Dir).OrderBy(firestore.DocumentID, order.Dir)
+
+	var kept []*firestore.DocumentSnapshot
+	for {
+		page := query
+		if after != nil {
+			page = page.StartAfter(after.At, after.ID)
+		}
+		docs, err := page.Limit(limit).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, "", err
+		}
+		for i, doc := range docs {
+			at, _ := doc.DataAt(order.Field)
+			t, _ := at.(time.Time)
+			after = &pageCursor{Sort: order.String(), At: t, ID: doc.Ref.ID}
+			if keep != nil && !keep(doc) {
+				continue
+			}
+			kept = append(kept, doc)
+			if len(kept) == limit {
+				if i == len(docs)-1 && len(docs) < limit {
+					return kept, "", nil
+				}
+				return kept, after.encode(), nil
+			}
+		}
+		if len(docs) < limit {
+			return kept, "", nil // Query exhausted
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dataKeys are the keys one patient's server-encrypted records can be opened
+// with. New records are sealed with the patient's own data key. Records brought
+// in by a merge stay sealed with the merged-away patient's key, and records
+// written before patients had their own key stay sealed with the master key.
+type dataKeys struct {
+	seal []byte   // The patient's own key, for new records
+	open [][]byte // Keys to try when opening, the patient's own first
+}
+
+// decrypt opens a record sealed with any of the keys
+func (k dataKeys) decrypt(ciphertext []byte) ([]byte, error) {
+	var err error
+	for _, key := range k.open {
+		var plaintext []byte
+		if plaintext, err = crypto.Decrypt(ciphertext, key); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, err
+}
+
+// decryptStream opens an attachment stream sealed with any of the keys
+func (k dataKeys) decryptStream(src io.Reader) (io.Reader, error) {
+	return crypto.NewDecryptReaderKeys(src, k.open...)
+}
+
+// patientKeys loads a patient's data keys, generating the patient's own key
+// on first use
+func patientKeys(ctx context.Context, fs *firebase.FirestoreClient, patientID string, master []byte) (dataKeys, error) {
+	record, err := getPatientDataKey(ctx, fs, patientID, master)
+	if err != nil {
+		return dataKeys{}, err
+	}
+
+	keys := dataKeys{}
+	for _, wrapped := range append([][]byte{record.WrappedKey}, slices.Collect(maps.Values(record.MergedKeys))...) {
+		key, err := crypto.Decrypt(wrapped, master)
+		if err != nil {
+			log.Printf("Failed to unwrap a data key of patient %s: %v", patientID, err)
+			return dataKeys{}, err
+		}
+		keys.open = append(keys.open, key)
+	}
+	keys.seal = keys.open[0]
+	keys.open = append(keys.open, master)
+	return keys, nil
+}
+
+// getPatientDataKey reads a patient's wrapped data key, creating it if the
+// patient has none yet
+func getPatientDataKey(ctx context.Context, fs *firebase.FirestoreClient, patientID string, master []byte) (*models.PatientDataKey, error) {
+	ref := fs.Client.Collection("data_keys").Doc(patientID)
+	var record models.PatientDataKey
+	doc, err := ref.Get(ctx)
+	if err == nil {
+		if err := doc.DataTo(&record); err != nil {
+			log.Printf("Failed to parse data key of patient %s: %v", patientID, err)
+			return nil, err
+		}
+		if len(record.WrappedKey) > 0 {
+			return &record, nil
+		}
+	} else if status.Code(err) != codes.NotFound {
+		log.Printf("Failed to get data key of patient %s: %v", patientID, err)
+		return nil, err
+	}
+
+	// A merge may already have filed keys under the patient, so the key is
+	// added in a transaction rather than by overwriting the document
+	err = fs.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		record = models.PatientDataKey{}
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if doc.Exists() {
+			if err := doc.DataTo(&record); err != nil {
+				return err
+			}
+			if len(record.WrappedKey) > 0 {
+				return nil // Another request created it first
+			}
+		}
+
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if record.WrappedKey, err = crypto.Encrypt(key, master); err != nil {
+			return err
+		}
+		record.PatientID = patientID
+		record.CreatedAt = time.Now().UTC()
+		return tx.Set(ref, record)
+	})
+	if err != nil {
+		log.Printf("Failed to create data key for patient %s: %v", patientID, err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// adoptMergedKeys files a merged-away patient's data keys under the survivor,
+// so the survivor's keys keep opening the records the merge moves over
+func adoptMergedKeys(ctx context.Context, fs *firebase.FirestoreClient, mergedID, survivorID string) error {
+	doc, err := fs.Client.Collection("data_keys").Doc(mergedID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil // Everything the merged patient has is sealed with the master key
+		}
+		log.Printf("Failed to get data key of patient %s: %v", mergedID, err)
+		return err
+	}
+	var merged models.PatientDataKey
+	if err := doc.DataTo(&merged); err != nil {
+		log.Printf("Failed to parse data key of patient %s: %v", mergedID, err)
+		return err
+	}
+
+	adopted := make(map[string]interface{}, len(merged.MergedKeys)+1)
+	for id, wrapped := range merged.MergedKeys {
+		adopted[id] = wrapped
+	}
+	if len(merged.WrappedKey) > 0 {
+		adopted[mergedID] = merged.WrappedKey
+	}
+	if len(adopted) == 0 {
+		return nil
+	}
+	_, err = fs.Client.Collection("data_keys").Doc(survivorID).Set(ctx, map[string]interface{}{
+		"merged_keys": adopted,
+	}, firestore.MergeAll)
+	if err != nil {
+		log.Printf("Failed to file data keys of patient %s under %s: %v", mergedID, survivorID, err)
+		return err
+	}
+	return nil
+}
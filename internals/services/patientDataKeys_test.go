@@ -0,0 +1,64 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+)
+
+func TestPatientKeys(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	master, _ := crypto.GenerateKey()
+	patientID, mergedID := testID(t)+"-patient", testID(t)+"-merged"
+	for _, id := range []string{patientID, mergedID} {
+		t.Cleanup(func() { fs.Client.Collection("data_keys").Doc(id).Delete(context.Background()) })
+	}
+
+	// Step 1: The patient's key is made once and differs from the master key
+	keys, err := patientKeys(ctx, fs, patientID, master)
+	if err != nil {
+		t.Fatalf("patientKeys: %v", err)
+	}
+	if bytes.Equal(keys.seal, master) {
+		t.Fatal("patient key is the master key")
+	}
+	again, err := patientKeys(ctx, fs, patientID, master)
+	if err != nil {
+		t.Fatalf("patientKeys again: %v", err)
+	}
+	if !bytes.Equal(again.seal, keys.seal) {
+		t.Error("patientKeys made a second key")
+	}
+
+	// Step 2: Records sealed before the patient had a key still open
+	legacy, _ := crypto.Encrypt([]byte("legacy"), master)
+	if got, err := keys.decrypt(legacy); err != nil || string(got) != "legacy" {
+		t.Errorf("decrypt legacy record = %q, %v", got, err)
+	}
+
+	// Step 3: After a merge, the survivor opens the merged patient's records
+	mergedKeys, err := patientKeys(ctx, fs, mergedID, master)
+	if err != nil {
+		t.Fatalf("patientKeys for merged patient: %v", err)
+	}
+	moved, _ := crypto.Encrypt([]byte("moved"), mergedKeys.seal)
+	if _, err := keys.decrypt(moved); err == nil {
+		t.Fatal("survivor opened a record before the merge")
+	}
+	if err := adoptMergedKeys(ctx, fs, mergedID, patientID); err != nil {
+		t.Fatalf("adoptMergedKeys: %v", err)
+	}
+	keys, err = patientKeys(ctx, fs, patientID, master)
+	if err != nil {
+		t.Fatalf("patientKeys after merge: %v", err)
+	}
+	if !bytes.Equal(keys.seal, again.seal) {
+		t.Error("merge replaced the survivor's own key")
+	}
+	if got, err := keys.decrypt(moved); err != nil || string(got) != "moved" {
+		t.Errorf("decrypt moved record = %q, %v", got, err)
+	}
+}
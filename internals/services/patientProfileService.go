@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/validate"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// allergySeverities are the allergy severities, mildest first
+var allergySeverities = []string{"mild", "moderate", "severe"}
+
+// sealedProfile is the encrypted part of a stored PatientProfile
+type sealedProfile struct {
+	BloodType         string                    `json:"blood_type,omitempty"`
+	Allergies         []models.Allergy          `json:"allergies,omitempty"`
+	Conditions        []string                  `json:"conditions,omitempty"`
+	EmergencyContacts []models.EmergencyContact `json:"emergency_contacts,omitempty"`
+	Insurance         *models.Insurance         `json:"insurance,omitempty"`
+}
+
+// PatientProfileService stores patients' demographic and clinical profiles
+type PatientProfileService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewPatientProfileService creates a new PatientProfileService instance
+func NewPatientProfileService(firestore *firebase.FirestoreClient) *PatientProfileService {
+	return &PatientProfileService{
+		Firestore: firestore,
+	}
+}
+
+// GetProfile returns a patient's profile with its sensitive fields decrypted
+func (ps *PatientProfileService) GetProfile(ctx context.Context, patientID string, key []byte) (*models.PatientProfile, error) {
+	profile, err := ps.FindProfile(ctx, patientID, key)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errs.NotFound("Patient profile not found: " + patientID)
+	}
+	return profile, nil
+}
+
+// FindProfile is GetProfile for callers that treat a missing profile as
+// normal; it returns nil if the patient has none
+func (ps *PatientProfileService) FindProfile(ctx context.Context, patientID string, key []byte) (*models.PatientProfile, error) {
+	keys, err := patientKeys(ctx, ps.Firestore, patientID, key)
+	if err != nil {
+		return nil, err
+	}
+	return ps.findProfile(ctx, patientID, keys)
+}
+
+// findProfile is FindProfile with the patient's data keys already loaded
+func (ps *PatientProfileService) findProfile(ctx context.Context, patientID string, keys dataKeys) (*models.PatientProfile, error) {
+	doc, err := ps.Firestore.Client.Collection("patient_profiles").Doc(patientID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		log.Printf("Failed to get patient profile %s: %v", patientID, err)
+		return nil, err
+	}
+
+	var profile models.PatientProfile
+	if err := doc.DataTo(&profile); err != nil {
+		log.Printf("Failed to parse patient profile: %v", err)
+		return nil, err
+	}
+	if err := unsealProfile(&profile, keys); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveProfile validates and creates or replaces a patient's profile on behalf
+// of the patient or one of their doctors
+func (ps *PatientProfileService) SaveProfile(ctx context.Context, actorID, patientID string, profile *models.PatientProfile, key []byte) (*models.PatientProfile, error) {
+	// Step 1: Validate and tidy the submitted fields
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+	profile.PatientID = patientID
+	profile.UpdatedAt = time.Now().UTC()
+	profile.UpdatedBy = actorID
+
+	// Step 2: Encrypt the sensitive fields with the patient's own key
+	keys, err := patientKeys(ctx, ps.Firestore, patientID, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := sealProfile(profile, keys.seal); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Store the profile
+	if _, err := ps.Firestore.Client.Collection("patient_profiles").Doc(patientID).Set(ctx, profile); err != nil {
+		log.Printf("Failed to save patient profile %s: %v", patientID, err)
+		return nil, err
+	}
+	invalidateEmergencySummary(ctx, ps.Firestore, patientID)
+	return profile, nil
+}
+
+// DeleteProfile erases a patient's profile
+func (ps *PatientProfileService) DeleteProfile(ctx context.Context, patientID string) error {
+	ref := ps.Firestore.Client.Collection("patient_profiles").Doc(patientID)
+	if _, err := ref.Get(ctx); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errs.NotFound("Patient profile not found: " + patientID)
+		}
+		log.Printf("Failed to get patient profile %s: %v", patientID, err)
+		return err
+	}
+	if _, err := ref.Delete(ctx); err != nil {
+		log.Printf("Failed to delete patient profile %s: %v", patientID, err)
+		return err
+	}
+	invalidateEmergencySummary(ctx, ps.Firestore, patientID)
+	return nil
+}
+
+// validateProfile tidies a submitted profile and checks it against its
+// validate tags, then replaces missing lists with empty ones
+func validateProfile(profile *models.PatientProfile) error {
+	if err := validate.Struct(profile); err != nil {
+		return err
+	}
+	if profile.DateOfBirth != "" {
+		dob, _ := time.Parse(time.DateOnly, profile.DateOfBirth)
+		if dob.After(time.Now().UTC()) || dob.Year() < 1900 {
+			return errs.InvalidFields(errs.FieldError{Field: "date_of_birth", Message: "is out of range"})
+		}
+	}
+	fillProfileLists(profile)
+	return nil
+}
+
+// sealProfile encrypts the sensitive fields of a profile into Sealed
+func sealProfile(profile *models.PatientProfile, key []byte) error {
+	plaintext, err := json.Marshal(sealedProfile{
+		BloodType:         profile.BloodType,
+		Allergies:         profile.Allergies,
+		Conditions:        profile.Conditions,
+		EmergencyContacts: profile.EmergencyContacts,
+		Insurance:         profile.Insurance,
+	})
+	if err != nil {
+		log.Printf("Failed to encode patient profile: %v", err)
+		return err
+	}
+	sealed, err := crypto.Encrypt(plaintext, key)
+	if err != nil {
+		return err
+	}
+	profile.Sealed = sealed
+	return nil
+}
+
+// unsealProfile decrypts Sealed back into a profile's sensitive fields
+func unsealProfile(profile *models.PatientProfile, keys dataKeys) error {
+	var fields sealedProfile
+	if len(profile.Sealed) > 0 {
+		plaintext, err := keys.decrypt(profile.Sealed)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(plaintext, &fields); err != nil {
+			log.Printf("Failed to decode patient profile: %v", err)
+			return err
+		}
+	}
+	profile.BloodType = fields.BloodType
+	profile.Allergies = fields.Allergies
+	profile.Conditions = fields.Conditions
+	profile.EmergencyContacts = fields.EmergencyContacts
+	profile.Insurance = fields.Insurance
+	fillProfileLists(profile)
+	profile.Sealed = nil
+	return nil
+}
+
+// fillProfileLists replaces missing lists with empty ones so they encode as []
+func fillProfileLists(profile *models.PatientProfile) {
+	if profile.Allergies == nil {
+		profile.Allergies = []models.Allergy{}
+	}
+	if profile.Conditions == nil {
+		profile.Conditions = []string{}
+	}
+	if profile.EmergencyContacts == nil {
+		profile.EmergencyContacts = []models.EmergencyContact{}
+	}
+}
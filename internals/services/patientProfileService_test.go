@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+)
+
+func testProfile() *models.PatientProfile {
+	return &models.PatientProfile{
+		DateOfBirth: "1980-02-29",
+		Sex:         "female",
+		BloodType:   "o-",
+		Allergies:   []models.Allergy{{Substance: " Penicillin ", Reaction: "Hives", Severity: "severe"}},
+		Conditions:  []string{"Type 1 diabetes"},
+		EmergencyContacts: []models.EmergencyContact{
+			{Name: "Sam Lee", Relationship: "Spouse", Phone: "+1 (555) 010-2030"},
+		},
+		Insurance: &models.Insurance{Provider: "Acme Health", PolicyNumber: "P-123"},
+	}
+}
+
+func TestProfileSealRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sealing key need not be the first one tried, as for legacy profiles
+	keys := dataKeys{seal: other, open: [][]byte{other, key}}
+	tests := []struct {
+		name    string
+		profile *models.PatientProfile
+	}{
+		{"full", testProfile()},
+		{"demographics only", &models.PatientProfile{DateOfBirth: "2001-07-04", Sex: "male"}},
+		{"empty", &models.PatientProfile{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateProfile(tt.profile); err != nil {
+				t.Fatalf("validateProfile: %v", err)
+			}
+			want := *tt.profile
+			if err := sealProfile(tt.profile, key); err != nil {
+				t.Fatalf("sealProfile: %v", err)
+			}
+
+			// Only the sealed copy of the sensitive fields is stored
+			stored := models.PatientProfile{DateOfBirth: tt.profile.DateOfBirth, Sex: tt.profile.Sex, Sealed: tt.profile.Sealed}
+			if err := unsealProfile(&stored, keys); err != nil {
+				t.Fatalf("unsealProfile: %v", err)
+			}
+			if !reflect.DeepEqual(stored, want) {
+				t.Errorf("unsealed profile = %+v, want %+v", stored, want)
+			}
+		})
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(*models.PatientProfile)
+		field string
+	}{
+		{"future birth", func(p *models.PatientProfile) { p.DateOfBirth = "2999-01-01" }, "date_of_birth"},
+		{"bad birth date", func(p *models.PatientProfile) { p.DateOfBirth = "1980-02-30" }, "date_of_birth"},
+		{"unknown sex", func(p *models.PatientProfile) { p.Sex = "f" }, "sex"},
+		{"unknown blood type", func(p *models.PatientProfile) { p.BloodType = "C+" }, "blood_type"},
+		{"blank substance", func(p *models.PatientProfile) { p.Allergies[0].Substance = "  " }, "allergies.0.substance"},
+		{"unknown severity", func(p *models.PatientProfile) { p.Allergies[0].Severity = "fatal" }, "allergies.0.severity"},
+		{"blank condition", func(p *models.PatientProfile) { p.Conditions = append(p.Conditions, " ") }, "conditions.1"},
+		{"long condition", func(p *models.PatientProfile) { p.Conditions[0] = strings.Repeat("x", 201) }, "conditions.0"},
+		{"short phone", func(p *models.PatientProfile) { p.EmergencyContacts[0].Phone = "555-01" }, "emergency_contacts.0.phone"},
+		{"too many contacts", func(p *models.PatientProfile) { p.EmergencyContacts = slices.Repeat(p.EmergencyContacts, 6) }, "emergency_contacts"},
+		{"no policy number", func(p *models.PatientProfile) { p.Insurance.PolicyNumber = "" }, "insurance.policy_number"},
+		{"bad expiry", func(p *models.PatientProfile) { p.Insurance.ExpiresOn = "soon" }, "insurance.expires_on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := testProfile()
+			tt.edit(profile)
+			err := validateProfile(profile)
+			var e *errs.Error
+			if !errors.As(err, &e) || len(e.Fields) == 0 || e.Fields[0].Field != tt.field {
+				t.Errorf("validateProfile = %v, want an error for %s", err, tt.field)
+			}
+		})
+	}
+
+	// Free text is tidied before it is checked
+	profile := testProfile()
+	if err := validateProfile(profile); err != nil {
+		t.Fatalf("validateProfile: %v", err)
+	}
+	if profile.BloodType != "O-" || profile.Allergies[0].Substance != "Penicillin" {
+		t.Errorf("validateProfile left %q and %q untidied", profile.BloodType, profile.Allergies[0].Substance)
+	}
+}
+
+func TestProfileSealRejectsWrongKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	profile := testProfile()
+	if err := sealProfile(profile, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := unsealProfile(profile, dataKeys{open: [][]byte{other}}); err == nil {
+		t.Error("unsealProfile with another key succeeded")
+	}
+	if err := sealProfile(testProfile(), []byte("32-byte-key-here-1234567890123456")); err == nil {
+		t.Error("sealProfile accepted a 33-byte key")
+	}
+}
+
+func TestSaveAndGetProfile(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	service := NewPatientProfileService(fs)
+	patientID := testID(t)
+	t.Cleanup(func() { fs.Client.Collection("data_keys").Doc(patientID).Delete(context.Background()) })
+
+	saved, err := service.SaveProfile(ctx, "doctor-1", patientID, testProfile(), key)
+	if err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	got, err := service.GetProfile(ctx, patientID, key)
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	if got.BloodType != "O-" || got.Allergies[0].Substance != "Penicillin" || got.Insurance.PolicyNumber != "P-123" {
+		t.Errorf("GetProfile = %+v, want the saved profile", got)
+	}
+	if got.UpdatedBy != "doctor-1" || !got.UpdatedAt.Equal(saved.UpdatedAt) {
+		t.Errorf("GetProfile updated by %s at %s, want doctor-1 at %s", got.UpdatedBy, got.UpdatedAt, saved.UpdatedAt)
+	}
+
+	// The profile is sealed with the patient's own key, not the master key
+	if _, err := crypto.Decrypt(saved.Sealed, key); err == nil {
+		t.Error("profile was sealed with the master key")
+	}
+}
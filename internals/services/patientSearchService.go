@@ -0,0 +1,403 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/search"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	searchSort             = "name" // The only order search results come in
+	defaultMatchScore      = 0.6
+	matchDateOfBirthWeight = 0.3              // Share of a match score carried by the date of birth, when given
+	matchPhoneWeight       = 0.2              // Share carried by the phone number, when given
+	matchCandidateLimit    = 200              // Candidates fetched per index query before scoring
+	maxPhoneticKeys        = 30               // Firestore's limit on array-contains-any values
+	indexerRestartBackoff  = 10 * time.Second // Wait before re-listening after the user listener fails
+)
+
+// PatientSearchService maintains and queries the patient_search index: one
+// document per patient holding their normalized name, its word prefixes, date of
+// birth and NFC ID. Queries are served by Firestore indexes instead of scanning
+// the users collection.
+type PatientSearchService struct {
+	Firestore *firebase.FirestoreClient
+}
+
+// NewPatientSearchService creates a new PatientSearchService instance
+func NewPatientSearchService(firestore *firebase.FirestoreClient) *PatientSearchService {
+	return &PatientSearchService{
+		Firestore: firestore,
+	}
+}
+
+// IndexPatient writes or replaces a patient's index entry
+func (ss *PatientSearchService) IndexPatient(ctx context.Context, patient *models.User) error {
+	entry := newPatientSearchEntry(patient)
+	if _, err := ss.Firestore.Client.Collection("patient_search").Doc(entry.PatientID).Set(ctx, entry); err != nil {
+		log.Printf("Failed to index patient %s: %v", patient.UID, err)
+		return err
+	}
+	return nil
+}
+
+// RunIndexer keeps the index in step with patient user documents, whoever
+// writes them, until ctx is cancelled. The listener's first snapshot holds every
+// patient, so starting it also rebuilds the index; later snapshots carry only
+// the changes.
+func (ss *PatientSearchService) RunIndexer(ctx context.Context) {
+	for {
+		err := ss.syncIndex(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Patient search indexer stopped, restarting: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(indexerRestartBackoff):
+		}
+	}
+}
+
+// syncIndex applies user changes to the index until the listener fails
+func (ss *PatientSearchService) syncIndex(ctx context.Context) error {
+	snapshots := ss.Firestore.Client.Collection("users").Where("role", "==", "patient").Snapshots(ctx)
+	defer snapshots.Stop()
+
+	index := ss.Firestore.Client.Collection("patient_search")
+	for {
+		snapshot, err := snapshots.Next()
+		if err != nil {
+			return err
+		}
+
+		batch := ss.Firestore.Client.Batch()
+		pending := 0
+		for _, change := range snapshot.Changes {
+			// A removal also covers a user whose role is no longer patient
+			if change.Kind == firestore.DocumentRemoved {
+				batch.Delete(index.Doc(change.Doc.Ref.ID))
+			} else {
+				var patient models.User
+				if err := change.Doc.DataTo(&patient); err != nil {
+					log.Printf("Failed to parse user %s for indexing: %v", change.Doc.Ref.ID, err)
+					continue
+				}
+				patient.UID = change.Doc.Ref.ID
+				batch.Set(index.Doc(patient.UID), newPatientSearchEntry(&patient))
+			}
+			pending++
+			if pending == writeBatchSize {
+				if _, err := batch.Commit(ctx); err != nil {
+					log.Printf("Failed to update patient search index: %v", err)
+					return err
+				}
+				batch = ss.Firestore.Client.Batch()
+				pending = 0
+			}
+		}
+		if pending > 0 {
+			if _, err := batch.Commit(ctx); err != nil {
+				log.Printf("Failed to update patient search index: %v", err)
+				return err
+			}
+		}
+	}
+}
+
+// Search returns a page of index entries matching q, ordered by name, and the
+// cursor for the next page. Restricted searches (q.PatientIDs set) are matched
+// against just those patients' entries, so their cost follows the size of the
+// restriction rather than of the index.
+func (ss *PatientSearchService) Search(ctx context.Context, q models.PatientSearchQuery) ([]*models.PatientSearchEntry, string, error) {
+	limit := pageLimit(q.Limit, defaultPageLimit, maxPageLimit)
+	after, err := decodePageCursor(q.Cursor, searchSort)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if q.PatientIDs != nil {
+		return ss.searchWithin(ctx, q, after, limit)
+	}
+	return ss.searchIndex(ctx, q, after, limit)
+}
+
+// searchIndex pages through the index with Firestore doing the filtering; only
+// extra name words are checked here. Needs composite indexes on prefixes
+// (array-contains) or the equality filters, followed by name_key.
+func (ss *PatientSearchService) searchIndex(ctx context.Context, q models.PatientSearchQuery, after *pageCursor, limit int) ([]*models.PatientSearchEntry, string, error) {
+	tokens := search.Tokens(q.Name)
+	query := ss.Firestore.Client.Collection("patient_search").Query
+	if len(tokens) > 0 {
+		// The longest word is the most selective
+		longest := slices.MaxFunc(tokens, func(a, b string) int { return len([]rune(a)) - len([]rune(b)) })
+		query = query.Where("prefixes", "array-contains", search.IndexPrefix(longest))
+	}
+	if q.DateOfBirth != "" {
+		query = query.Where("date_of_birth", "==", q.DateOfBirth)
+	}
+	if q.NFCID != "" {
+		query = query.Where("nfc_id", "==", q.NFCID)
+	}
+	query = query.OrderBy("name_key", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc)
+
+	var entries []*models.PatientSearchEntry
+	for len(entries) < limit {
+		page := query
+		if after != nil {
+			page = page.StartAfter(after.Key, after.ID)
+		}
+		docs, err := page.Limit(limit).Documents(ctx).GetAll()
+		if err != nil {
+			log.Printf("Failed to search patients: %v", err)
+			return nil, "", err
+		}
+		for _, doc := range docs {
+			var entry models.PatientSearchEntry
+			if err := doc.DataTo(&entry); err != nil {
+				log.Printf("Failed to parse search entry %s: %v", doc.Ref.ID, err)
+				continue
+			}
+			after = &pageCursor{Sort: searchSort, Key: entry.NameKey, ID: doc.Ref.ID}
+			if search.MatchesPrefixes(entry.Tokens, tokens) {
+				entries = append(entries, &entry)
+				if len(entries) == limit {
+					break
+				}
+			}
+		}
+		if len(docs) < limit {
+			return entries, "", nil // Index exhausted
+		}
+	}
+	return entries, after.encode(), nil
+}
+
+// searchWithin matches q against the entries of the listed patients only
+func (ss *PatientSearchService) searchWithin(ctx context.Context, q models.PatientSearchQuery, after *pageCursor, limit int) ([]*models.PatientSearchEntry, string, error) {
+	entries, err := ss.loadEntries(ctx, q.PatientIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokens := search.Tokens(q.Name)
+	var matches []*models.PatientSearchEntry
+	for _, entry := range entries {
+		if !search.MatchesPrefixes(entry.Tokens, tokens) ||
+			(q.DateOfBirth != "" && entry.DateOfBirth != q.DateOfBirth) ||
+			(q.NFCID != "" && entry.NFCID != q.NFCID) {
+			continue
+		}
+		if after != nil && !searchFollows(entry, after) {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+
+	slices.SortFunc(matches, func(a, b *models.PatientSearchEntry) int {
+		if c := strings.Compare(a.NameKey, b.NameKey); c != 0 {
+			return c
+		}
+		return strings.Compare(a.PatientID, b.PatientID)
+	})
+	if len(matches) <= limit {
+		return matches, "", nil
+	}
+	last := matches[limit-1]
+	return matches[:limit], (&pageCursor{Sort: searchSort, Key: last.NameKey, ID: last.PatientID}).encode(), nil
+}
+
+// MatchPatients ranks patients by how closely their names are spelled or sound
+// like q.Name, allowing for typos, transliteration and phonetic variants, with a
+// boost for a matching date of birth or phone number. Candidates are drawn from
+// the index by phonetic code, date of birth and phone, then scored here.
+func (ss *PatientSearchService) MatchPatients(ctx context.Context, q models.PatientMatchQuery) ([]*models.PatientMatch, error) {
+	if len(search.Tokens(q.Name)) == 0 {
+		return nil, errs.Invalid("Name is required")
+	}
+	limit := pageLimit(q.Limit, defaultPageLimit, maxPageLimit)
+	minScore := q.MinScore
+	if minScore <= 0 {
+		minScore = defaultMatchScore
+	}
+
+	// Step 1: Gather candidates
+	var candidates []*models.PatientSearchEntry
+	var err error
+	if q.PatientIDs != nil {
+		candidates, err = ss.loadEntries(ctx, q.PatientIDs)
+	} else {
+		candidates, err = ss.matchCandidates(ctx, q)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Score and rank them
+	matches := []*models.PatientMatch{}
+	names := make(map[string]string)
+	for _, entry := range candidates {
+		score, reasons := scoreMatch(q, entry)
+		if score < minScore {
+			continue
+		}
+		names[entry.PatientID] = entry.NameKey
+		matches = append(matches, &models.PatientMatch{
+			PatientID: entry.PatientID,
+			Score:     math.Round(score*1000) / 1000,
+			Reasons:   reasons,
+		})
+	}
+	slices.SortFunc(matches, func(a, b *models.PatientMatch) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		if c := strings.Compare(names[a.PatientID], names[b.PatientID]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.PatientID, b.PatientID)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// scoreMatch scores an index entry against q. The name's share of the score
+// shrinks by the weight of each other field given; a field the patient has not
+// recorded earns half its weight.
+func scoreMatch(q models.PatientMatchQuery, entry *models.PatientSearchEntry) (float64, []string) {
+	var nameScore float64
+	var reasons []string
+	if q.FullName {
+		nameScore, reasons = search.NameSimilarity(q.Name, entry.Name)
+	} else {
+		nameScore, reasons = search.QueryScore(q.Name, entry.Name)
+	}
+
+	score, nameWeight := 0.0, 1.0
+	field := func(want, have string, weight float64, reason string) {
+		if want == "" {
+			return
+		}
+		nameWeight -= weight
+		switch have {
+		case want:
+			score += weight
+			reasons = append(reasons, reason)
+		case "":
+			score += weight / 2 // Unknown, neither for nor against
+		}
+	}
+	field(q.DateOfBirth, entry.DateOfBirth, matchDateOfBirthWeight, "date_of_birth")
+	field(search.NormalizePhone(q.Phone), entry.Phone, matchPhoneWeight, "phone")
+	return score + nameWeight*nameScore, reasons
+}
+
+// matchCandidates queries the index for entries sharing a phonetic code with the
+// name or, when given, the date of birth or phone number. Needs single-field
+// indexes on phonetic, date_of_birth and phone.
+func (ss *PatientSearchService) matchCandidates(ctx context.Context, q models.PatientMatchQuery) ([]*models.PatientSearchEntry, error) {
+	index := ss.Firestore.Client.Collection("patient_search")
+	queries := []firestore.Query{}
+	if keys := search.PhoneticKeys(q.Name); len(keys) > 0 {
+		if len(keys) > maxPhoneticKeys {
+			keys = keys[:maxPhoneticKeys]
+		}
+		queries = append(queries, index.Where("phonetic", "array-contains-any", keys).Limit(matchCandidateLimit))
+	}
+	if q.DateOfBirth != "" {
+		queries = append(queries, index.Where("date_of_birth", "==", q.DateOfBirth).Limit(matchCandidateLimit))
+	}
+	if phone := search.NormalizePhone(q.Phone); phone != "" {
+		queries = append(queries, index.Where("phone", "==", phone).Limit(matchCandidateLimit))
+	}
+
+	seen := make(map[string]bool)
+	var candidates []*models.PatientSearchEntry
+	for _, query := range queries {
+		docs, err := query.Documents(ctx).GetAll()
+		if err != nil {
+			log.Printf("Failed to query match candidates: %v", err)
+			return nil, err
+		}
+		for _, entry := range parseSearchEntries(docs) {
+			if !seen[entry.PatientID] {
+				seen[entry.PatientID] = true
+				candidates = append(candidates, entry)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// loadEntries returns the index entries of the listed patients that exist
+func (ss *PatientSearchService) loadEntries(ctx context.Context, patientIDs []string) ([]*models.PatientSearchEntry, error) {
+	if len(patientIDs) == 0 {
+		return []*models.PatientSearchEntry{}, nil
+	}
+	refs := make([]*firestore.DocumentRef, len(patientIDs))
+	for i, id := range patientIDs {
+		refs[i] = ss.Firestore.Client.Collection("patient_search").Doc(id)
+	}
+	docs, err := ss.Firestore.Client.GetAll(ctx, refs)
+	if err != nil {
+		log.Printf("Failed to load search entries: %v", err)
+		return nil, err
+	}
+	return parseSearchEntries(docs), nil
+}
+
+// parseSearchEntries decodes index documents, skipping missing or malformed ones
+func parseSearchEntries(docs []*firestore.DocumentSnapshot) []*models.PatientSearchEntry {
+	entries := make([]*models.PatientSearchEntry, 0, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var entry models.PatientSearchEntry
+		if err := doc.DataTo(&entry); err != nil {
+			log.Printf("Failed to parse search entry %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		entry.PatientID = doc.Ref.ID
+		entries = append(entries, &entry)
+	}
+	return entries
+}
+
+// newPatientSearchEntry derives a patient's index entry from their user document
+func newPatientSearchEntry(patient *models.User) *models.PatientSearchEntry {
+	return &models.PatientSearchEntry{
+		PatientID:   patient.UID,
+		Name:        patient.Name,
+		NameKey:     search.Normalize(patient.Name),
+		Tokens:      search.Tokens(patient.Name),
+		Prefixes:    search.Prefixes(patient.Name),
+		Phonetic:    search.PhoneticKeys(patient.Name),
+		DateOfBirth: patient.DateOfBirth,
+		NFCID:       patient.NFCID,
+		Phone:       search.NormalizePhone(patient.Phone),
+		UpdatedAt:   time.Now().UTC(),
+	}
+}
+
+// searchFollows reports whether entry sorts after the cursor
+func searchFollows(entry *models.PatientSearchEntry, after *pageCursor) bool {
+	if entry.NameKey != after.Key {
+		return entry.NameKey > after.Key
+	}
+	return entry.PatientID > after.ID
+}
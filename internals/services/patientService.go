@@ -2,32 +2,40 @@ package services
 
 import (
 	"context"
+	"io"
 	"log"
+	"time"
 
 	//"strings"
 
 	"github.com/Frhnmj2004/hippocard-server/internals/models"
 	"github.com/Frhnmj2004/hippocard-server/pkg/crypto"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
 	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
 	//"cloud.google.com/go/firestore"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type PatientService struct {
 	Firestore *firebase.FirestoreClient
-	IPFS      *storage.IPFSClient
+	Storage   storage.BlobStore
+	Pins      *PinService
+	Keys      *KeyService
 }
 
-func NewPatientService(firestore *firebase.FirestoreClient, ipfs *storage.IPFSClient) *PatientService {
+func NewPatientService(firestore *firebase.FirestoreClient, store storage.BlobStore) *PatientService {
 	return &PatientService{
 		Firestore: firestore,
-		IPFS:      ipfs,
+		Storage:   store,
+		Pins:      NewPinService(firestore, store),
+		Keys:      NewKeyService(firestore),
 	}
 }
 
-func (ps *PatientService) GetProfile(userID string) (*models.User, error) {
-	ctx := context.Background()
-
+func (ps *PatientService) GetProfile(ctx context.Context, userID string) (*models.User, error) {
 	// Fetch user document from Firestore by UID
 	doc, err := ps.Firestore.Client.Collection("users").Doc(userID).Get(ctx)
 	if err != nil {
@@ -43,59 +51,198 @@ func (ps *PatientService) GetProfile(userID string) (*models.User, error) {
 	user.UID = doc.Ref.ID
 
 	if user.Role != "patient" {
-		return nil, logError("User is not a patient: " + userID)
+		return nil, errs.Forbidden("User is not a patient: " + userID)
 	}
 
 	return &user, nil
 }
 
-func (ps *PatientService) GetMedicalHistory(userID string, key []byte) ([]*models.MedicalHistoryEntry, error) {
-	ctx := context.Background()
+// GetMedicalHistory returns a page of the patient's server-encrypted history
+func (ps *PatientService) GetMedicalHistory(ctx context.Context, userID string, q models.PageQuery, key []byte) (*models.Page[*models.MedicalHistoryEntry], error) {
+	keys, err := patientKeys(ctx, ps.Firestore, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	return pageMedicalHistory(ctx, ps.Firestore, ps.Storage, userID, q, keys)
+}
 
-	// Query Firestore for medical history entries
-	docs, err := ps.Firestore.Client.Collection("medical_history").
-		Where("user_id", "==", userID).
-		Documents(ctx).GetAll()
+// GetMedicalHistoryVersions returns every version of one of the patient's
+// server-encrypted history entries, including amended and retracted ones
+func (ps *PatientService) GetMedicalHistoryVersions(ctx context.Context, userID, entryID string, key []byte) (*models.MedicalHistoryVersions, error) {
+	record, err := getHistoryRecord(ctx, ps.Firestore, entryID)
 	if err != nil {
-		log.Printf("Failed to query medical history: %v", err)
 		return nil, err
 	}
+	if record.UserID != userID {
+		return nil, errs.Forbidden("Record does not belong to patient: " + userID)
+	}
+	if record.E2E {
+		return nil, errs.Invalid("End-to-end entries have no server-readable versions: " + entryID)
+	}
+	keys, err := patientKeys(ctx, ps.Firestore, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	return loadHistoryVersions(ctx, ps.Storage, record, keys), nil
+}
 
-	var history []*models.MedicalHistoryEntry
-	for _, doc := range docs {
-		var mh models.MedicalHistory
-		if err := doc.DataTo(&mh); err != nil {
-			log.Printf("Failed to parse medical history: %v", err)
-			continue
-		}
-		mh.ID = doc.Ref.ID
+// GetEncryptedMedicalHistory returns the patient's end-to-end entries for
+// decryption on their own device
+func (ps *PatientService) GetEncryptedMedicalHistory(ctx context.Context, userID string) ([]*models.EncryptedHistoryEntry, error) {
+	return fetchEncryptedHistory(ctx, ps.Firestore, ps.Storage, userID, userID)
+}
 
-		// Fetch encrypted data from IPFS
-		encryptedData, err := ps.IPFS.GetData(mh.CID)
-		if err != nil {
-			log.Printf("Failed to fetch from IPFS for CID %s: %v", mh.CID, err)
-			continue
-		}
+// EraseRecord deletes one of the patient's records ("medical_history" or
+// "attachment") and unpins its blob. If unpinning fails the blob is left for pin
+// verification to report as an orphan.
+func (ps *PatientService) EraseRecord(ctx context.Context, userID, recordType, recordID string) error {
+	collection, ok := recordCollections[recordType]
+	if !ok {
+		return errs.Invalid("Unknown record type: " + recordType)
+	}
 
-		// Decrypt the data
-		decryptedData, err := crypto.Decrypt(encryptedData, key)
-		if err != nil {
-			log.Printf("Failed to decrypt history for CID %s: %v", mh.CID, err)
-			continue
-		}
+	ref := ps.Firestore.Client.Collection(collection).Doc(recordID)
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		log.Printf("Failed to get %s %s: %v", recordType, recordID, err)
+		return err
+	}
+	owner, _ := doc.DataAt("user_id")
+	if owner != userID {
+		return errs.Forbidden("Record does not belong to patient: " + userID)
+	}
 
-		// Add to results
-		history = append(history, &models.MedicalHistoryEntry{
-			History:   string(decryptedData),
-			CreatedAt: mh.CreatedAt,
-		})
+	if _, err := ref.Delete(ctx); err != nil {
+		log.Printf("Failed to erase %s %s: %v", recordType, recordID, err)
+		return err
 	}
 
-	return history, nil
+	// Every version of an amended entry goes with it
+	for _, cid := range recordCIDs(doc) {
+		if err := ps.Pins.UnpinRecord(ctx, cid); err != nil {
+			log.Printf("Erased %s %s but failed to unpin %s: %v", recordType, recordID, cid, err)
+		}
+	}
+	return nil
 }
 
-func (ps *PatientService) GetPrescriptions(userID string) ([]*models.Prescription, error) {
+func (ps *PatientService) GetPrescriptions(ctx context.Context, userID string) ([]*models.Prescription, error) {
 	// TODO: Implement with blockchain NFT data
 	log.Println("GetPrescriptions not implemented yet—waiting for blockchain")
 	return nil, nil
 }
+
+// GetAttachment returns an attachment's metadata and a decrypting stream over its
+// content. The caller must close the stream.
+func (ps *PatientService) GetAttachment(ctx context.Context, userID, attachmentID string, key []byte) (*models.Attachment, io.ReadCloser, error) {
+	doc, err := ps.Firestore.Client.Collection("attachments").Doc(attachmentID).Get(ctx)
+	if err != nil {
+		log.Printf("Failed to get attachment %s: %v", attachmentID, err)
+		return nil, nil, err
+	}
+
+	var attachment models.Attachment
+	if err := doc.DataTo(&attachment); err != nil {
+		log.Printf("Failed to parse attachment data: %v", err)
+		return nil, nil, err
+	}
+	attachment.ID = doc.Ref.ID
+
+	if attachment.UserID != userID {
+		return nil, nil, errs.Forbidden("Attachment does not belong to patient: " + userID)
+	}
+
+	keys, err := patientKeys(ctx, ps.Firestore, userID, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	encrypted, err := ps.Storage.GetReader(ctx, attachment.CID)
+	if err != nil {
+		log.Printf("Failed to fetch attachment from storage for CID %s: %v", attachment.CID, err)
+		return nil, nil, err
+	}
+
+	plaintext, err := keys.decryptStream(encrypted)
+	if err != nil {
+		encrypted.Close()
+		log.Printf("Failed to set up attachment decryption for CID %s: %v", attachment.CID, err)
+		return nil, nil, err
+	}
+
+	return &attachment, struct {
+		io.Reader
+		io.Closer
+	}{plaintext, encrypted}, nil
+}
+
+// EscrowKey splits the patient's own data key among custodians so that a quorum
+// of them can restore emergency access. Each share is wrapped to its custodian's
+// public key and kept for that custodian alone to collect.
+func (ps *PatientService) EscrowKey(ctx context.Context, patientID string, key []byte, custodianIDs []string, threshold int) (*models.KeyEscrow, error) {
+	// Step 1: Make sure every custodian is a hospital, since only hospitals can
+	// approve break-glass requests, and can receive a wrapped share
+	seen := make(map[string]bool, len(custodianIDs))
+	publicKeys := make(map[string][]byte, len(custodianIDs))
+	for _, id := range custodianIDs {
+		if seen[id] {
+			return nil, errs.Invalid("Duplicate custodian: " + id)
+		}
+		seen[id] = true
+
+		custodian, err := firebase.GetUserByUID(ctx, ps.Firestore.Client, id)
+		if err != nil {
+			return nil, err
+		}
+		if custodian.Role != "hospital" {
+			return nil, errs.Invalid("Custodian must be a hospital: " + id)
+		}
+		publicKey, err := ps.Keys.GetPublicKey(ctx, id)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return nil, errs.Invalid("Custodian has not published a public key: " + id)
+			}
+			return nil, err
+		}
+		publicKeys[id] = publicKey.Key
+	}
+
+	// Step 2: Split the patient's key, never the master key
+	keys, err := patientKeys(ctx, ps.Firestore, patientID, key)
+	if err != nil {
+		return nil, err
+	}
+	rawShares, err := crypto.SplitSecret(keys.seal, len(custodianIDs), threshold)
+	if err != nil {
+		log.Printf("Failed to split key for patient %s: %v", patientID, err)
+		return nil, errs.Invalid("Need at least two custodians and a threshold between 2 and the number of custodians")
+	}
+
+	// Step 3: Keep each share wrapped for its custodian, plus fingerprints to
+	// check submitted shares and the rebuilt key against
+	escrow := &models.KeyEscrow{
+		PatientID:         patientID,
+		Threshold:         threshold,
+		Custodians:        custodianIDs,
+		Shares:            make(map[string]models.WrappedKey, len(custodianIDs)),
+		ShareFingerprints: make(map[string]string, len(custodianIDs)),
+		KeyFingerprint:    crypto.Fingerprint(keys.seal),
+		CreatedAt:         time.Now().UTC(),
+	}
+	for i, id := range custodianIDs {
+		ephemeralKey, wrapped, err := crypto.WrapKey(publicKeys[id], rawShares[i])
+		if err != nil {
+			log.Printf("Failed to wrap share for custodian %s: %v", id, err)
+			return nil, err
+		}
+		escrow.Shares[id] = models.WrappedKey{EphemeralKey: ephemeralKey, Ciphertext: wrapped}
+		escrow.ShareFingerprints[id] = crypto.Fingerprint(rawShares[i])
+	}
+
+	_, err = ps.Firestore.Client.Collection("key_escrows").Doc(patientID).Set(ctx, escrow)
+	if err != nil {
+		log.Printf("Failed to save key escrow for patient %s: %v", patientID, err)
+		return nil, err
+	}
+
+	return escrow, nil
+}
@@ -21,58 +21,35 @@ func NewPharmacistService(firestore *firebase.FirestoreClient) *PharmacistServic
 	}
 }
 
-func (ps *PharmacistService) GetActivePrescriptions(nfcID string) ([]*models.Prescription, error) {
-	ctx := context.Background()
-
+// GetActivePrescriptions returns a page of the undispensed prescriptions of the
+// patient with the card, filtered and sorted by created_at (newest first by default)
+func (ps *PharmacistService) GetActivePrescriptions(ctx context.Context, nfcID string, q models.PageQuery) (*models.Page[*models.Prescription], error) {
 	// Step 1: Find patient by NFC ID
-	userDocs, err := ps.Firestore.Client.Collection("users").
-		Where("nfc_id", "==", nfcID).
-		Where("role", "==", "patient").
-		Documents(ctx).GetAll()
+	patient, err := findPatientByNFC(ctx, ps.Firestore, nfcID)
 	if err != nil {
-		log.Printf("Failed to query patient by NFC ID: %v", err)
 		return nil, err
 	}
-	if len(userDocs) == 0 {
-		return nil, logError("Patient not found with NFC ID: " + nfcID)
-	}
 
-	var patient models.User
-	if err := userDocs[0].DataTo(&patient); err != nil {
-		log.Printf("Failed to parse patient data: %v", err)
+	// Step 2: Query a page of active prescriptions
+	order, err := parsePageOrder(q.Sort, "-created_at", "created_at")
+	if err != nil {
 		return nil, err
 	}
-	patient.UID = userDocs[0].Ref.ID
-
-	// Step 2: Query active prescriptions
-	docs, err := ps.Firestore.Client.Collection("prescriptions").
+	query := ps.Firestore.Client.Collection("prescriptions").
 		Where("user_id", "==", patient.UID).
-		Where("is_active", "==", true).
-		Documents(ctx).GetAll()
+		Where("is_active", "==", true)
+	docs, nextCursor, err := timePage(ctx, query, order, q, pageLimit(q.Limit, defaultPageLimit, maxPageLimit), nil)
 	if err != nil {
 		log.Printf("Failed to query active prescriptions: %v", err)
 		return nil, err
 	}
-
-	var prescriptions []*models.Prescription
-	for _, doc := range docs {
-		var p models.Prescription
-		if err := doc.DataTo(&p); err != nil {
-			log.Printf("Failed to parse prescription data: %v", err)
-			continue
-		}
-		p.ID = doc.Ref.ID
-		prescriptions = append(prescriptions, &p)
-	}
-
-	return prescriptions, nil
+	return &models.Page[*models.Prescription]{Items: parsePrescriptions(docs), NextCursor: nextCursor}, nil
 }
 
-func (ps *PharmacistService) DispensePrescription(tokenID string) error {
-	ctx := context.Background()
-
+func (ps *PharmacistService) DispensePrescription(ctx context.Context, tokenID string) error {
 	// Update Firestore (temporary—blockchain burning TBD)
-	_, err := ps.Firestore.Client.Collection("prescriptions").Doc(tokenID).Update(ctx, []firestore.Update{
+	ref := ps.Firestore.Client.Collection("prescriptions").Doc(tokenID)
+	_, err := ref.Update(ctx, []firestore.Update{
 		{Path: "is_active", Value: false},
 		{Path: "dispensed_at", Value: time.Now().UTC()},
 	})
@@ -81,6 +58,14 @@ func (ps *PharmacistService) DispensePrescription(tokenID string) error {
 		return err
 	}
 
+	// The patient's emergency summary lists current medications
+	if doc, err := ref.Get(ctx); err == nil {
+		value, _ := doc.DataAt("user_id")
+		if patientID, ok := value.(string); ok && patientID != "" {
+			invalidateEmergencySummary(ctx, ps.Firestore, patientID)
+		}
+	}
+
 	// TODO: Add blockchain NFT burning logic here
 	log.Println("DispensePrescription blockchain logic not implemented yet")
 	return nil
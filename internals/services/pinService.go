@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+	"github.com/Frhnmj2004/hippocard-server/pkg/firebase"
+	"github.com/Frhnmj2004/hippocard-server/pkg/storage"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	writeBatchSize     = 400            // Stay under Firestore's 500 writes per batch
+	defaultOrphanGrace = 24 * time.Hour // How long an unreferenced blob is kept before it may be released
+)
+
+// recordCollections maps each record type to the collection holding its CIDs
+var recordCollections = map[string]string{
+	"medical_history": "medical_history",
+	"attachment":      "attachments",
+}
+
+// PinService tracks which stored blobs are referenced by records, keeps them
+// pinned, and releases blobs nothing refers to
+type PinService struct {
+	Firestore    *firebase.FirestoreClient
+	Storage      storage.BlobStore
+	UnpinOrphans bool          // Release unreferenced blobs during verification instead of only reporting them
+	OrphanGrace  time.Duration // Blobs are uploaded before their record is written, so younger orphans are kept
+}
+
+// NewPinService creates a new PinService instance
+func NewPinService(firestore *firebase.FirestoreClient, store storage.BlobStore) *PinService {
+	return &PinService{
+		Firestore:   firestore,
+		Storage:     store,
+		OrphanGrace: defaultOrphanGrace,
+	}
+}
+
+// PinRecord pins a newly stored blob and records which patient record it belongs to
+func (pns *PinService) PinRecord(ctx context.Context, cid, patientID, recordType, recordID string) error {
+	if err := pns.Storage.Pin(ctx, cid); err != nil {
+		log.Printf("Failed to pin %s for %s %s: %v", cid, recordType, recordID, err)
+		return err
+	}
+
+	_, err := pns.Firestore.Client.Collection("pins").Doc(cid).Set(ctx, models.PinRecord{
+		CID:        cid,
+		PatientID:  patientID,
+		RecordType: recordType,
+		RecordID:   recordID,
+		Status:     "pinned",
+		PinnedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to save pin record for %s: %v", cid, err)
+		return err
+	}
+	return nil
+}
+
+// UnpinRecord releases a blob whose record has been erased
+func (pns *PinService) UnpinRecord(ctx context.Context, cid string) error {
+	if err := pns.Storage.Delete(ctx, cid); err != nil {
+		log.Printf("Failed to unpin %s: %v", cid, err)
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err := pns.Firestore.Client.Collection("pins").Doc(cid).Set(ctx, map[string]interface{}{
+		"cid":         cid,
+		"status":      "unpinned",
+		"unpinned_at": now,
+	}, firestore.MergeAll)
+	if err != nil {
+		log.Printf("Failed to update pin record for %s: %v", cid, err)
+		return err
+	}
+	return nil
+}
+
+// VerifyPins checks that every CID referenced by a record is pinned, re-pins any
+// that are not, and reports (or, with UnpinOrphans, releases) pinned blobs that no
+// record references. Orphans younger than OrphanGrace are reported but kept, since
+// their record may still be on its way. The report is saved to the pin_reports
+// collection.
+func (pns *PinService) VerifyPins(ctx context.Context) (*models.PinReport, error) {
+	report := &models.PinReport{
+		ID:        uuid.New().String(),
+		StartedAt: time.Now().UTC(),
+		Repinned:  []string{},
+		Missing:   []string{},
+		Orphaned:  []string{},
+		Unpinned:  []string{},
+	}
+
+	// Step 1: List pinned blobs before reading records, so that a blob uploaded
+	// while records are read is never mistaken for an orphan
+	pinnedIDs, err := pns.Storage.ListPinned(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Collect every CID referenced by a record
+	referenced := make(map[string]models.PinRecord)
+	for recordType, collection := range recordCollections {
+		docs, err := pns.Firestore.Client.Collection(collection).Select("user_id", "cid", "versions").Documents(ctx).GetAll()
+		if err != nil {
+			log.Printf("Failed to list %s for pin verification: %v", collection, err)
+			return nil, err
+		}
+		for _, doc := range docs {
+			patientID, _ := doc.DataAt("user_id")
+			patientStr, _ := patientID.(string)
+			for _, cid := range recordCIDs(doc) {
+				referenced[cid] = models.PinRecord{
+					CID:        cid,
+					PatientID:  patientStr,
+					RecordType: recordType,
+					RecordID:   doc.Ref.ID,
+				}
+			}
+		}
+	}
+
+	// Step 3: Make sure each referenced CID is pinned and refresh its pin record
+	batch := pns.Firestore.Client.Batch()
+	pending := 0
+	for cid, ref := range referenced {
+		report.Checked++
+		status := "pinned"
+
+		pinned, err := pns.Storage.Pinned(ctx, cid)
+		if err != nil || !pinned {
+			if err := pns.Storage.Pin(ctx, cid); err != nil {
+				log.Printf("Referenced blob %s (%s %s) is missing: %v", cid, ref.RecordType, ref.RecordID, err)
+				report.Missing = append(report.Missing, cid)
+				status = "missing"
+			} else {
+				report.Repinned = append(report.Repinned, cid)
+			}
+		}
+
+		update := map[string]interface{}{
+			"cid":         cid,
+			"patient_id":  ref.PatientID,
+			"record_type": ref.RecordType,
+			"record_id":   ref.RecordID,
+			"status":      status,
+			"orphaned_at": firestore.Delete,
+		}
+		if status == "pinned" {
+			update["last_verified_at"] = time.Now().UTC()
+		}
+		batch.Set(pns.Firestore.Client.Collection("pins").Doc(cid), update, firestore.MergeAll)
+		pending++
+		if pending == writeBatchSize {
+			if _, err := batch.Commit(ctx); err != nil {
+				log.Printf("Failed to update pin records: %v", err)
+				return nil, err
+			}
+			batch = pns.Firestore.Client.Batch()
+			pending = 0
+		}
+	}
+	if pending > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			log.Printf("Failed to update pin records: %v", err)
+			return nil, err
+		}
+	}
+
+	// Step 4: Find pinned blobs that nothing references, releasing those past the grace period
+	for _, cid := range pinnedIDs {
+		if _, ok := referenced[cid]; ok {
+			continue
+		}
+		report.Orphaned = append(report.Orphaned, cid)
+		if !pns.UnpinOrphans {
+			continue
+		}
+		since, err := pns.orphanedSince(ctx, cid)
+		if err != nil || time.Since(since) < pns.OrphanGrace {
+			continue
+		}
+		if err := pns.UnpinRecord(ctx, cid); err == nil {
+			report.Unpinned = append(report.Unpinned, cid)
+		}
+	}
+
+	// Step 5: Save the report
+	report.FinishedAt = time.Now().UTC()
+	if _, err := pns.Firestore.Client.Collection("pin_reports").Doc(report.ID).Set(ctx, report); err != nil {
+		log.Printf("Failed to save pin report: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Pin verification: %d checked, %d repinned, %d missing, %d orphaned, %d unpinned",
+		report.Checked, len(report.Repinned), len(report.Missing), len(report.Orphaned), len(report.Unpinned))
+	return report, nil
+}
+
+// orphanedSince returns how long an unreferenced blob has been around: its
+// modification time where the backend reports one, or else when verification
+// first found it unreferenced. The pin record's pinned_at is not used, since a
+// blob released and later uploaded again keeps its old record.
+func (pns *PinService) orphanedSince(ctx context.Context, cid string) (time.Time, error) {
+	info, err := pns.Storage.Stat(ctx, cid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !info.ModifiedAt.IsZero() {
+		return info.ModifiedAt, nil
+	}
+
+	ref := pns.Firestore.Client.Collection("pins").Doc(cid)
+	doc, err := ref.Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		log.Printf("Failed to get pin record for %s: %v", cid, err)
+		return time.Time{}, err
+	}
+	if doc.Exists() {
+		var pin models.PinRecord
+		if err := doc.DataTo(&pin); err == nil && pin.OrphanedAt != nil {
+			return *pin.OrphanedAt, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	_, err = ref.Set(ctx, map[string]interface{}{
+		"cid":         cid,
+		"status":      "orphaned",
+		"orphaned_at": now,
+	}, firestore.MergeAll)
+	if err != nil {
+		log.Printf("Failed to mark %s as orphaned: %v", cid, err)
+		return time.Time{}, err
+	}
+	return now, nil
+}
+
+// recordCIDs returns every CID a record refers to: its current blob and those of
+// any superseded versions
+func recordCIDs(doc *firestore.DocumentSnapshot) []string {
+	var cids []string
+	cid, _ := doc.DataAt("cid")
+	if cidStr, _ := cid.(string); cidStr != "" {
+		cids = append(cids, cidStr)
+	}
+	versions, _ := doc.DataAt("versions")
+	list, _ := versions.([]interface{})
+	for _, v := range list {
+		version, _ := v.(map[string]interface{})
+		if cidStr, _ := version["cid"].(string); cidStr != "" {
+			cids = append(cids, cidStr)
+		}
+	}
+	return cids
+}
+
+// RunVerifier verifies pins every interval until ctx is cancelled
+func (pns *PinService) RunVerifier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pns.VerifyPins(ctx); err != nil {
+				log.Printf("Pin verification failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/internals/models"
+)
+
+func TestVerifyPinsKeepsYoungOrphans(t *testing.T) {
+	fs := testFirestore(t)
+	ctx := context.Background()
+	store := testStore(t)
+
+	// One blob belongs to a record; the other was uploaded for a record that
+	// has not been written yet
+	referencedID, err := store.AddData(ctx, []byte("referenced "+testID(t)))
+	if err != nil {
+		t.Fatalf("AddData: %v", err)
+	}
+	pendingID, err := store.AddData(ctx, []byte("pending "+testID(t)))
+	if err != nil {
+		t.Fatalf("AddData: %v", err)
+	}
+	recordID := testID(t)
+	_, err = fs.Client.Collection("medical_history").Doc(recordID).Set(ctx, models.MedicalHistory{
+		ID: recordID, UserID: testID(t), CID: referencedID, CreatedAt: time.Now().UTC(), Version: 1,
+	})
+	if err != nil {
+		t.Fatalf("storing record: %v", err)
+	}
+	t.Cleanup(func() { fs.Client.Collection("medical_history").Doc(recordID).Delete(context.Background()) })
+
+	tests := []struct {
+		name         string
+		grace        time.Duration
+		wantReleased bool
+	}{
+		{"within the grace period", time.Hour, false},
+		{"past the grace period", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pins := NewPinService(fs, store)
+			pins.UnpinOrphans = true
+			pins.OrphanGrace = tt.grace
+
+			report, err := pins.VerifyPins(ctx)
+			if err != nil {
+				t.Fatalf("VerifyPins: %v", err)
+			}
+			if !slices.Contains(report.Orphaned, pendingID) {
+				t.Errorf("orphans %v do not include the pending blob", report.Orphaned)
+			}
+			if slices.Contains(report.Orphaned, referencedID) {
+				t.Error("the referenced blob was reported as an orphan")
+			}
+			if released := slices.Contains(report.Unpinned, pendingID); released != tt.wantReleased {
+				t.Errorf("pending blob released = %v, want %v", released, tt.wantReleased)
+			}
+			if pinned, _ := store.Pinned(ctx, pendingID); pinned == tt.wantReleased {
+				t.Errorf("pending blob stored = %v, want %v", pinned, !tt.wantReleased)
+			}
+			if pinned, _ := store.Pinned(ctx, referencedID); !pinned {
+				t.Error("the referenced blob was released")
+			}
+		})
+	}
+}
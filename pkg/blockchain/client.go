@@ -4,12 +4,15 @@ package blockchain
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"time"
 
 	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -38,19 +41,24 @@ type Client struct {
 	ChainID      *big.Int
 	PrivateKey   *ecdsa.PrivateKey // For signing transactions
 	FromAddress  common.Address    // Sender’s address
+	Timeout      time.Duration     // Bounds each RPC or transaction submission
 }
 
 // NewClient initializes a new Polygon blockchain client
-func NewClient(config *configs.Config) (*Client, error) {
+func NewClient(ctx context.Context, config *configs.Config) (*Client, error) {
+	timeout := config.Timeouts.Blockchain
+
 	// Connect to Polygon RPC (e.g., Mumbai testnet)
-	ethClient, err := ethclient.Dial(config.Blockchain.RPCURL)
+	dialCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	ethClient, err := ethclient.DialContext(dialCtx, config.Blockchain.RPCURL)
 	if err != nil {
 		log.Printf("Failed to connect to Polygon RPC: %v", err)
 		return nil, err
 	}
 
 	// Get chain ID to verify network
-	chainID, err := ethClient.NetworkID(context.Background())
+	chainID, err := ethClient.NetworkID(dialCtx)
 	if err != nil {
 		log.Printf("Failed to get chain ID: %v", err)
 		return nil, err
@@ -70,7 +78,8 @@ func NewClient(config *configs.Config) (*Client, error) {
 	publicKey := privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
 	if !ok {
-		return nil, logError("Invalid public key type")
+		log.Println("Invalid public key type")
+		return nil, errors.New("invalid public key type")
 	}
 	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
@@ -88,17 +97,22 @@ func NewClient(config *configs.Config) (*Client, error) {
 		ChainID:      chainID,
 		PrivateKey:   privateKey,
 		FromAddress:  fromAddress,
+		Timeout:      timeout,
 	}, nil
 }
 
 // MintPrescription mints a new prescription NFT for a patient
-func (c *Client) MintPrescription(patientAddr string, medication string, dosage uint64) (uint64, error) {
+func (c *Client) MintPrescription(ctx context.Context, patientAddr string, medication string, dosage uint64) (uint64, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
 	// Create transaction options with private key
 	auth, err := bind.NewKeyedTransactorWithChainID(c.PrivateKey, c.ChainID)
 	if err != nil {
 		log.Printf("Failed to create transactor: %v", err)
 		return 0, err
 	}
+	auth.Context = ctx // Bounds nonce, gas estimation and submission
 
 	// Convert patient address to common.Address
 	to := common.HexToAddress(patientAddr)
@@ -114,13 +128,17 @@ func (c *Client) MintPrescription(patientAddr string, medication string, dosage
 }
 
 // DispensePrescription burns a prescription NFT
-func (c *Client) DispensePrescription(tokenID uint64) error {
+func (c *Client) DispensePrescription(ctx context.Context, tokenID uint64) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
 	// Create transaction options with private key
 	auth, err := bind.NewKeyedTransactorWithChainID(c.PrivateKey, c.ChainID)
 	if err != nil {
 		log.Printf("Failed to create transactor: %v", err)
 		return err
 	}
+	auth.Context = ctx
 
 	// Burn the NFT
 	tx, err := c.Contract.Burn(auth, big.NewInt(int64(tokenID)))
@@ -133,9 +151,49 @@ func (c *Client) DispensePrescription(tokenID uint64) error {
 	return nil
 }
 
-// logError helper function for consistent error logging
-func logError(msg string) error {
-	err := fmt.Errorf(msg)
-	log.Println(err)
-	return err
+// TransferPrescription moves a prescription NFT from one patient wallet to
+// another. The server's account must be approved for the token by its owner; a
+// token already held by toAddr is left alone, so retries are safe.
+func (c *Client) TransferPrescription(ctx context.Context, tokenID uint64, fromAddr, toAddr string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	from, to, token := common.HexToAddress(fromAddr), common.HexToAddress(toAddr), new(big.Int).SetUint64(tokenID)
+	owner, err := c.Contract.OwnerOf(&bind.CallOpts{Context: ctx}, token)
+	if err != nil {
+		log.Printf("Failed to get owner of prescription NFT %d: %v", tokenID, err)
+		return err
+	}
+	if owner == to {
+		return nil
+	}
+	if owner != from {
+		log.Printf("Prescription NFT %d is held by %s, not %s", tokenID, owner.Hex(), from.Hex())
+		return errs.Conflict(fmt.Sprintf("Prescription NFT %d is held by %s, not %s", tokenID, owner.Hex(), from.Hex()))
+	}
+
+	// Create transaction options with private key
+	auth, err := bind.NewKeyedTransactorWithChainID(c.PrivateKey, c.ChainID)
+	if err != nil {
+		log.Printf("Failed to create transactor: %v", err)
+		return err
+	}
+	auth.Context = ctx
+
+	tx, err := c.Contract.TransferFrom(auth, from, to, token)
+	if err != nil {
+		log.Printf("Failed to transfer prescription NFT %d: %v", tokenID, err)
+		return err
+	}
+
+	log.Printf("Transferred prescription NFT (tokenID: %d) to %s, transaction: %s", tokenID, to.Hex(), tx.Hash().Hex())
+	return nil
+}
+
+// withTimeout bounds a single chain call; a zero timeout only inherits ctx
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
@@ -0,0 +1,1330 @@
+// Code generated by openapiclient from ../../api/routes/openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// An RFC 7807 problem, returned by every failed request
+type Problem struct {
+	Type      string       `json:"type"`                 // Always "about:blank"; the status says what went wrong
+	Title     string       `json:"title"`                // The status text
+	Status    int          `json:"status"`               // The HTTP status code
+	Detail    string       `json:"detail"`               // What went wrong, safe to show to users
+	Instance  string       `json:"instance"`             // The request path
+	Code      string       `json:"code"`                 // Machine-readable kind of problem
+	RequestID string       `json:"request_id,omitempty"` // Quote it when reporting a problem
+	Errors    []FieldError `json:"errors,omitempty"`     // Each invalid field of the request
+}
+
+type FieldError struct {
+	Field   string `json:"field"` // Dotted JSON path, e.g. "wrapped_key.ciphertext"
+	Message string `json:"message"`
+}
+
+type Message struct {
+	Message string `json:"message"`
+}
+
+type User struct {
+	UID           string    `json:"uid"`              // Firebase UID
+	NFCID         string    `json:"nfc_id,omitempty"` // NFC card identifier; absent in responses to doctors
+	Name          string    `json:"name"`
+	DateOfBirth   string    `json:"date_of_birth,omitempty"` // Patients only
+	Phone         string    `json:"phone,omitempty"`
+	Role          string    `json:"role"`
+	WalletAddress string    `json:"wallet_address,omitempty"`
+	MergedInto    string    `json:"merged_into,omitempty"` // Surviving patient's UID once this record is merged
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type UserPage struct {
+	Items      []User `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass back as cursor for the next page; absent on the last page
+}
+
+type Allergy struct {
+	Substance string `json:"substance"`
+	Reaction  string `json:"reaction,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+}
+
+type EmergencyContact struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship,omitempty"`
+	Phone        string `json:"phone"`
+}
+
+type Insurance struct {
+	Provider     string `json:"provider"`
+	PolicyNumber string `json:"policy_number"`
+	GroupNumber  string `json:"group_number,omitempty"`
+	ExpiresOn    string `json:"expires_on,omitempty"`
+}
+
+// A patient's demographics and emergency clinical facts
+type PatientProfile struct {
+	PatientID         string             `json:"patient_id,omitempty"`
+	DateOfBirth       string             `json:"date_of_birth,omitempty"`
+	Sex               string             `json:"sex,omitempty"`
+	BloodType         string             `json:"blood_type,omitempty"` // ABO and Rh, e.g. "O-"
+	Allergies         []Allergy          `json:"allergies,omitempty"`
+	Conditions        []string           `json:"conditions,omitempty"`
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts,omitempty"`
+	Insurance         *Insurance         `json:"insurance,omitempty"`
+	UpdatedAt         *time.Time         `json:"updated_at,omitempty"`
+	UpdatedBy         string             `json:"updated_by,omitempty"`
+}
+
+type CurrentMedication struct {
+	Medication string    `json:"medication"`
+	Dosage     int64     `json:"dosage"`
+	Since      time.Time `json:"since"`
+}
+
+type EmergencySummary struct {
+	PatientID         string              `json:"patient_id"`
+	Name              string              `json:"name"`
+	DateOfBirth       string              `json:"date_of_birth,omitempty"`
+	BloodType         string              `json:"blood_type,omitempty"`
+	Allergies         []Allergy           `json:"allergies"`
+	Conditions        []string            `json:"conditions"`
+	Medications       []CurrentMedication `json:"medications"` // Null when the caller may not see prescriptions
+	EmergencyContacts []EmergencyContact  `json:"emergency_contacts"`
+	HasProfile        bool                `json:"has_profile"` // False when no clinical profile is recorded, so empty lists mean unknown rather than none
+	GeneratedAt       time.Time           `json:"generated_at"`
+	Compact           string              `json:"compact"` // The summary as short plain text, for printing or a QR code
+}
+
+type Prescription struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	TokenID     string     `json:"token_id"`
+	Medication  string     `json:"medication"`
+	Dosage      int64      `json:"dosage"`
+	IsActive    bool       `json:"is_active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DispensedAt *time.Time `json:"dispensed_at,omitempty"`
+}
+
+type PrescriptionPage struct {
+	Items      []Prescription `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type MedicalHistoryEntry struct {
+	ID        string          `json:"id"`
+	Version   int             `json:"version"`
+	Type      string          `json:"type,omitempty"`    // Absent on a retraction
+	Data      json.RawMessage `json:"data,omitempty"`    // The entry's fields, as described by its type's schema
+	History   string          `json:"history,omitempty"` // Deprecated: The text of a note, kept for older clients
+	AuthorID  string          `json:"author_id,omitempty"`
+	Reason    string          `json:"reason,omitempty"` // Why this version replaced the previous one
+	Retracted bool            `json:"retracted,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+type MedicalHistoryEntryPage struct {
+	Items      []MedicalHistoryEntry `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type MedicalHistoryVersions struct {
+	ID        string                `json:"id"`
+	PatientID string                `json:"patient_id"`
+	Versions  []MedicalHistoryEntry `json:"versions"` // Oldest first
+}
+
+// A record's data key encrypted to one reader's public key
+type WrappedKey struct {
+	EphemeralKey []byte `json:"ephemeral_key"` // Sender's ephemeral X25519 public key, 32 bytes
+	Ciphertext   []byte `json:"ciphertext"`    // AES-GCM sealed data key
+}
+
+type EncryptedHistoryEntry struct {
+	ID         string     `json:"id"`
+	AuthorID   string     `json:"author_id"`
+	Ciphertext []byte     `json:"ciphertext"`
+	WrappedKey WrappedKey `json:"wrapped_key"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type HistoryWriteResult struct {
+	DocID     string `json:"doc_id"`
+	Version   int    `json:"version,omitempty"`
+	Retracted bool   `json:"retracted,omitempty"`
+}
+
+type AttachmentCreated struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+type PrescriptionCreated struct {
+	PrescriptionID string `json:"prescription_id,omitempty"`
+	Message        string `json:"message,omitempty"` // Set instead of prescription_id while minting is not available
+}
+
+type PublicKey struct {
+	UserID    string    `json:"user_id"`
+	Key       []byte    `json:"key"` // Raw 32-byte X25519 public key
+	Algorithm string    `json:"algorithm"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// How the patient's data key was split; each custodian collects their own share
+type KeyEscrow struct {
+	PatientID  string    `json:"patient_id"`
+	Threshold  int       `json:"threshold"`  // Shares needed to rebuild the key
+	Custodians []string  `json:"custodians"` // UIDs of the custodians holding shares
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AccessGrant struct {
+	ID         string     `json:"id"` // Presented by the hospital in X-Access-Grant
+	PatientID  string     `json:"patient_id"`
+	NFCID      string     `json:"nfc_id"`
+	HospitalID string     `json:"hospital_id"`
+	IssuedBy   string     `json:"issued_by"`
+	IssuerRole string     `json:"issuer_role"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+}
+
+type Consent struct {
+	ID          string     `json:"id"`
+	PatientID   string     `json:"patient_id"`
+	GranteeID   string     `json:"grantee_id"`
+	GranteeRole string     `json:"grantee_role"`
+	Scopes      []string   `json:"scopes"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // Absent means until revoked
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CareRelationship struct {
+	ID        string     `json:"id"`
+	DoctorID  string     `json:"doctor_id"`
+	PatientID string     `json:"patient_id"`
+	Status    string     `json:"status"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	EndedBy   string     `json:"ended_by,omitempty"`
+	Blocked   bool       `json:"blocked,omitempty"` // The patient ended it; the doctor cannot restart it until the patient allows them
+}
+
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	ActorID   string    `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	ActorRole string    `json:"actor_role"`
+	Resource  string    `json:"resource"`
+	Action    string    `json:"action"`
+	Purpose   string    `json:"purpose,omitempty"`
+	Outcome   string    `json:"outcome"`
+}
+
+type AccessLogEntryPage struct {
+	Items      []AccessLogEntry `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	Seq        int64     `json:"seq"` // Position in the hash chain; 0 until the entry is linked
+	ActorID    string    `json:"actor_id"`
+	ActorRole  string    `json:"actor_role"`
+	PatientIDs []string  `json:"patient_ids"`
+	NFCID      string    `json:"nfc_id,omitempty"`
+	Resource   string    `json:"resource"`
+	Action     string    `json:"action"`
+	Endpoint   string    `json:"endpoint"` // Method and route template, e.g. "GET /api/doctor/patient/:nfc_id"
+	Purpose    string    `json:"purpose,omitempty"`
+	Emergency  bool      `json:"emergency,omitempty"`
+	Outcome    string    `json:"outcome"`
+	StatusCode int       `json:"status_code"`
+	RequestIP  string    `json:"request_ip,omitempty"`
+	Time       time.Time `json:"time"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+type AuditEntryPage struct {
+	Items      []AuditEntry `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type HospitalPatientData struct {
+	Patient        User                  `json:"patient"`
+	Profile        *PatientProfile       `json:"profile,omitempty"`
+	Scopes         []string              `json:"scopes"` // Consented parts of the record included
+	Prescriptions  []Prescription        `json:"prescriptions"`
+	MedicalHistory []MedicalHistoryEntry `json:"medical_history"`
+	AccessTime     time.Time             `json:"access_time"`
+	AccessID       string                `json:"access_id"`
+}
+
+type BreakGlassRequest struct {
+	ID          string    `json:"id"`
+	PatientID   string    `json:"patient_id"`
+	NFCID       string    `json:"nfc_id"`
+	RequesterID string    `json:"requester_id"`
+	Reason      string    `json:"reason"`
+	Status      string    `json:"status"`
+	Approvals   []string  `json:"approvals"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type PatientMatch struct {
+	PatientID string   `json:"patient_id"`
+	Patient   *User    `json:"patient,omitempty"`
+	Score     float64  `json:"score"` // 0 to 1, higher is closer
+	Reasons   []string `json:"reasons"`
+}
+
+type DuplicateCandidate struct {
+	ID         string     `json:"id"`
+	PatientIDs []string   `json:"patient_ids"`
+	Score      float64    `json:"score"`
+	Reasons    []string   `json:"reasons"`
+	Status     string     `json:"status"`
+	DetectedAt time.Time  `json:"detected_at"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	SurvivorID string     `json:"survivor_id,omitempty"`
+}
+
+type PatientMerge struct {
+	MergedID         string         `json:"merged_id"`
+	SurvivorID       string         `json:"survivor_id"`
+	NFCID            string         `json:"nfc_id"`
+	CandidateID      string         `json:"candidate_id"`
+	MergedBy         string         `json:"merged_by"`
+	MergedAt         time.Time      `json:"merged_at"`
+	Moved            map[string]int `json:"moved"`                       // Collection -> documents moved to the survivor
+	PendingTransfers []string       `json:"pending_transfers,omitempty"` // NFT token IDs that could not be moved to the survivor's wallet
+}
+
+type ScanResult struct {
+	Flagged int `json:"flagged"` // New duplicate candidates queued
+}
+
+type LoginResult struct {
+	Token   string `json:"token"`
+	Message string `json:"message"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type KeyEscrowRequest struct {
+	Custodians []string `json:"custodians"` // UIDs of hospital accounts that have published a public key
+	Threshold  int      `json:"threshold"`  // Shares needed to rebuild the key
+}
+
+type GrantHistoryAccessRequest struct {
+	RecipientID string     `json:"recipient_id"`
+	WrappedKey  WrappedKey `json:"wrapped_key"`
+}
+
+type PatientAccessGrantRequest struct {
+	HospitalID string `json:"hospital_id"`
+}
+
+type DoctorAccessGrantRequest struct {
+	NFCID      string `json:"nfc_id"`
+	HospitalID string `json:"hospital_id"`
+}
+
+type GrantConsentRequest struct {
+	GranteeID string     `json:"grantee_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Absent means until revoked
+}
+
+type PublishPublicKeyRequest struct {
+	PublicKey []byte `json:"public_key"` // Raw 32-byte X25519 public key
+}
+
+type CreatePrescriptionRequest struct {
+	PatientID  string `json:"patient_id"`
+	Medication string `json:"medication"`
+	Dosage     int64  `json:"dosage"`
+}
+
+type AddMedicalHistoryRequest struct {
+	PatientID string          `json:"patient_id"`
+	Type      string          `json:"type,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`    // The entry's fields, validated against the type's schema
+	History   string          `json:"history,omitempty"` // Deprecated: Free text stored as a note when type is absent
+}
+
+type AmendMedicalHistoryRequest struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"` // The entry's fields, validated against the type's schema
+	Reason string          `json:"reason"`
+}
+
+type RetractMedicalHistoryRequest struct {
+	Reason string `json:"reason"`
+}
+
+type AddEncryptedMedicalHistoryRequest struct {
+	PatientID   string                `json:"patient_id"`
+	Ciphertext  []byte                `json:"ciphertext"`
+	WrappedKeys map[string]WrappedKey `json:"wrapped_keys"` // Reader UID -> data key wrapped for them; must include the patient
+}
+
+type EstablishCareRequest struct {
+	NFCID string `json:"nfc_id"` // Card the doctor has just scanned
+}
+
+type DispensePrescriptionRequest struct {
+	TokenID string `json:"token_id"`
+}
+
+type OpenBreakGlassRequest struct {
+	NFCID  string `json:"nfc_id"`
+	Reason string `json:"reason"`
+}
+
+type ApproveBreakGlassRequest struct {
+	Share string `json:"share"` // The custodian's unwrapped share, base64-encoded
+}
+
+type MergeDuplicateRequest struct {
+	SurvivorID string `json:"survivor_id"` // One of the candidate's patients
+}
+
+// Login calls POST /api/login: log in with email and password
+func (c *Client) Login(ctx context.Context, body *LoginRequest) (*LoginResult, error) {
+	path := "/api/login"
+	query := url.Values{}
+	header := http.Header{}
+	var out LoginResult
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPISpec calls GET /api/openapi.json: this document
+func (c *Client) GetOpenAPISpec(ctx context.Context) (json.RawMessage, error) {
+	path := "/api/openapi.json"
+	query := url.Values{}
+	header := http.Header{}
+	var out json.RawMessage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatientGetProfile calls GET /api/patient/profile: the patient's account
+func (c *Client) PatientGetProfile(ctx context.Context) (*User, error) {
+	path := "/api/patient/profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out User
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientListPrescriptions calls GET /api/patient/prescriptions: the patient's prescriptions (not available yet)
+func (c *Client) PatientListPrescriptions(ctx context.Context) (*Message, error) {
+	path := "/api/patient/prescriptions"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientListMedicalHistoryParams holds the optional parameters of PatientListMedicalHistory; zero values are not sent
+type PatientListMedicalHistoryParams struct {
+	Cursor string // next_cursor from the previous page; only valid with the same sort
+	Limit  int    // Items per page; each list has a default and a maximum
+	From   string // Only items dated at or after this RFC 3339 time or YYYY-MM-DD date
+	To     string // Only items dated before this RFC 3339 time, or on or before this YYYY-MM-DD date
+	Sort   string // A field the list can be sorted by, prefixed with "-" for descending
+}
+
+// PatientListMedicalHistory calls GET /api/patient/medical-history: a page of the patient's medical history, newest first by default; sorts by created_at
+func (c *Client) PatientListMedicalHistory(ctx context.Context, params *PatientListMedicalHistoryParams) (*MedicalHistoryEntryPage, error) {
+	path := "/api/patient/medical-history"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.From != "" {
+			query.Set("from", params.From)
+		}
+		if params.To != "" {
+			query.Set("to", params.To)
+		}
+		if params.Sort != "" {
+			query.Set("sort", params.Sort)
+		}
+	}
+	var out MedicalHistoryEntryPage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGetMedicalHistoryVersions calls GET /api/patient/medical-history/{id}/versions: every version of one of the patient's history entries
+func (c *Client) PatientGetMedicalHistoryVersions(ctx context.Context, id string) (*MedicalHistoryVersions, error) {
+	path := "/api/patient/medical-history/" + url.PathEscape(id) + "/versions"
+	query := url.Values{}
+	header := http.Header{}
+	var out MedicalHistoryVersions
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientDownloadAttachment calls GET /api/patient/attachments/{id}: download a decrypted attachment
+//
+// The caller must close the returned body.
+func (c *Client) PatientDownloadAttachment(ctx context.Context, id string) (io.ReadCloser, error) {
+	path := "/api/patient/attachments/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	resp, err := c.send(ctx, http.MethodGet, path, query, header, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PatientEraseAttachment calls DELETE /api/patient/attachments/{id}: erase one of the patient's attachments
+func (c *Client) PatientEraseAttachment(ctx context.Context, id string) (*Message, error) {
+	path := "/api/patient/attachments/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientEscrowKey calls POST /api/patient/key-escrow: split the patient's data key among emergency custodians
+func (c *Client) PatientEscrowKey(ctx context.Context, body *KeyEscrowRequest) (*KeyEscrow, error) {
+	path := "/api/patient/key-escrow"
+	query := url.Values{}
+	header := http.Header{}
+	var out KeyEscrow
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientListEncryptedMedicalHistory calls GET /api/patient/medical-history/e2e: the patient's end-to-end encrypted entries, for decryption on the device
+func (c *Client) PatientListEncryptedMedicalHistory(ctx context.Context) ([]EncryptedHistoryEntry, error) {
+	path := "/api/patient/medical-history/e2e"
+	query := url.Values{}
+	header := http.Header{}
+	var out []EncryptedHistoryEntry
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatientGrantHistoryAccess calls POST /api/patient/medical-history/{id}/grants: share an end-to-end entry by storing its data key re-wrapped for another reader
+func (c *Client) PatientGrantHistoryAccess(ctx context.Context, id string, body *GrantHistoryAccessRequest) (*Message, error) {
+	path := "/api/patient/medical-history/" + url.PathEscape(id) + "/grants"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientEraseMedicalHistory calls DELETE /api/patient/medical-history/{id}: erase one of the patient's history entries
+func (c *Client) PatientEraseMedicalHistory(ctx context.Context, id string) (*Message, error) {
+	path := "/api/patient/medical-history/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientIssueAccessGrant calls POST /api/patient/access-grants: let a hospital read the patient's data once
+func (c *Client) PatientIssueAccessGrant(ctx context.Context, body *PatientAccessGrantRequest) (*AccessGrant, error) {
+	path := "/api/patient/access-grants"
+	query := url.Values{}
+	header := http.Header{}
+	var out AccessGrant
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGetAccessLogParams holds the optional parameters of PatientGetAccessLog; zero values are not sent
+type PatientGetAccessLogParams struct {
+	Role   string // Only accesses by this role
+	Cursor string // next_cursor from the previous page; only valid with the same sort
+	Limit  int    // Items per page; each list has a default and a maximum
+	From   string // Only items dated at or after this RFC 3339 time or YYYY-MM-DD date
+	To     string // Only items dated before this RFC 3339 time, or on or before this YYYY-MM-DD date
+	Sort   string // A field the list can be sorted by, prefixed with "-" for descending
+}
+
+// PatientGetAccessLog calls GET /api/patient/access-log: who accessed the patient's data; sorts by time
+func (c *Client) PatientGetAccessLog(ctx context.Context, params *PatientGetAccessLogParams) (*AccessLogEntryPage, error) {
+	path := "/api/patient/access-log"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Role != "" {
+			query.Set("role", params.Role)
+		}
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.From != "" {
+			query.Set("from", params.From)
+		}
+		if params.To != "" {
+			query.Set("to", params.To)
+		}
+		if params.Sort != "" {
+			query.Set("sort", params.Sort)
+		}
+	}
+	var out AccessLogEntryPage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGrantConsent calls POST /api/patient/consents: consent to a practitioner or organization seeing parts of the record
+func (c *Client) PatientGrantConsent(ctx context.Context, body *GrantConsentRequest) (*Consent, error) {
+	path := "/api/patient/consents"
+	query := url.Values{}
+	header := http.Header{}
+	var out Consent
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientListConsents calls GET /api/patient/consents: the patient's consents
+func (c *Client) PatientListConsents(ctx context.Context) ([]Consent, error) {
+	path := "/api/patient/consents"
+	query := url.Values{}
+	header := http.Header{}
+	var out []Consent
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatientRevokeConsent calls DELETE /api/patient/consents/{id}: withdraw a consent
+func (c *Client) PatientRevokeConsent(ctx context.Context, id string) (*Message, error) {
+	path := "/api/patient/consents/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientListCareRelationships calls GET /api/patient/care-relationships: the doctors currently treating the patient, then those the patient stopped
+func (c *Client) PatientListCareRelationships(ctx context.Context) ([]CareRelationship, error) {
+	path := "/api/patient/care-relationships"
+	query := url.Values{}
+	header := http.Header{}
+	var out []CareRelationship
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatientEndCareRelationship calls DELETE /api/patient/care-relationships/{id}: stop a doctor treating the patient until the patient allows them again
+func (c *Client) PatientEndCareRelationship(ctx context.Context, id string) (*Message, error) {
+	path := "/api/patient/care-relationships/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientAllowCareRelationship calls POST /api/patient/care-relationships/{id}/allow: let a stopped doctor restart treating the patient with another scan of their card
+func (c *Client) PatientAllowCareRelationship(ctx context.Context, id string) (*Message, error) {
+	path := "/api/patient/care-relationships/" + url.PathEscape(id) + "/allow"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPost, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGetClinicalProfile calls GET /api/patient/clinical-profile: the patient's clinical profile
+func (c *Client) PatientGetClinicalProfile(ctx context.Context) (*PatientProfile, error) {
+	path := "/api/patient/clinical-profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out PatientProfile
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientSaveClinicalProfile calls PUT /api/patient/clinical-profile: create or replace the patient's clinical profile
+func (c *Client) PatientSaveClinicalProfile(ctx context.Context, body *PatientProfile) (*PatientProfile, error) {
+	path := "/api/patient/clinical-profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out PatientProfile
+	if err := c.do(ctx, http.MethodPut, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientDeleteClinicalProfile calls DELETE /api/patient/clinical-profile: erase the patient's clinical profile
+func (c *Client) PatientDeleteClinicalProfile(ctx context.Context) (*Message, error) {
+	path := "/api/patient/clinical-profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGetEmergencySummaryParams holds the optional parameters of PatientGetEmergencySummary; zero values are not sent
+type PatientGetEmergencySummaryParams struct {
+	Format string // text returns only the compact summary, as text/plain
+}
+
+// PatientGetEmergencySummary calls GET /api/patient/emergency-summary: the patient's own emergency summary, for a printed card
+//
+// The response is decoded as JSON, so leave unset any parameter selecting another representation.
+func (c *Client) PatientGetEmergencySummary(ctx context.Context, params *PatientGetEmergencySummaryParams) (*EmergencySummary, error) {
+	path := "/api/patient/emergency-summary"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Format != "" {
+			query.Set("format", params.Format)
+		}
+	}
+	var out EmergencySummary
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientPublishPublicKey calls PUT /api/patient/public-key: publish the patient's X25519 public key
+func (c *Client) PatientPublishPublicKey(ctx context.Context, body *PublishPublicKeyRequest) (*Message, error) {
+	path := "/api/patient/public-key"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPut, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatientGetPublicKey calls GET /api/patient/public-keys/{uid}: another user's public key
+func (c *Client) PatientGetPublicKey(ctx context.Context, uid string) (*PublicKey, error) {
+	path := "/api/patient/public-keys/" + url.PathEscape(uid)
+	query := url.Values{}
+	header := http.Header{}
+	var out PublicKey
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorGetPatient calls GET /api/doctor/patient/{nfc_id}: look up a patient by the NFC ID on their card
+func (c *Client) DoctorGetPatient(ctx context.Context, nfcID string) (*User, error) {
+	path := "/api/doctor/patient/" + url.PathEscape(nfcID)
+	query := url.Values{}
+	header := http.Header{}
+	var out User
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorCreatePrescription calls POST /api/doctor/prescription: prescribe for a patient
+func (c *Client) DoctorCreatePrescription(ctx context.Context, body *CreatePrescriptionRequest) (*PrescriptionCreated, error) {
+	path := "/api/doctor/prescription"
+	query := url.Values{}
+	header := http.Header{}
+	var out PrescriptionCreated
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorAddMedicalHistory calls POST /api/doctor/medical-history: add a typed entry to a patient's history
+func (c *Client) DoctorAddMedicalHistory(ctx context.Context, body *AddMedicalHistoryRequest) (*HistoryWriteResult, error) {
+	path := "/api/doctor/medical-history"
+	query := url.Values{}
+	header := http.Header{}
+	var out HistoryWriteResult
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorGetHistorySchemas calls GET /api/doctor/medical-history/schemas: the JSON schema of each history entry type
+func (c *Client) DoctorGetHistorySchemas(ctx context.Context) (map[string]json.RawMessage, error) {
+	path := "/api/doctor/medical-history/schemas"
+	query := url.Values{}
+	header := http.Header{}
+	var out map[string]json.RawMessage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DoctorAmendMedicalHistory calls PUT /api/doctor/medical-history/{id}: replace a history entry with a corrected version
+func (c *Client) DoctorAmendMedicalHistory(ctx context.Context, id string, body *AmendMedicalHistoryRequest) (*HistoryWriteResult, error) {
+	path := "/api/doctor/medical-history/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out HistoryWriteResult
+	if err := c.do(ctx, http.MethodPut, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorRetractMedicalHistory calls POST /api/doctor/medical-history/{id}/retract: withdraw a history entry recorded in error
+func (c *Client) DoctorRetractMedicalHistory(ctx context.Context, id string, body *RetractMedicalHistoryRequest) (*HistoryWriteResult, error) {
+	path := "/api/doctor/medical-history/" + url.PathEscape(id) + "/retract"
+	query := url.Values{}
+	header := http.Header{}
+	var out HistoryWriteResult
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorGetMedicalHistoryVersions calls GET /api/doctor/medical-history/{id}/versions: every version of a history entry
+func (c *Client) DoctorGetMedicalHistoryVersions(ctx context.Context, id string) (*MedicalHistoryVersions, error) {
+	path := "/api/doctor/medical-history/" + url.PathEscape(id) + "/versions"
+	query := url.Values{}
+	header := http.Header{}
+	var out MedicalHistoryVersions
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorSearchPatientsParams holds the optional parameters of DoctorSearchPatients; zero values are not sent
+type DoctorSearchPatientsParams struct {
+	Name        string // Each word must prefix a word of the patient's name
+	DateOfBirth string // YYYY-MM-DD
+	NFCID       string // Exact card ID
+	Cursor      string // next_cursor from the previous page; only valid with the same sort
+	Limit       int    // Items per page; each list has a default and a maximum
+}
+
+// DoctorSearchPatients calls GET /api/doctor/patients/search: search the doctor's patients, ordered by name
+func (c *Client) DoctorSearchPatients(ctx context.Context, params *DoctorSearchPatientsParams) (*UserPage, error) {
+	path := "/api/doctor/patients/search"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Name != "" {
+			query.Set("name", params.Name)
+		}
+		if params.DateOfBirth != "" {
+			query.Set("date_of_birth", params.DateOfBirth)
+		}
+		if params.NFCID != "" {
+			query.Set("nfc_id", params.NFCID)
+		}
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out UserPage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorMatchPatientsParams holds the optional parameters of DoctorMatchPatients; zero values are not sent
+type DoctorMatchPatientsParams struct {
+	DateOfBirth string // Raises the score of patients born that day
+	Limit       int    // Items per page; each list has a default and a maximum
+}
+
+// DoctorMatchPatients calls GET /api/doctor/patients/match: patients whose names are spelled or sound like name, best first
+func (c *Client) DoctorMatchPatients(ctx context.Context, name string, params *DoctorMatchPatientsParams) ([]PatientMatch, error) {
+	path := "/api/doctor/patients/match"
+	query := url.Values{}
+	header := http.Header{}
+	query.Set("name", name)
+	if params != nil {
+		if params.DateOfBirth != "" {
+			query.Set("date_of_birth", params.DateOfBirth)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out []PatientMatch
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DoctorGetPatientProfile calls GET /api/doctor/patients/{patient_id}/clinical-profile: the clinical profile of a patient the doctor is treating
+func (c *Client) DoctorGetPatientProfile(ctx context.Context, patientID string) (*PatientProfile, error) {
+	path := "/api/doctor/patients/" + url.PathEscape(patientID) + "/clinical-profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out PatientProfile
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorSavePatientProfile calls PUT /api/doctor/patients/{patient_id}/clinical-profile: create or replace the clinical profile of a patient the doctor is treating
+func (c *Client) DoctorSavePatientProfile(ctx context.Context, patientID string, body *PatientProfile) (*PatientProfile, error) {
+	path := "/api/doctor/patients/" + url.PathEscape(patientID) + "/clinical-profile"
+	query := url.Values{}
+	header := http.Header{}
+	var out PatientProfile
+	if err := c.do(ctx, http.MethodPut, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorAddAttachment calls POST /api/doctor/attachment: upload a file to a patient's encrypted attachments; the body is the raw file, sent with its own Content-Type
+func (c *Client) DoctorAddAttachment(ctx context.Context, patientID string, fileName string, contentType string, body io.Reader) (*AttachmentCreated, error) {
+	path := "/api/doctor/attachment"
+	query := url.Values{}
+	header := http.Header{}
+	query.Set("patient_id", patientID)
+	query.Set("file_name", fileName)
+	resp, err := c.send(ctx, http.MethodPost, path, query, header, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	var out AttachmentCreated
+	return &out, decode(resp, &out)
+}
+
+// DoctorListEncryptedMedicalHistory calls GET /api/doctor/medical-history/e2e/{patient_id}: a patient's end-to-end entries the doctor can read
+func (c *Client) DoctorListEncryptedMedicalHistory(ctx context.Context, patientID string) ([]EncryptedHistoryEntry, error) {
+	path := "/api/doctor/medical-history/e2e/" + url.PathEscape(patientID)
+	query := url.Values{}
+	header := http.Header{}
+	var out []EncryptedHistoryEntry
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DoctorAddEncryptedMedicalHistory calls POST /api/doctor/medical-history/e2e: store a history entry encrypted on the doctor's device
+func (c *Client) DoctorAddEncryptedMedicalHistory(ctx context.Context, body *AddEncryptedMedicalHistoryRequest) (*HistoryWriteResult, error) {
+	path := "/api/doctor/medical-history/e2e"
+	query := url.Values{}
+	header := http.Header{}
+	var out HistoryWriteResult
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorIssueAccessGrant calls POST /api/doctor/access-grants: let a hospital read the data of a patient on site once
+func (c *Client) DoctorIssueAccessGrant(ctx context.Context, body *DoctorAccessGrantRequest) (*AccessGrant, error) {
+	path := "/api/doctor/access-grants"
+	query := url.Values{}
+	header := http.Header{}
+	var out AccessGrant
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorEstablishCareRelationship calls POST /api/doctor/care-relationships: start or renew treating the patient whose card was just scanned; refused if the patient stopped the doctor
+func (c *Client) DoctorEstablishCareRelationship(ctx context.Context, body *EstablishCareRequest) (*CareRelationship, error) {
+	path := "/api/doctor/care-relationships"
+	query := url.Values{}
+	header := http.Header{}
+	var out CareRelationship
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorListCareRelationships calls GET /api/doctor/care-relationships: the doctor's current patients
+func (c *Client) DoctorListCareRelationships(ctx context.Context) ([]CareRelationship, error) {
+	path := "/api/doctor/care-relationships"
+	query := url.Values{}
+	header := http.Header{}
+	var out []CareRelationship
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DoctorEndCareRelationship calls DELETE /api/doctor/care-relationships/{id}: stop treating a patient
+func (c *Client) DoctorEndCareRelationship(ctx context.Context, id string) (*Message, error) {
+	path := "/api/doctor/care-relationships/" + url.PathEscape(id)
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodDelete, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorPublishPublicKey calls PUT /api/doctor/public-key: publish the doctor's X25519 public key
+func (c *Client) DoctorPublishPublicKey(ctx context.Context, body *PublishPublicKeyRequest) (*Message, error) {
+	path := "/api/doctor/public-key"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPut, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DoctorGetPublicKey calls GET /api/doctor/public-keys/{uid}: another user's public key
+func (c *Client) DoctorGetPublicKey(ctx context.Context, uid string) (*PublicKey, error) {
+	path := "/api/doctor/public-keys/" + url.PathEscape(uid)
+	query := url.Values{}
+	header := http.Header{}
+	var out PublicKey
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PharmacistListActivePrescriptionsParams holds the optional parameters of PharmacistListActivePrescriptions; zero values are not sent
+type PharmacistListActivePrescriptionsParams struct {
+	Cursor string // next_cursor from the previous page; only valid with the same sort
+	Limit  int    // Items per page; each list has a default and a maximum
+	From   string // Only items dated at or after this RFC 3339 time or YYYY-MM-DD date
+	To     string // Only items dated before this RFC 3339 time, or on or before this YYYY-MM-DD date
+	Sort   string // A field the list can be sorted by, prefixed with "-" for descending
+}
+
+// PharmacistListActivePrescriptions calls GET /api/pharmacy/prescriptions/active/{nfc_id}: a patient's active prescriptions; sorts by created_at
+func (c *Client) PharmacistListActivePrescriptions(ctx context.Context, nfcID string, params *PharmacistListActivePrescriptionsParams) (*PrescriptionPage, error) {
+	path := "/api/pharmacy/prescriptions/active/" + url.PathEscape(nfcID)
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.From != "" {
+			query.Set("from", params.From)
+		}
+		if params.To != "" {
+			query.Set("to", params.To)
+		}
+		if params.Sort != "" {
+			query.Set("sort", params.Sort)
+		}
+	}
+	var out PrescriptionPage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PharmacistDispensePrescription calls POST /api/pharmacy/prescription/dispense: dispense a prescription
+func (c *Client) PharmacistDispensePrescription(ctx context.Context, body *DispensePrescriptionRequest) (*Message, error) {
+	path := "/api/pharmacy/prescription/dispense"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalGetPatientData calls GET /api/hospital/patient/{nfc_id}: the consented parts of a patient's record, once per access grant
+func (c *Client) HospitalGetPatientData(ctx context.Context, nfcID string, accessGrant string) (*HospitalPatientData, error) {
+	path := "/api/hospital/patient/" + url.PathEscape(nfcID)
+	query := url.Values{}
+	header := http.Header{}
+	header.Set("X-Access-Grant", accessGrant)
+	var out HospitalPatientData
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalGetEmergencySummaryParams holds the optional parameters of HospitalGetEmergencySummary; zero values are not sent
+type HospitalGetEmergencySummaryParams struct {
+	Format string // text returns only the compact summary, as text/plain
+}
+
+// HospitalGetEmergencySummary calls GET /api/hospital/patient/{nfc_id}/emergency-summary: a patient's emergency summary; needs profile consent or an emergency override
+//
+// The response is decoded as JSON, so leave unset any parameter selecting another representation.
+func (c *Client) HospitalGetEmergencySummary(ctx context.Context, nfcID string, params *HospitalGetEmergencySummaryParams) (*EmergencySummary, error) {
+	path := "/api/hospital/patient/" + url.PathEscape(nfcID) + "/emergency-summary"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Format != "" {
+			query.Set("format", params.Format)
+		}
+	}
+	var out EmergencySummary
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalRequestBreakGlass calls POST /api/hospital/break-glass: open an emergency access request for an unconscious patient
+func (c *Client) HospitalRequestBreakGlass(ctx context.Context, body *OpenBreakGlassRequest) (*BreakGlassRequest, error) {
+	path := "/api/hospital/break-glass"
+	query := url.Values{}
+	header := http.Header{}
+	var out BreakGlassRequest
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalGetBreakGlassShare calls GET /api/hospital/break-glass/{id}/share: collect the custodian's share for an open request, wrapped to their public key
+func (c *Client) HospitalGetBreakGlassShare(ctx context.Context, id string) (*WrappedKey, error) {
+	path := "/api/hospital/break-glass/" + url.PathEscape(id) + "/share"
+	query := url.Values{}
+	header := http.Header{}
+	var out WrappedKey
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalApproveBreakGlass calls POST /api/hospital/break-glass/{id}/approve: approve a request by submitting the custodian's share
+func (c *Client) HospitalApproveBreakGlass(ctx context.Context, id string, body *ApproveBreakGlassRequest) (*BreakGlassRequest, error) {
+	path := "/api/hospital/break-glass/" + url.PathEscape(id) + "/approve"
+	query := url.Values{}
+	header := http.Header{}
+	var out BreakGlassRequest
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HospitalRedeemBreakGlass calls POST /api/hospital/break-glass/{id}/redeem: redeem an approved request for the patient's data
+func (c *Client) HospitalRedeemBreakGlass(ctx context.Context, id string) (*HospitalPatientData, error) {
+	path := "/api/hospital/break-glass/" + url.PathEscape(id) + "/redeem"
+	query := url.Values{}
+	header := http.Header{}
+	var out HospitalPatientData
+	if err := c.do(ctx, http.MethodPost, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdminQueryAuditLogParams holds the optional parameters of AdminQueryAuditLog; zero values are not sent
+type AdminQueryAuditLogParams struct {
+	PatientID string
+	ActorID   string
+	Role      string // Actor role
+	Cursor    string // next_cursor from the previous page; only valid with the same sort
+	Limit     int    // Items per page; each list has a default and a maximum
+	From      string // Only items dated at or after this RFC 3339 time or YYYY-MM-DD date
+	To        string // Only items dated before this RFC 3339 time, or on or before this YYYY-MM-DD date
+	Sort      string // A field the list can be sorted by, prefixed with "-" for descending
+}
+
+// AdminQueryAuditLog calls GET /api/admin/audit-log: search the audit log; sorts by time
+func (c *Client) AdminQueryAuditLog(ctx context.Context, params *AdminQueryAuditLogParams) (*AuditEntryPage, error) {
+	path := "/api/admin/audit-log"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.PatientID != "" {
+			query.Set("patient_id", params.PatientID)
+		}
+		if params.ActorID != "" {
+			query.Set("actor_id", params.ActorID)
+		}
+		if params.Role != "" {
+			query.Set("role", params.Role)
+		}
+		if params.Cursor != "" {
+			query.Set("cursor", params.Cursor)
+		}
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+		if params.From != "" {
+			query.Set("from", params.From)
+		}
+		if params.To != "" {
+			query.Set("to", params.To)
+		}
+		if params.Sort != "" {
+			query.Set("sort", params.Sort)
+		}
+	}
+	var out AuditEntryPage
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdminListDuplicatesParams holds the optional parameters of AdminListDuplicates; zero values are not sent
+type AdminListDuplicatesParams struct {
+	Status string // Defaults to pending
+}
+
+// AdminListDuplicates calls GET /api/admin/duplicates: the duplicate review queue
+func (c *Client) AdminListDuplicates(ctx context.Context, params *AdminListDuplicatesParams) ([]DuplicateCandidate, error) {
+	path := "/api/admin/duplicates"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []DuplicateCandidate
+	if err := c.do(ctx, http.MethodGet, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminScanDuplicatesParams holds the optional parameters of AdminScanDuplicates; zero values are not sent
+type AdminScanDuplicatesParams struct {
+	PatientID string // Only check this patient
+}
+
+// AdminScanDuplicates calls POST /api/admin/duplicates/scan: check one patient, or every patient, for duplicates
+func (c *Client) AdminScanDuplicates(ctx context.Context, params *AdminScanDuplicatesParams) (*ScanResult, error) {
+	path := "/api/admin/duplicates/scan"
+	query := url.Values{}
+	header := http.Header{}
+	if params != nil {
+		if params.PatientID != "" {
+			query.Set("patient_id", params.PatientID)
+		}
+	}
+	var out ScanResult
+	if err := c.do(ctx, http.MethodPost, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdminMergeDuplicate calls POST /api/admin/duplicates/{id}/merge: merge a queued pair into the survivor; refused while the other patient has end-to-end entries not shared with the survivor
+func (c *Client) AdminMergeDuplicate(ctx context.Context, id string, body *MergeDuplicateRequest) (*PatientMerge, error) {
+	path := "/api/admin/duplicates/" + url.PathEscape(id) + "/merge"
+	query := url.Values{}
+	header := http.Header{}
+	var out PatientMerge
+	if err := c.do(ctx, http.MethodPost, path, query, header, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdminDismissDuplicate calls POST /api/admin/duplicates/{id}/dismiss: dismiss a queued pair
+func (c *Client) AdminDismissDuplicate(ctx context.Context, id string) (*Message, error) {
+	path := "/api/admin/duplicates/" + url.PathEscape(id) + "/dismiss"
+	query := url.Values{}
+	header := http.Header{}
+	var out Message
+	if err := c.do(ctx, http.MethodPost, path, query, header, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,127 @@
+// Package client calls the HippoCard API. The request and response types and
+// one method per endpoint are generated from api/routes/openapi.json into
+// client.gen.go; run go generate after changing the document.
+//
+//	c := client.New("https://api.example.com", idToken)
+//	patient, err := c.DoctorGetPatient(ctx, nfcID)
+//
+// Failed requests return a *Problem, so callers can check its Code or Status.
+package client
+
+//go:generate go run ../../cmd/openapiclient -spec ../../api/routes/openapi.json -out client.gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the API as one user
+type Client struct {
+	BaseURL    string       // Scheme and host, without a trailing slash
+	HTTPClient *http.Client // http.DefaultClient if nil
+	Token      string       // Firebase ID token sent as a bearer token, if set
+	Header     http.Header  // Sent with every request, e.g. X-Access-Purpose
+}
+
+// New creates a Client for the API at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   token,
+		Header:  make(http.Header),
+	}
+}
+
+// Error describes a problem returned by the API
+func (p *Problem) Error() string {
+	if p.RequestID != "" {
+		return fmt.Sprintf("%d %s: %s (request %s)", p.Status, p.Title, p.Detail, p.RequestID)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// do sends body as JSON, if it is not nil, and decodes the response into out,
+// if it is not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, out any) error {
+	var reader io.Reader
+	var contentType string
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+	resp, err := c.send(ctx, method, path, query, header, contentType, reader)
+	if err != nil {
+		return err
+	}
+	return decode(resp, out)
+}
+
+// send makes a request and returns the response if it succeeded, or its
+// Problem if it did not
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	// Anything but a problem, e.g. from a proxy, is reported by its status
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	problem := &Problem{Status: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+	if err := json.Unmarshal(data, problem); err != nil || problem.Detail == "" {
+		problem.Detail = strings.TrimSpace(string(data))
+	}
+	problem.Status = resp.StatusCode
+	return nil, problem
+}
+
+// decode reads a JSON response into out and closes it
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if out == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Join(errors.New("decoding response"), err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Public-key wrapping of data keys for client-side (end-to-end) encryption
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+)
+
+const wrapInfo = "hippocard x25519 key wrap v1"
+
+// In end-to-end mode the client encrypts content with a random data key using
+// Encrypt, then wraps that key for each reader with WrapKey. The server only ever
+// sees ciphertext, wrapped keys and public keys; it never calls UnwrapKey.
+
+// ParsePublicKey validates an X25519 public key
+func ParsePublicKey(raw []byte) (*ecdh.PublicKey, error) {
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateKeyPair creates an X25519 key pair (for clients and testing)
+func GenerateKeyPair() (privateKey, publicKey []byte, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Printf("Failed to generate X25519 key: %v", err)
+		return nil, nil, err
+	}
+	return priv.Bytes(), priv.PublicKey().Bytes(), nil
+}
+
+// WrapKey encrypts dataKey to a recipient's public key using an ephemeral X25519
+// exchange, returning the ephemeral public key and the sealed data key
+func WrapKey(recipientPublicKey, dataKey []byte) (ephemeralKey, wrapped []byte, err error) {
+	recipient, err := ParsePublicKey(recipientPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Printf("Failed to generate ephemeral key: %v", err)
+		return nil, nil, err
+	}
+
+	kek, err := deriveWrapKey(ephemeral, recipient, ephemeral.PublicKey().Bytes(), recipientPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err = Encrypt(dataKey, kek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ephemeral.PublicKey().Bytes(), wrapped, nil
+}
+
+// UnwrapKey recovers a data key with the recipient's private key (client side)
+func UnwrapKey(recipientPrivateKey, ephemeralKey, wrapped []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(recipientPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+	ephemeral, err := ParsePublicKey(ephemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := deriveWrapKey(priv, ephemeral, ephemeralKey, priv.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(wrapped, kek)
+}
+
+// deriveWrapKey binds the shared secret to both public keys via HKDF
+func deriveWrapKey(priv *ecdh.PrivateKey, peer *ecdh.PublicKey, ephemeralKey, recipientKey []byte) ([]byte, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		log.Printf("Failed to compute X25519 shared secret: %v", err)
+		return nil, err
+	}
+	salt := append(append([]byte{}, ephemeralKey...), recipientKey...)
+	return hkdf.Key(sha256.New, shared, salt, wrapInfo, 32)
+}
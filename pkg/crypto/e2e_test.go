@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrapKeyRoundTrip(t *testing.T) {
+	priv, pub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	dataKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ephemeral, wrapped, err := WrapKey(pub, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey: %v", err)
+	}
+	got, err := UnwrapKey(priv, ephemeral, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Fatal("unwrapped key differs from the original")
+	}
+
+	// Each wrap uses a fresh ephemeral key
+	again, rewrapped, err := WrapKey(pub, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey: %v", err)
+	}
+	if bytes.Equal(again, ephemeral) || bytes.Equal(rewrapped, wrapped) {
+		t.Error("wrapping the same key twice gave the same output")
+	}
+}
+
+func TestUnwrapKeyRejectsTampering(t *testing.T) {
+	priv, pub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	otherPriv, _, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	_, otherPub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	dataKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	ephemeral, wrapped, err := WrapKey(pub, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey: %v", err)
+	}
+
+	flip := func(data []byte, i int) []byte {
+		out := append([]byte(nil), data...)
+		out[i] ^= 0x01
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		priv      []byte
+		ephemeral []byte
+		wrapped   []byte
+	}{
+		{"wrong recipient", otherPriv, ephemeral, wrapped},
+		{"other ephemeral key", priv, otherPub, wrapped},
+		{"ephemeral key flipped", priv, flip(ephemeral, 0), wrapped},
+		{"wrapped key flipped", priv, ephemeral, flip(wrapped, len(wrapped)-1)},
+		{"wrapped key truncated", priv, ephemeral, wrapped[:len(wrapped)-1]},
+		{"ephemeral key truncated", priv, ephemeral[:31], wrapped},
+		{"private key truncated", priv[:31], ephemeral, wrapped},
+		{"low-order ephemeral key", priv, make([]byte, 32), wrapped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnwrapKey(tt.priv, tt.ephemeral, tt.wrapped); err == nil {
+				t.Error("UnwrapKey succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, pub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr bool
+	}{
+		{"valid", pub, false},
+		{"empty", nil, true},
+		{"short", pub[:31], true},
+		{"long", append(append([]byte(nil), pub...), 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePublicKey(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePublicKey error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, _, err := WrapKey(pub[:31], []byte("key")); err == nil {
+		t.Error("WrapKey accepted a short public key")
+	}
+}
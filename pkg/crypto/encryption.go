@@ -6,7 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 
-	//"encoding/base64"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"log"
@@ -81,9 +81,24 @@ func Decrypt(ciphertext []byte, key []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// KeySize is the length of the AES-256 keys Encrypt and Decrypt take
+const KeySize = 32
+
+// ParseKey decodes a base64 AES-256 key, as set in DATA_ENCRYPTION_KEY
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
 // GenerateKey generates a 32-byte AES key (for testing or initial setup)
 func GenerateKey() ([]byte, error) {
-	key := make([]byte, 32) // AES-256 requires a 32-byte key
+	key := make([]byte, KeySize) // AES-256 requires a 32-byte key
 	if _, err := io.ReadFull(rand.Reader, key); err != nil {
 		log.Printf("Failed to generate key: %v", err)
 		return nil, err
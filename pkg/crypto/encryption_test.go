@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range [][]byte{{}, []byte("a"), bytes.Repeat([]byte("history "), 1000)} {
+		ciphertext, err := Encrypt(plaintext, key)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		got, err := Decrypt(ciphertext, key)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("Decrypt = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key, _ := GenerateKey()
+	ciphertext, _ := Encrypt([]byte("blood type O-"), key)
+	other, _ := GenerateKey()
+
+	flipped := bytes.Clone(ciphertext)
+	flipped[len(flipped)-1] ^= 1
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		key        []byte
+	}{
+		{"flipped bit", flipped, key},
+		{"truncated", ciphertext[:len(ciphertext)-1], key},
+		{"shorter than a nonce", ciphertext[:4], key},
+		{"wrong key", ciphertext, other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.ciphertext, tt.key); err == nil {
+				t.Error("Decrypt succeeded")
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key, _ := GenerateKey()
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"32 bytes", base64.StdEncoding.EncodeToString(key), false},
+		{"empty", "", true},
+		{"not base64", "not a key!", true},
+		{"33 bytes", base64.StdEncoding.EncodeToString([]byte("32-byte-key-here-1234567890123456")), true},
+		{"16 bytes", base64.StdEncoding.EncodeToString(key[:16]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, key) {
+				t.Errorf("ParseKey = %x, want %x", got, key)
+			}
+		})
+	}
+}
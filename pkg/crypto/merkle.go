@@ -0,0 +1,100 @@
+// Merkle trees for anchoring audit log batches
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Leaves and inner nodes are hashed with different prefixes so a leaf can never
+// be passed off as a node. A node without a sibling is promoted unchanged rather
+// than paired with itself, so no two leaf lists share a root.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleStep is one sibling on the path from a leaf to the root
+type MerkleStep struct {
+	Hash []byte `json:"hash"`
+	Left bool   `json:"left"` // Sibling sits to the left of the running hash
+}
+
+// MerkleRoot returns the root of the tree over leaves, in order
+func MerkleRoot(leaves [][]byte) ([]byte, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("cannot build a Merkle tree without leaves")
+	}
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = merkleLeaf(leaf)
+	}
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0], nil
+}
+
+// MerkleProof returns the siblings needed to rebuild the root from leaves[index]
+func MerkleProof(leaves [][]byte, index int) ([]MerkleStep, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range for %d leaves", index, len(leaves))
+	}
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = merkleLeaf(leaf)
+	}
+
+	var proof []MerkleStep
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, MerkleStep{Hash: level[sibling], Left: sibling < index})
+		}
+		level = merkleLevel(level)
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof reports whether leaf and proof rebuild root
+func VerifyMerkleProof(leaf []byte, proof []MerkleStep, root []byte) bool {
+	hash := merkleLeaf(leaf)
+	for _, step := range proof {
+		if step.Left {
+			hash = merkleNode(step.Hash, hash)
+		} else {
+			hash = merkleNode(hash, step.Hash)
+		}
+	}
+	return bytes.Equal(hash, root)
+}
+
+// merkleLevel hashes adjacent pairs, promoting an odd node out
+func merkleLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, merkleNode(level[i], level[i+1]))
+	}
+	return next
+}
+
+func merkleLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+// testLeaves returns n distinct leaves
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("entry %d", i)))
+		leaves[i] = sum[:]
+	}
+	return leaves
+}
+
+func TestMerkleRoot(t *testing.T) {
+	leaves := testLeaves(3)
+	leaf := func(i int) []byte {
+		return sha256Of(append([]byte{0x00}, leaves[i]...))
+	}
+	node := func(left, right []byte) []byte {
+		return sha256Of(append(append([]byte{0x01}, left...), right...))
+	}
+
+	tests := []struct {
+		name   string
+		leaves [][]byte
+		want   []byte
+	}{
+		{"one leaf", leaves[:1], leaf(0)},
+		{"two leaves", leaves[:2], node(leaf(0), leaf(1))},
+		{"odd leaf promoted", leaves, node(node(leaf(0), leaf(1)), leaf(2))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MerkleRoot(tt.leaves)
+			if err != nil {
+				t.Fatalf("MerkleRoot: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("root = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := MerkleRoot(nil); err == nil {
+		t.Error("MerkleRoot accepted no leaves")
+	}
+}
+
+func TestMerkleRootDistinguishesLeafLists(t *testing.T) {
+	leaves := testLeaves(4)
+	pair, _ := MerkleRoot(leaves[:2])
+
+	tests := []struct {
+		name string
+		a, b [][]byte
+	}{
+		// Pairing an odd node with itself would make these collide
+		{"duplicated last leaf", leaves[:3], [][]byte{leaves[0], leaves[1], leaves[2], leaves[2]}},
+		{"reordered", leaves[:2], [][]byte{leaves[1], leaves[0]}},
+		// Domain separation keeps an inner node from passing as a leaf
+		{"inner node as a leaf", leaves[:2], [][]byte{pair}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := MerkleRoot(tt.a)
+			b, _ := MerkleRoot(tt.b)
+			if bytes.Equal(a, b) {
+				t.Error("different leaf lists share a root")
+			}
+		})
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root, err := MerkleRoot(leaves)
+		if err != nil {
+			t.Fatalf("MerkleRoot(%d leaves): %v", n, err)
+		}
+		for i := range leaves {
+			proof, err := MerkleProof(leaves, i)
+			if err != nil {
+				t.Fatalf("MerkleProof(%d of %d): %v", i, n, err)
+			}
+			if !VerifyMerkleProof(leaves[i], proof, root) {
+				t.Errorf("proof for leaf %d of %d does not verify", i, n)
+			}
+		}
+	}
+}
+
+func TestMerkleProofRejectsTampering(t *testing.T) {
+	leaves := testLeaves(5)
+	root, _ := MerkleRoot(leaves)
+	proof, err := MerkleProof(leaves, 2)
+	if err != nil {
+		t.Fatalf("MerkleProof: %v", err)
+	}
+
+	flipped := func(data []byte) []byte {
+		out := append([]byte(nil), data...)
+		out[0] ^= 0x01
+		return out
+	}
+	withStep := func(i int, step MerkleStep) []MerkleStep {
+		out := append([]MerkleStep(nil), proof...)
+		out[i] = step
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		leaf  []byte
+		proof []MerkleStep
+		root  []byte
+	}{
+		{"other leaf", leaves[3], proof, root},
+		{"leaf flipped", flipped(leaves[2]), proof, root},
+		{"sibling flipped", leaves[2], withStep(0, MerkleStep{Hash: flipped(proof[0].Hash), Left: proof[0].Left}), root},
+		{"side swapped", leaves[2], withStep(0, MerkleStep{Hash: proof[0].Hash, Left: !proof[0].Left}), root},
+		{"step dropped", leaves[2], proof[:len(proof)-1], root},
+		{"step added", leaves[2], append(append([]MerkleStep(nil), proof...), proof[0]), root},
+		{"root flipped", leaves[2], proof, flipped(root)},
+		{"no proof", leaves[2], nil, root},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyMerkleProof(tt.leaf, tt.proof, tt.root) {
+				t.Error("tampered proof verified")
+			}
+		})
+	}
+
+	for _, index := range []int{-1, 5} {
+		if _, err := MerkleProof(leaves, index); err == nil {
+			t.Errorf("MerkleProof accepted index %d of 5", index)
+		}
+	}
+}
+
+func sha256Of(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
@@ -0,0 +1,177 @@
+// Shamir secret sharing for emergency key escrow
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+)
+
+// Shares are the secret's bytes evaluated on random polynomials over GF(2^8),
+// followed by a single byte holding the x-coordinate of the share.
+
+// SplitSecret divides secret into n shares, any threshold of which can rebuild it
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("cannot split an empty secret")
+	}
+	if threshold < 2 || n < threshold || n > 255 {
+		return nil, fmt.Errorf("invalid share parameters: need 2 <= threshold <= n <= 255, got threshold %d, n %d", threshold, n)
+	}
+
+	// Distinct non-zero x-coordinates, shuffled so share order reveals nothing
+	xs := make([]byte, 255)
+	for i := range xs {
+		xs[i] = byte(i + 1)
+	}
+	if err := shuffle(xs); err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = xs[i]
+	}
+
+	coefficients := make([]byte, threshold)
+	for b, s := range secret {
+		coefficients[0] = s
+		if _, err := io.ReadFull(rand.Reader, coefficients[1:]); err != nil {
+			log.Printf("Failed to generate polynomial coefficients: %v", err)
+			return nil, err
+		}
+		for i := range shares {
+			shares[i][b] = evaluate(coefficients, xs[i])
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret from at least threshold shares. With fewer
+// shares the result is a random value rather than an error, so callers should
+// check it against a stored fingerprint.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("at least two shares are required")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, fmt.Errorf("share too short")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, fmt.Errorf("shares have inconsistent lengths")
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("invalid or duplicate share")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for b := range secret {
+		for i, share := range shares {
+			ys[i] = share[b]
+		}
+		secret[b] = interpolateAtZero(xs, ys)
+	}
+	return secret, nil
+}
+
+// Fingerprint returns a hex SHA-256 digest, used to recognise shares and keys
+// without storing them
+func Fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FingerprintMatches checks data against a stored fingerprint in constant time
+func FingerprintMatches(data []byte, fingerprint string) bool {
+	return subtle.ConstantTimeCompare([]byte(Fingerprint(data)), []byte(fingerprint)) == 1
+}
+
+// evaluate computes the polynomial at x using Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfAdd(gfMul(result, x), coefficients[i])
+	}
+	return result
+}
+
+// interpolateAtZero applies Lagrange interpolation to recover the constant term
+func interpolateAtZero(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfDiv(xs[j], gfAdd(xs[i], xs[j])))
+		}
+		result = gfAdd(result, gfMul(ys[i], basis))
+	}
+	return result
+}
+
+// shuffle performs a Fisher-Yates shuffle with crypto/rand
+func shuffle(values []byte) error {
+	buf := make([]byte, 1)
+	for i := len(values) - 1; i > 0; i-- {
+		// Rejection sampling keeps the draw uniform over [0, i]
+		limit := 256 - 256%(i+1)
+		for {
+			if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+				log.Printf("Failed to shuffle share coordinates: %v", err)
+				return err
+			}
+			if int(buf[0]) < limit {
+				break
+			}
+		}
+		j := int(buf[0]) % (i + 1)
+		values[i], values[j] = values[j], values[i]
+	}
+	return nil
+}
+
+// GF(2^8) arithmetic with the AES reduction polynomial x^8 + x^4 + x^3 + x + 1
+
+func gfAdd(a, b byte) byte {
+	return a ^ b
+}
+
+// gfMul multiplies without table lookups so timing does not depend on the operands
+func gfMul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		mask := -(b & 1)
+		product ^= a & mask
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+		b >>= 1
+	}
+	return product
+}
+
+// gfDiv computes a / b as a * b^254, since b^255 = 1 for non-zero b
+func gfDiv(a, b byte) byte {
+	inverse := byte(1)
+	for i := 0; i < 254; i++ {
+		inverse = gfMul(inverse, b)
+	}
+	return gfMul(a, inverse)
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShamirRoundTrip(t *testing.T) {
+	secret, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		n, threshold int
+		use          []int // Indexes of the shares to combine
+	}{
+		{"2 of 2", 2, 2, []int{0, 1}},
+		{"2 of 3, first pair", 3, 2, []int{0, 1}},
+		{"2 of 3, last pair", 3, 2, []int{1, 2}},
+		{"2 of 3, all shares", 3, 2, []int{0, 1, 2}},
+		{"3 of 5, out of order", 5, 3, []int{4, 0, 2}},
+		{"5 of 5", 5, 5, []int{0, 1, 2, 3, 4}},
+		{"255 shares", 255, 3, []int{254, 100, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := SplitSecret(secret, tt.n, tt.threshold)
+			if err != nil {
+				t.Fatalf("SplitSecret: %v", err)
+			}
+			if len(shares) != tt.n {
+				t.Fatalf("got %d shares, want %d", len(shares), tt.n)
+			}
+			for _, share := range shares {
+				if len(share) != len(secret)+1 {
+					t.Fatalf("share is %d bytes, want %d", len(share), len(secret)+1)
+				}
+			}
+
+			subset := make([][]byte, len(tt.use))
+			for i, index := range tt.use {
+				subset[i] = shares[index]
+			}
+			got, err := CombineShares(subset)
+			if err != nil {
+				t.Fatalf("CombineShares: %v", err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Fatal("combined secret differs from the original")
+			}
+			if !FingerprintMatches(got, Fingerprint(secret)) {
+				t.Error("fingerprint of the combined secret does not match")
+			}
+		})
+	}
+}
+
+func TestShamirBelowThreshold(t *testing.T) {
+	secret, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("SplitSecret: %v", err)
+	}
+
+	// Two shares of a 3-of-5 split combine to an unrelated value, which the
+	// stored fingerprint rejects
+	got, err := CombineShares(shares[:2])
+	if err != nil {
+		t.Fatalf("CombineShares: %v", err)
+	}
+	if FingerprintMatches(got, Fingerprint(secret)) {
+		t.Error("two shares of a 3-of-5 split rebuilt the secret")
+	}
+}
+
+func TestSplitSecretRejectsBadParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret       []byte
+		n, threshold int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold of one", []byte("secret"), 3, 1},
+		{"threshold above n", []byte("secret"), 2, 3},
+		{"too many shares", []byte("secret"), 256, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SplitSecret(tt.secret, tt.n, tt.threshold); err == nil {
+				t.Error("SplitSecret succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCombineSharesRejectsMalformedShares(t *testing.T) {
+	shares, err := SplitSecret([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatalf("SplitSecret: %v", err)
+	}
+	zeroX := append([]byte(nil), shares[1]...)
+	zeroX[len(zeroX)-1] = 0
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"no shares", nil},
+		{"one share", shares[:1]},
+		{"share too short", [][]byte{{1}, {2}}},
+		{"inconsistent lengths", [][]byte{shares[0], shares[1][1:]}},
+		{"duplicate share", [][]byte{shares[0], shares[0]}},
+		{"zero x-coordinate", [][]byte{shares[0], zeroX}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CombineShares(tt.shares); err == nil {
+				t.Error("CombineShares succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestGFArithmetic(t *testing.T) {
+	// Known products in the AES field, from FIPS 197 section 4.2
+	tests := []struct {
+		a, b, want byte
+	}{
+		{0x57, 0x83, 0xc1},
+		{0x57, 0x13, 0xfe},
+		{0x53, 0xca, 0x01},
+		{0x00, 0x9d, 0x00},
+		{0x01, 0x9d, 0x9d},
+	}
+	for _, tt := range tests {
+		if got := gfMul(tt.a, tt.b); got != tt.want {
+			t.Errorf("gfMul(%#x, %#x) = %#x, want %#x", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for b := 1; b < 256; b++ {
+		if got := gfMul(gfDiv(1, byte(b)), byte(b)); got != 1 {
+			t.Fatalf("%#x * (1 / %#x) = %#x, want 1", b, b, got)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Chunked streaming encryption for large attachments
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+const (
+	streamVersion     = 1
+	streamSaltSize    = 16
+	streamSegmentSize = 64 * 1024 // Plaintext bytes per segment
+	streamInfo        = "hippocard stream v1"
+	streamOverhead    = 16 // AES-GCM tag per segment
+)
+
+// ErrStreamTruncated is returned when a stream ends before its final segment
+var ErrStreamTruncated = errors.New("encrypted stream truncated")
+
+// NewEncryptReader returns a reader yielding the STREAM-style encryption of src.
+// The plaintext is split into fixed-size segments, each sealed with AES-GCM under a
+// per-stream subkey; the nonce carries the segment counter and a last-segment flag,
+// so reordering, truncation and extension are all detected on decryption.
+func NewEncryptReader(src io.Reader, key []byte) (io.Reader, error) {
+	salt := make([]byte, streamSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		log.Printf("Failed to generate stream salt: %v", err)
+		return nil, err
+	}
+
+	gcm, err := newStreamAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{streamVersion}, salt...)
+	return &streamEncrypter{
+		src:     src,
+		aead:    gcm,
+		out:     header,
+		segment: make([]byte, streamSegmentSize+1),
+	}, nil
+}
+
+// NewDecryptReader returns a reader yielding the plaintext of a stream produced by
+// NewEncryptReader. Data is only released after its segment has been authenticated.
+func NewDecryptReader(src io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, 1+streamSaltSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		log.Printf("Failed to read stream header: %v", err)
+		return nil, ErrStreamTruncated
+	}
+	if header[0] != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version %d", header[0])
+	}
+
+	gcm, err := newStreamAEAD(key, header[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamDecrypter{
+		src:     src,
+		aead:    gcm,
+		segment: make([]byte, streamSegmentSize+gcm.Overhead()+1),
+		plain:   make([]byte, 0, streamSegmentSize),
+	}, nil
+}
+
+// NewDecryptReaderKeys is NewDecryptReader for a stream sealed with one of several
+// keys, such as while records move from one key to another. The first segment is
+// opened with each key in turn and the first key that authenticates it is kept.
+func NewDecryptReaderKeys(src io.Reader, keys ...[]byte) (io.Reader, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no stream keys")
+	}
+	header := make([]byte, 1+streamSaltSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		log.Printf("Failed to read stream header: %v", err)
+		return nil, ErrStreamTruncated
+	}
+	if header[0] != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version %d", header[0])
+	}
+
+	// Buffer the first segment (and its lookahead byte) so it can be retried
+	first := make([]byte, streamSegmentSize+streamOverhead+1)
+	n, err := io.ReadFull(src, first)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		log.Printf("Failed to read encrypted stream: %v", err)
+		return nil, err
+	}
+	first = first[:n]
+
+	for _, key := range keys {
+		gcm, err := newStreamAEAD(key, header[1:])
+		if err != nil {
+			return nil, err
+		}
+		d := &streamDecrypter{
+			src:     io.MultiReader(bytes.NewReader(first), src),
+			aead:    gcm,
+			segment: make([]byte, streamSegmentSize+gcm.Overhead()+1),
+			plain:   make([]byte, 0, streamSegmentSize),
+		}
+		if err = d.openNext(); err == nil {
+			return d, nil
+		}
+		if err == ErrStreamTruncated {
+			return nil, err
+		}
+	}
+	return nil, errors.New("no key opens the encrypted stream")
+}
+
+// newStreamAEAD derives the per-stream subkey from the data key and salt
+func newStreamAEAD(key, salt []byte) (cipher.AEAD, error) {
+	subkey, err := hkdf.Key(sha256.New, key, salt, streamInfo, 32)
+	if err != nil {
+		log.Printf("Failed to derive stream key: %v", err)
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		log.Printf("Failed to create AES cipher: %v", err)
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Printf("Failed to create GCM: %v", err)
+		return nil, err
+	}
+	return gcm, nil
+}
+
+// streamNonce builds the segment nonce: 7 zero bytes, 4-byte counter, last flag
+func streamNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+type streamEncrypter struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	out     []byte // Pending ciphertext not yet returned to the caller
+	sealed  []byte
+	segment []byte // Plaintext buffer with one byte of lookahead
+	pending int    // Lookahead bytes carried over from the previous fill
+	counter uint32
+	done    bool
+}
+
+func (e *streamEncrypter) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// sealNext reads one segment plus a lookahead byte to learn whether it is the last
+func (e *streamEncrypter) sealNext() error {
+	n, err := io.ReadFull(e.src, e.segment[e.pending:])
+	n += e.pending
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		log.Printf("Failed to read plaintext stream: %v", err)
+		return err
+	}
+
+	last := n <= streamSegmentSize
+	size := n
+	if !last {
+		size = streamSegmentSize
+	}
+	if e.counter == ^uint32(0) && !last {
+		return errors.New("encrypted stream too long")
+	}
+
+	e.sealed = e.aead.Seal(e.sealed[:0], streamNonce(e.counter, last), e.segment[:size], nil)
+	e.out = e.sealed
+	e.counter++
+
+	if last {
+		e.done = true
+		e.pending = 0
+	} else {
+		e.segment[0] = e.segment[streamSegmentSize]
+		e.pending = 1
+	}
+	return nil
+}
+
+type streamDecrypter struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	out     []byte // Authenticated plaintext not yet returned to the caller
+	segment []byte // Ciphertext buffer with one byte of lookahead
+	plain   []byte
+	pending int
+	counter uint32
+	done    bool
+}
+
+func (d *streamDecrypter) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.openNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *streamDecrypter) openNext() error {
+	n, err := io.ReadFull(d.src, d.segment[d.pending:])
+	n += d.pending
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		log.Printf("Failed to read encrypted stream: %v", err)
+		return err
+	}
+
+	sealedSize := streamSegmentSize + d.aead.Overhead()
+	last := n <= sealedSize
+	size := n
+	if !last {
+		size = sealedSize
+	}
+	if size < d.aead.Overhead() {
+		return ErrStreamTruncated
+	}
+
+	plaintext, err := d.aead.Open(d.plain[:0], streamNonce(d.counter, last), d.segment[:size], nil)
+	if err != nil {
+		log.Printf("Failed to decrypt stream segment %d: %v", d.counter, err)
+		return err
+	}
+	d.out = plaintext
+	d.counter++
+
+	if last {
+		d.done = true
+		d.pending = 0
+	} else {
+		d.segment[0] = d.segment[sealedSize]
+		d.pending = 1
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// sealedSegment is the size of one full encrypted segment
+const sealedSegment = streamSegmentSize + 16
+
+func encryptStream(t *testing.T, plaintext, key []byte) []byte {
+	t.Helper()
+	reader, err := NewEncryptReader(bytes.NewReader(plaintext), key)
+	if err != nil {
+		t.Fatalf("NewEncryptReader: %v", err)
+	}
+	ciphertext, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	return ciphertext
+}
+
+func decryptStream(ciphertext, key []byte) ([]byte, error) {
+	reader, err := NewDecryptReader(bytes.NewReader(ciphertext), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	key, _ := GenerateKey()
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"one byte", 1},
+		{"just under a segment", streamSegmentSize - 1},
+		{"one segment", streamSegmentSize},
+		{"just over a segment", streamSegmentSize + 1},
+		{"several segments", 3*streamSegmentSize + 17},
+		{"exact segments", 2 * streamSegmentSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := make([]byte, tt.size)
+			rand.Read(plaintext)
+
+			ciphertext := encryptStream(t, plaintext, key)
+			// A full final segment is marked last; only empty input gets an empty one
+			segments := max(1, (tt.size+streamSegmentSize-1)/streamSegmentSize)
+			if want := 1 + streamSaltSize + tt.size + 16*segments; len(ciphertext) != want {
+				t.Errorf("ciphertext is %d bytes, want %d", len(ciphertext), want)
+			}
+
+			got, err := decryptStream(ciphertext, key)
+			if err != nil {
+				t.Fatalf("decrypting: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Error("decrypted stream differs from the plaintext")
+			}
+		})
+	}
+}
+
+func TestStreamSmallReads(t *testing.T) {
+	key, _ := GenerateKey()
+	plaintext := make([]byte, streamSegmentSize+100)
+	rand.Read(plaintext)
+
+	// Sources and callers that move a byte at a time see the same bytes
+	reader, err := NewEncryptReader(iotest.OneByteReader(bytes.NewReader(plaintext)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := io.ReadAll(iotest.OneByteReader(reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypter, err := NewDecryptReader(iotest.OneByteReader(bytes.NewReader(ciphertext)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iotest.TestReader(decrypter, plaintext); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	key, _ := GenerateKey()
+	plaintext := make([]byte, 3*streamSegmentSize+17)
+	rand.Read(plaintext)
+	ciphertext := encryptStream(t, plaintext, key)
+	header := 1 + streamSaltSize
+	other, _ := GenerateKey()
+
+	flip := func(i int) []byte {
+		c := bytes.Clone(ciphertext)
+		c[i] ^= 0x80
+		return c
+	}
+	swapped := bytes.Clone(ciphertext)
+	first := swapped[header : header+sealedSegment]
+	second := swapped[header+sealedSegment : header+2*sealedSegment]
+	tmp := bytes.Clone(first)
+	copy(first, second)
+	copy(second, tmp)
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		key        []byte
+	}{
+		{"wrong key", ciphertext, other},
+		{"unknown version", flip(0), key},
+		{"salt flipped", flip(1), key},
+		{"first segment flipped", flip(header + 10), key},
+		{"last segment flipped", flip(len(ciphertext) - 1), key},
+		{"segments reordered", swapped, key},
+		{"truncated header", ciphertext[:header-1], key},
+		{"header only", ciphertext[:header], key},
+		{"truncated mid-segment", ciphertext[:header+100], key},
+		{"truncated at a segment boundary", ciphertext[:header+2*sealedSegment], key},
+		{"last byte dropped", ciphertext[:len(ciphertext)-1], key},
+		{"extended", append(bytes.Clone(ciphertext), 0), key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decryptStream(tt.ciphertext, tt.key); err == nil {
+				t.Error("decrypting succeeded")
+			}
+		})
+	}
+}
+
+func TestStreamReleasesOnlyAuthenticatedData(t *testing.T) {
+	key, _ := GenerateKey()
+	plaintext := make([]byte, 2*streamSegmentSize+5)
+	ciphertext := encryptStream(t, plaintext, key)
+	ciphertext[len(ciphertext)-1] ^= 1
+
+	// Segments before the tampered one are released; nothing after it is
+	got, err := decryptStream(ciphertext, key)
+	if err == nil {
+		t.Fatal("decrypting succeeded")
+	}
+	if len(got) != 2*streamSegmentSize {
+		t.Errorf("released %d bytes, want the %d bytes of the intact segments", len(got), 2*streamSegmentSize)
+	}
+}
+
+func TestDecryptReaderKeys(t *testing.T) {
+	key, _ := GenerateKey()
+	old, _ := GenerateKey()
+	other, _ := GenerateKey()
+	for _, size := range []int{0, 100, 2*streamSegmentSize + 5} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		ciphertext := encryptStream(t, plaintext, old)
+
+		// The stream opens under whichever key sealed it, wherever it is in the list
+		reader, err := NewDecryptReaderKeys(iotest.OneByteReader(bytes.NewReader(ciphertext)), key, old)
+		if err != nil {
+			t.Fatalf("NewDecryptReaderKeys with %d bytes: %v", size, err)
+		}
+		if err := iotest.TestReader(reader, plaintext); err != nil {
+			t.Errorf("%d bytes: %v", size, err)
+		}
+
+		if _, err := NewDecryptReaderKeys(bytes.NewReader(ciphertext), key, other); err == nil {
+			t.Errorf("%d bytes opened without the key that sealed them", size)
+		}
+	}
+
+	// Tampering after the first segment still surfaces on Read
+	ciphertext := encryptStream(t, make([]byte, 2*streamSegmentSize+5), old)
+	ciphertext[len(ciphertext)-1] ^= 1
+	reader, err := NewDecryptReaderKeys(bytes.NewReader(ciphertext), key, old)
+	if err != nil {
+		t.Fatalf("NewDecryptReaderKeys: %v", err)
+	}
+	if _, err := io.ReadAll(reader); err == nil {
+		t.Error("reading a tampered stream succeeded")
+	}
+	if _, err := NewDecryptReaderKeys(bytes.NewReader(ciphertext[:10]), key); err != ErrStreamTruncated {
+		t.Errorf("truncated header = %v, want ErrStreamTruncated", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Package errs defines the kinds of failure the server reports to clients. A
+// service marks an error with its kind and a message safe to show; the API's
+// error handler turns the kind into a status code and never shows anything else.
+package errs
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kinds of failure, matched with errors.Is
+var (
+	ErrInvalid      = errors.New("invalid request")         // The request is malformed or breaks a rule
+	ErrUnauthorized = errors.New("unauthorized")            // The caller is not authenticated
+	ErrForbidden    = errors.New("forbidden")               // The caller may not do this
+	ErrNotFound     = errors.New("not found")               // The thing asked for does not exist
+	ErrConflict     = errors.New("conflict")                // The request clashes with the current state
+	ErrUpstream     = errors.New("upstream service failed") // Firestore, storage, the chain or auth failed
+)
+
+// Error is a failure of a given kind. Detail and Fields are shown to clients;
+// Cause, if any, is only logged.
+type Error struct {
+	Kind   error
+	Detail string
+	Fields []FieldError // What is wrong with each invalid field of a request
+	Cause  error
+}
+
+// FieldError says what is wrong with one field of a request
+type FieldError struct {
+	Field   string `json:"field"` // Dotted JSON path, e.g. "wrapped_key.ciphertext"
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Detail + ": " + e.Cause.Error()
+	}
+	return e.Detail
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// Invalid reports a malformed request or one that breaks a rule
+func Invalid(detail string) error {
+	return &Error{Kind: ErrInvalid, Detail: detail}
+}
+
+// InvalidFields reports a request whose fields break their rules, one
+// FieldError per broken rule
+func InvalidFields(fields ...FieldError) error {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Field + " " + f.Message
+	}
+	return &Error{Kind: ErrInvalid, Detail: strings.Join(messages, "; "), Fields: fields}
+}
+
+// Unauthorized reports a missing or unverifiable identity
+func Unauthorized(detail string) error {
+	return &Error{Kind: ErrUnauthorized, Detail: detail}
+}
+
+// Forbidden reports an action the caller is not allowed to take
+func Forbidden(detail string) error {
+	return &Error{Kind: ErrForbidden, Detail: detail}
+}
+
+// NotFound reports that the thing asked for does not exist
+func NotFound(detail string) error {
+	return &Error{Kind: ErrNotFound, Detail: detail}
+}
+
+// Conflict reports a request that clashes with the current state, e.g. acting
+// on something already ended or changed by someone else
+func Conflict(detail string) error {
+	return &Error{Kind: ErrConflict, Detail: detail}
+}
+
+// Upstream reports that a service the server depends on failed; cause is
+// logged but not shown
+func Upstream(detail string, cause error) error {
+	return &Error{Kind: ErrUpstream, Detail: detail, Cause: cause}
+}
+
+// Message returns what a client may be told about err: the whole message when
+// err is built only from details, otherwise the detail of its first Error. ok
+// is false when err carries no Error at all, so nothing about it may be shown.
+func Message(err error) (message string, ok bool) {
+	var e *Error
+	if !errors.As(err, &e) {
+		return "", false
+	}
+	if hasCause(err) {
+		return e.Detail, true
+	}
+	return err.Error(), true
+}
+
+// hasCause reports whether any Error in err's chain wraps an underlying cause
+func hasCause(err error) bool {
+	switch x := err.(type) {
+	case *Error:
+		return x.Cause != nil
+	case interface{ Unwrap() error }:
+		return hasCause(x.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range x.Unwrap() {
+			if hasCause(inner) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,29 +4,42 @@ package firebase
 import (
 	"context"
 	"log"
+	"time"
 
 	firebase "firebase.google.com/go" // Updated path
 	"firebase.google.com/go/auth"
 )
 
 type AuthClient struct {
-	Client *auth.Client
+	Client  *auth.Client
+	Timeout time.Duration // Bounds each call to Firebase Auth; 0 means no limit
 }
 
-func NewAuthClient(app *firebase.App) (*AuthClient, error) {
+func NewAuthClient(ctx context.Context, app *firebase.App, timeout time.Duration) (*AuthClient, error) {
 	// Initialize the Auth client
-	client, err := app.Auth(context.Background())
+	client, err := app.Auth(ctx)
 	if err != nil {
 		log.Printf("Failed to initialize Firebase Auth client: %v", err)
 		return nil, err
 	}
 
-	return &AuthClient{Client: client}, nil
+	return &AuthClient{Client: client, Timeout: timeout}, nil
+}
+
+// WithTimeout derives a context for a single Firebase Auth call
+func (ac *AuthClient) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ac.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, ac.Timeout)
 }
 
 // VerifyIDToken verifies a Firebase JWT token and returns the decoded token
-func (ac *AuthClient) VerifyIDToken(token string) (*auth.Token, error) {
-	verifiedToken, err := ac.Client.VerifyIDToken(context.Background(), token)
+func (ac *AuthClient) VerifyIDToken(ctx context.Context, token string) (*auth.Token, error) {
+	ctx, cancel := ac.WithTimeout(ctx)
+	defer cancel()
+
+	verifiedToken, err := ac.Client.VerifyIDToken(ctx, token)
 	if err != nil {
 		log.Printf("Failed to verify ID token: %v", err)
 		return nil, err
@@ -35,8 +48,10 @@ func (ac *AuthClient) VerifyIDToken(token string) (*auth.Token, error) {
 }
 
 // SetCustomClaims sets role-based claims for a user (Admin SDK)
-func (ac *AuthClient) SetCustomClaims(uid, role string) error {
-	ctx := context.Background()
+func (ac *AuthClient) SetCustomClaims(ctx context.Context, uid, role string) error {
+	ctx, cancel := ac.WithTimeout(ctx)
+	defer cancel()
+
 	params := (&auth.UserToUpdate{}).CustomClaims(map[string]interface{}{
 		"role": role,
 	})
@@ -49,8 +64,10 @@ func (ac *AuthClient) SetCustomClaims(uid, role string) error {
 }
 
 // GetUserByUID retrieves user info to verify or manage roles
-func (ac *AuthClient) GetUserByUID(uid string) (*auth.UserRecord, error) {
-	ctx := context.Background()
+func (ac *AuthClient) GetUserByUID(ctx context.Context, uid string) (*auth.UserRecord, error) {
+	ctx, cancel := ac.WithTimeout(ctx)
+	defer cancel()
+
 	user, err := ac.Client.GetUser(ctx, uid)
 	if err != nil {
 		log.Printf("Failed to get user %s: %v", uid, err)
@@ -58,3 +75,19 @@ func (ac *AuthClient) GetUserByUID(uid string) (*auth.UserRecord, error) {
 	}
 	return user, nil
 }
+
+// GetUserByEmail looks up a user account by email address
+func (ac *AuthClient) GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error) {
+	ctx, cancel := ac.WithTimeout(ctx)
+	defer cancel()
+
+	return ac.Client.GetUserByEmail(ctx, email)
+}
+
+// CustomToken mints a custom token for a user
+func (ac *AuthClient) CustomToken(ctx context.Context, uid string) (string, error) {
+	ctx, cancel := ac.WithTimeout(ctx)
+	defer cancel()
+
+	return ac.Client.CustomToken(ctx, uid)
+}
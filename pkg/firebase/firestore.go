@@ -4,18 +4,21 @@ package firebase
 import (
 	"context"
 	"log"
+	"time"
 
 	"cloud.google.com/go/firestore" // Updated path
 	firebase "firebase.google.com/go"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
 type FirestoreClient struct {
 	Client *firestore.Client
 }
 
-func NewFirestoreClient(app *firebase.App) (*FirestoreClient, error) {
+func NewFirestoreClient(ctx context.Context, app *firebase.App) (*FirestoreClient, error) {
 	// Initialize the Firestore client
-	client, err := app.Firestore(context.Background())
+	client, err := app.Firestore(ctx)
 	if err != nil {
 		log.Printf("Failed to initialize Firestore client: %v", err)
 		return nil, err
@@ -30,3 +33,45 @@ func (fc *FirestoreClient) Close() error {
 	}
 	return nil
 }
+
+// RPCTimeoutOptions bound every Firestore RPC, including those made inside
+// transactions and query streams, by timeout. Pass them to firebase.NewApp so the
+// limit applies without each call site deriving its own deadline; the caller's
+// context still ends the call earlier when the request finishes.
+func RPCTimeoutOptions(timeout time.Duration) []option.ClientOption {
+	if timeout <= 0 {
+		return nil
+	}
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &timeoutStream{ClientStream: s, cancel: cancel}, nil
+	}
+	return []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(unary)),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(stream)),
+	}
+}
+
+// timeoutStream releases its deadline once the stream has been fully read
+type timeoutStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *timeoutStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
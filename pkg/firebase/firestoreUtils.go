@@ -9,8 +9,7 @@ import (
 )
 
 // GetUserByUID fetches a user from Firestore by UID
-func GetUserByUID(client *firestore.Client, uid string) (*models.User, error) {
-	ctx := context.Background()
+func GetUserByUID(ctx context.Context, client *firestore.Client, uid string) (*models.User, error) {
 	doc, err := client.Collection("users").Doc(uid).Get(ctx)
 	if err != nil {
 		log.Printf("Failed to get user by UID: %v", err)
@@ -27,8 +26,7 @@ func GetUserByUID(client *firestore.Client, uid string) (*models.User, error) {
 }
 
 // BatchSavePrescriptions saves multiple prescriptions to Firestore
-func BatchSavePrescriptions(client *firestore.Client, prescriptions []*models.Prescription) error {
-	ctx := context.Background()
+func BatchSavePrescriptions(ctx context.Context, client *firestore.Client, prescriptions []*models.Prescription) error {
 	batch := client.Batch()
 	for _, p := range prescriptions {
 		docRef := client.Collection("prescriptions").Doc(p.ID)
@@ -43,8 +41,7 @@ func BatchSavePrescriptions(client *firestore.Client, prescriptions []*models.Pr
 }
 
 // GetPrescriptionsByUserID fetches all prescriptions for a user
-func GetPrescriptionsByUserID(client *firestore.Client, userID string) ([]*models.Prescription, error) {
-	ctx := context.Background()
+func GetPrescriptionsByUserID(ctx context.Context, client *firestore.Client, userID string) ([]*models.Prescription, error) {
 	docs, err := client.Collection("prescriptions").
 		Where("user_id", "==", userID).
 		Documents(ctx).GetAll()
@@ -67,8 +64,7 @@ func GetPrescriptionsByUserID(client *firestore.Client, userID string) ([]*model
 }
 
 // SaveMedicalHistory saves a medical history entry to Firestore
-func SaveMedicalHistory(client *firestore.Client, history *models.MedicalHistory) error {
-	ctx := context.Background()
+func SaveMedicalHistory(ctx context.Context, client *firestore.Client, history *models.MedicalHistory) error {
 	_, err := client.Collection("medical_history").Doc(history.ID).Set(ctx, history)
 	if err != nil {
 		log.Printf("Failed to save medical history for user %s: %v", history.UserID, err)
@@ -78,8 +74,7 @@ func SaveMedicalHistory(client *firestore.Client, history *models.MedicalHistory
 }
 
 // LogTransaction logs a hospital one-time access event
-func LogTransaction(client *firestore.Client, transaction *models.Transaction) error {
-	ctx := context.Background()
+func LogTransaction(ctx context.Context, client *firestore.Client, transaction *models.Transaction) error {
 	_, err := client.Collection("transactions").Doc(transaction.ID).Set(ctx, transaction)
 	if err != nil {
 		log.Printf("Failed to log transaction for user %s: %v", transaction.UserID, err)
@@ -89,8 +84,7 @@ func LogTransaction(client *firestore.Client, transaction *models.Transaction) e
 }
 
 // GetTransactionsByUserID fetches all transactions for a user (e.g., hospital)
-func GetTransactionsByUserID(client *firestore.Client, userID string) ([]*models.Transaction, error) {
-	ctx := context.Background()
+func GetTransactionsByUserID(ctx context.Context, client *firestore.Client, userID string) ([]*models.Transaction, error) {
 	docs, err := client.Collection("transactions").
 		Where("user_id", "==", userID).
 		Documents(ctx).GetAll()
@@ -0,0 +1,289 @@
+// Package schema validates JSON documents against the subset of JSON Schema
+// (draft 2020-12) that the server's own schemas use. Parse rejects keywords
+// outside that subset, so a schema never silently loses a constraint.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema
+type Schema struct {
+	// Annotations, kept for clients but not checked
+	SchemaURI   string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Examples    []any  `json:"examples,omitempty"`
+
+	// Assertions
+	Type                 string             `json:"type,omitempty"` // "object", "array", "string", "number", "integer" or "boolean"
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"` // "date" or "date-time"
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// ValidationError describes the first place a document breaks its schema
+type ValidationError struct {
+	Path    string // Dotted path to the offending value, e.g. "reference_range.low"; empty for the root
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Parse reads a schema, compiling its patterns
+func Parse(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s Schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MustParse is Parse for schemas built into the server; it panics on error
+func MustParse(data []byte) *Schema {
+	s, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compile checks the schema and its subschemas and compiles their patterns
+func (s *Schema) compile() error {
+	switch s.Type {
+	case "", "object", "array", "string", "number", "integer", "boolean":
+	default:
+		return fmt.Errorf("schema: unsupported type %q", s.Type)
+	}
+	switch s.Format {
+	case "", "date", "date-time":
+	default:
+		return fmt.Errorf("schema: unsupported format %q", s.Format)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		s.pattern = re
+	}
+	children := slices.Clone(s.AnyOf)
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+	for _, child := range children {
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a JSON document against the schema
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &ValidationError{Message: "invalid JSON"}
+	}
+	if dec.More() {
+		return &ValidationError{Message: "invalid JSON"}
+	}
+	return s.validate(value, "")
+}
+
+// validate checks a decoded value found at path
+func (s *Schema) validate(value any, path string) error {
+	fail := func(format string, args ...any) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if s.Type != "" && !hasType(value, s.Type) {
+		return fail("must be of type %s", s.Type)
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return equal(allowed, value) }) {
+		return fail("must be one of %s", enumList(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: join(path, name), Message: "is required"}
+			}
+		}
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			return fail("must have at least %d properties", *s.MinProperties)
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names) // Report the same error for the same document every time
+		for _, name := range names {
+			child, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Path: join(path, name), Message: "is not allowed"}
+				}
+				continue
+			}
+			if err := child.validate(v[name], join(path, name)); err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item, join(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("must match %s", s.Pattern)
+		}
+		switch s.Format {
+		case "date":
+			if _, err := time.Parse(time.DateOnly, v); err != nil {
+				return fail("must be a YYYY-MM-DD date")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fail("must be an RFC 3339 date and time")
+			}
+		}
+
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			return fail("must be at least %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fail("must be at most %s", formatNumber(*s.Maximum))
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		var first error
+		for _, option := range s.AnyOf {
+			err := option.validate(value, path)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+	return nil
+}
+
+// hasType reports whether a decoded value is of a JSON Schema type
+func hasType(value any, typ string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		n, err := v.Float64()
+		return typ == "integer" && err == nil && n == math.Trunc(n)
+	default:
+		return false
+	}
+}
+
+// equal compares an enum value from a schema with a decoded value
+func equal(allowed, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		a, ok := allowed.(float64)
+		return ok && a == f
+	}
+	return allowed == value
+}
+
+// enumList formats enum values for an error message
+func enumList(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatNumber prints a bound without a trailing ".0"
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// join appends a property name or index to a path
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
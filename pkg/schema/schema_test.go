@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+// testSchema covers every supported keyword
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Lab result",
+	"type": "object",
+	"required": ["test", "value"],
+	"additionalProperties": false,
+	"properties": {
+		"test": {"type": "string", "minLength": 1, "maxLength": 5},
+		"code": {"type": "string", "pattern": "^[A-Z][0-9]{2}$"},
+		"value": {"anyOf": [{"type": "number", "minimum": 0, "maximum": 1000}, {"type": "string", "enum": ["positive", "negative"]}]},
+		"count": {"type": "integer"},
+		"flag": {"type": "boolean"},
+		"taken_on": {"type": "string", "format": "date"},
+		"taken_at": {"type": "string", "format": "date-time"},
+		"range": {"type": "object", "minProperties": 1, "properties": {"low": {"type": "number"}, "high": {"type": "number"}}},
+		"notes": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string", "maxLength": 3}}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s := MustParse([]byte(testSchema))
+	tests := []struct {
+		name     string
+		doc      string
+		wantPath string // Empty with an empty wantMsg for a valid document
+		wantMsg  string
+	}{
+		{"minimal", `{"test": "hb", "value": 13.5}`, "", ""},
+		{"complete", `{"test": "hb", "code": "D50", "value": "positive", "count": 2, "flag": true, "taken_on": "2024-02-29", "taken_at": "2024-02-29T08:30:00Z", "range": {"low": 12}, "notes": ["ok"]}`, "", ""},
+		{"integral float as integer", `{"test": "hb", "value": 1, "count": 2.0}`, "", ""},
+		{"unknown field without additionalProperties", `{"test": "hb", "value": 1, "range": {"low": 1, "unit": "g/dL"}}`, "", ""},
+
+		{"not an object", `[]`, "", "must be of type object"},
+		{"invalid JSON", `{"test":`, "", "invalid JSON"},
+		{"trailing document", `{"test": "hb", "value": 1} {}`, "", "invalid JSON"},
+		{"missing required", `{"value": 1}`, "test", "is required"},
+		{"additional property", `{"test": "hb", "value": 1, "extra": 1}`, "extra", "is not allowed"},
+		{"wrong type", `{"test": 5, "value": 1}`, "test", "must be of type string"},
+		{"too short", `{"test": "", "value": 1}`, "test", "must be at least 1 characters"},
+		{"too long in characters", `{"test": "ééééééé", "value": 1}`, "test", "must be at most 5 characters"},
+		{"pattern", `{"test": "hb", "code": "d50", "value": 1}`, "code", "must match ^[A-Z][0-9]{2}$"},
+		{"not an integer", `{"test": "hb", "value": 1, "count": 2.5}`, "count", "must be of type integer"},
+		{"bad date", `{"test": "hb", "value": 1, "taken_on": "2023-02-29"}`, "taken_on", "must be a YYYY-MM-DD date"},
+		{"bad date-time", `{"test": "hb", "value": 1, "taken_at": "2024-02-29 08:30"}`, "taken_at", "must be an RFC 3339 date and time"},
+		{"too few properties", `{"test": "hb", "value": 1, "range": {}}`, "range", "must have at least 1 properties"},
+		{"nested type", `{"test": "hb", "value": 1, "range": {"low": "12"}}`, "range.low", "must be of type number"},
+		{"too few items", `{"test": "hb", "value": 1, "notes": []}`, "notes", "must have at least 1 items"},
+		{"too many items", `{"test": "hb", "value": 1, "notes": ["a", "b", "c"]}`, "notes", "must have at most 2 items"},
+		{"item path", `{"test": "hb", "value": 1, "notes": ["ok", "long"]}`, "notes.1", "must be at most 3 characters"},
+		// anyOf reports the first option's error when none match
+		{"below minimum", `{"test": "hb", "value": -1}`, "value", "must be at least 0"},
+		{"above maximum", `{"test": "hb", "value": 1000.5}`, "value", "must be at most 1000"},
+		{"no option", `{"test": "hb", "value": "unknown"}`, "value", "must be of type number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Validate([]byte(tt.doc))
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v, want valid", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a ValidationError", err)
+			}
+			if verr.Path != tt.wantPath || verr.Message != tt.wantMsg {
+				t.Errorf("Validate = %q at %q, want %q at %q", verr.Message, verr.Path, tt.wantMsg, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestValidateReportsTheSameErrorEveryTime(t *testing.T) {
+	s := MustParse([]byte(testSchema))
+	doc := []byte(`{"test": 1, "value": "x", "code": 2, "flag": "no"}`)
+	first := s.Validate(doc)
+	for range 20 {
+		if err := s.Validate(doc); err == nil || err.Error() != first.Error() {
+			t.Fatalf("Validate = %v, then %v", first, err)
+		}
+	}
+	if first.Error() != "code: must be of type string" {
+		t.Errorf("Validate = %v, want the first property in name order", first)
+	}
+}
+
+func TestParseRejectsUnsupportedSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"unknown keyword", `{"type": "string", "oneOf": []}`},
+		{"unknown type", `{"type": "null"}`},
+		{"unknown format", `{"type": "string", "format": "email"}`},
+		{"bad pattern", `{"type": "string", "pattern": "("}`},
+		{"nested unknown type", `{"type": "object", "properties": {"a": {"type": "tuple"}}}`},
+		{"unknown type in items", `{"type": "array", "items": {"type": "set"}}`},
+		{"bad pattern in anyOf", `{"anyOf": [{"type": "string", "pattern": "["}]}`},
+		{"not JSON", `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.schema)); err == nil {
+				t.Error("Parse accepted an unsupported schema")
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParse did not panic")
+		}
+	}()
+	MustParse([]byte(`{"type": "null"}`))
+}
@@ -0,0 +1,141 @@
+package search
+
+import "slices"
+
+// Reasons a name matched, reported with match scores
+const (
+	ReasonName        = "name"         // Same normalized name
+	ReasonSimilarName = "similar_name" // Close spelling or a prefix
+	ReasonSoundsLike  = "sounds_like"  // Same phonetic code
+)
+
+const (
+	prefixScore   = 0.9  // A query word that starts a name word, e.g. "alex" for "alexander"
+	phoneticScore = 0.85 // Words that sound alike but are spelled apart, e.g. "smith" and "schmidt"
+)
+
+// Levenshtein returns the number of single-character insertions, deletions and
+// substitutions that turn a into b
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Similarity scores two words from 0 (nothing alike) to 1 (equal) by their
+// edit distance relative to the longer word
+func Similarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
+
+// QueryScore scores how well a name matches a typed query, from 0 to 1, with
+// the reasons it matched. Each query word is scored against its best name word
+// and the scores averaged, so extra words in the name (a middle name, say) cost
+// nothing while unmatched query words do.
+func QueryScore(query, name string) (float64, []string) {
+	return tokensScore(Tokens(query), Tokens(name), true)
+}
+
+// NameSimilarity scores how likely two full names belong to the same person,
+// from 0 to 1, with the reasons. Unlike QueryScore it is symmetric and words
+// missing from either name count against the score.
+func NameSimilarity(a, b string) (float64, []string) {
+	ta, tb := Tokens(a), Tokens(b)
+	ab, reasonsAB := tokensScore(ta, tb, false)
+	ba, reasonsBA := tokensScore(tb, ta, false)
+	if ab < ba {
+		return ab, reasonsAB
+	}
+	return ba, reasonsBA
+}
+
+// tokensScore averages the best score of each query token against the name
+// tokens. Prefix matches only count for typed queries.
+func tokensScore(query, name []string, prefixes bool) (float64, []string) {
+	if len(query) == 0 || len(name) == 0 {
+		return 0, nil
+	}
+	if slices.Equal(query, name) {
+		return 1, []string{ReasonName}
+	}
+
+	total := 0.0
+	exact, similar, soundsLike := true, false, false
+	for _, q := range query {
+		best, bestPhonetic := 0.0, false
+		for _, token := range name {
+			score, phonetic := tokenScore(q, token, prefixes)
+			if score > best {
+				best, bestPhonetic = score, phonetic
+			}
+		}
+		if best < 1 {
+			exact = false
+		}
+		if best > 0 && best < 1 {
+			if bestPhonetic {
+				soundsLike = true
+			} else {
+				similar = true
+			}
+		}
+		total += best
+	}
+
+	var reasons []string
+	if exact {
+		reasons = append(reasons, ReasonName) // Same words in another order or with extras
+	}
+	if similar {
+		reasons = append(reasons, ReasonSimilarName)
+	}
+	if soundsLike {
+		reasons = append(reasons, ReasonSoundsLike)
+	}
+	return total / float64(len(query)), reasons
+}
+
+// tokenScore scores one query word against one name word and reports whether the
+// score came from their sound rather than their spelling
+func tokenScore(q, token string, prefixes bool) (float64, bool) {
+	if q == token {
+		return 1, false
+	}
+	score := Similarity(q, token)
+	if prefixes && len([]rune(q)) >= 2 && len(q) < len(token) && token[:len(q)] == q {
+		score = max(score, prefixScore)
+	}
+	if score < phoneticScore && soundAlike(q, token) {
+		return phoneticScore, true
+	}
+	return score, false
+}
+
+// soundAlike reports whether two words share a Double Metaphone code
+func soundAlike(a, b string) bool {
+	pa, aa := DoubleMetaphone(a)
+	pb, ab := DoubleMetaphone(b)
+	if pa == "" || pb == "" {
+		return false
+	}
+	return pa == pb || (aa != "" && aa == pb) || (ab != "" && pa == ab) || (aa != "" && aa == ab)
+}
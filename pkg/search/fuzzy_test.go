@@ -0,0 +1,77 @@
+package search
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"saturday", "sunday", 3},
+		{"zoë", "zoe", 1}, // Counts characters, not bytes
+		{"", "abc", 3},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+
+	if got := Similarity("kitten", "sitting"); math.Abs(got-(1-3.0/7)) > 1e-9 {
+		t.Errorf("Similarity = %v, want %v", got, 1-3.0/7)
+	}
+	if got := Similarity("", ""); got != 1 {
+		t.Errorf("Similarity of empty words = %v, want 1", got)
+	}
+}
+
+func TestQueryScore(t *testing.T) {
+	tests := []struct {
+		query, name string
+		want        float64
+		reasons     []string
+	}{
+		{"John Smith", "john smith", 1, []string{ReasonName}},
+		{"smith", "John Smith", 1, []string{ReasonName}}, // Extra name words cost nothing
+		{"alex", "Alexander Smith", prefixScore, []string{ReasonSimilarName}},
+		{"smith", "Schmidt", phoneticScore, []string{ReasonSoundsLike}},
+		{"", "John Smith", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, reasons := QueryScore(tt.query, tt.name)
+			if math.Abs(got-tt.want) > 1e-9 || !slices.Equal(reasons, tt.reasons) {
+				t.Errorf("QueryScore(%q, %q) = %v %v, want %v %v", tt.query, tt.name, got, reasons, tt.want, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	if score, reasons := NameSimilarity("Smith John", "john smith"); score != 1 || !slices.Equal(reasons, []string{ReasonName}) {
+		t.Errorf("reordered names = %v %v, want 1 [name]", score, reasons)
+	}
+	if score, reasons := NameSimilarity("Jon Smith", "John Smyth"); score < phoneticScore || !slices.Contains(reasons, ReasonSoundsLike) {
+		t.Errorf("spelling variants = %v %v, want at least %v and sounds_like", score, reasons, phoneticScore)
+	}
+
+	// Symmetric, and a missing word counts against the shorter name
+	ab, _ := NameSimilarity("John Smith", "John Paul Smith")
+	ba, _ := NameSimilarity("John Paul Smith", "John Smith")
+	if ab != ba || ab >= 1 {
+		t.Errorf("NameSimilarity = %v and %v, want equal and below 1", ab, ba)
+	}
+	if score, _ := NameSimilarity("Alice Brown", "Zygmunt Kowalczyk"); score >= phoneticScore {
+		t.Errorf("unrelated names scored %v", score)
+	}
+}
@@ -0,0 +1,90 @@
+// Text normalization for the patient search index
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxPrefixLength caps the prefixes indexed per token; longer queries are
+// matched on this many characters and then filtered exactly
+const MaxPrefixLength = 12
+
+// Normalize lowercases s, spells Cyrillic and Greek in Latin, strips accents and
+// collapses everything that is not a letter or digit into single spaces, so
+// "  Zoë O'Brien-Smith" becomes "zoe o brien smith" and "Иван" becomes "ivan"
+func Normalize(s string) string {
+	latin := Transliterate(strings.ToLower(s))
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), latin)
+	if err != nil {
+		stripped = latin
+	}
+	fields := strings.FieldsFunc(stripped, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// NormalizePhone keeps only the digits of a phone number, so "+44 (20) 7946-0958"
+// and "442079460958" compare equal
+func NormalizePhone(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// Tokens returns the normalized words of s
+func Tokens(s string) []string {
+	return strings.Fields(Normalize(s))
+}
+
+// Prefixes returns every prefix, up to MaxPrefixLength characters, of each
+// token of s, without duplicates
+func Prefixes(s string) []string {
+	seen := make(map[string]bool)
+	prefixes := []string{}
+	for _, token := range Tokens(s) {
+		chars := []rune(token)
+		for n := 1; n <= len(chars) && n <= MaxPrefixLength; n++ {
+			prefix := string(chars[:n])
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return prefixes
+}
+
+// IndexPrefix is the indexed prefix to look up for a query token
+func IndexPrefix(token string) string {
+	chars := []rune(token)
+	if len(chars) > MaxPrefixLength {
+		chars = chars[:MaxPrefixLength]
+	}
+	return string(chars)
+}
+
+// MatchesPrefixes reports whether every query token is a prefix of some name token
+func MatchesPrefixes(nameTokens, queryTokens []string) bool {
+	for _, q := range queryTokens {
+		found := false
+		for _, token := range nameTokens {
+			if strings.HasPrefix(token, q) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
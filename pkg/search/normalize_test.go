@@ -0,0 +1,75 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"  Zoë O'Brien-Smith", "zoe o brien smith"},
+		{"Иван Петров", "ivan petrov"},
+		{"Щукин", "shchukin"},
+		{"Γιώργος", "giorgos"},
+		{"José  Müller", "jose muller"},
+		{"Anne-Marie 2nd", "anne marie 2nd"},
+		{"", ""},
+		{"-- ''", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	if got := NormalizePhone("+44 (20) 7946-0958"); got != "442079460958" {
+		t.Errorf("NormalizePhone = %q, want 442079460958", got)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	want := []string{"a", "an", "ann", "anna"}
+	if got := Prefixes("Ann ANNA"); !slices.Equal(got, want) {
+		t.Errorf("Prefixes = %v, want %v", got, want)
+	}
+
+	long := "abcdefghijklmnop"
+	got := Prefixes(long)
+	if len(got) != MaxPrefixLength || got[len(got)-1] != long[:MaxPrefixLength] {
+		t.Errorf("Prefixes(%q) = %v, want prefixes up to %d characters", long, got, MaxPrefixLength)
+	}
+	if got := IndexPrefix(long); got != long[:MaxPrefixLength] {
+		t.Errorf("IndexPrefix = %q, want %q", got, long[:MaxPrefixLength])
+	}
+	if got := IndexPrefix("ann"); got != "ann" {
+		t.Errorf("IndexPrefix = %q, want ann", got)
+	}
+}
+
+func TestMatchesPrefixes(t *testing.T) {
+	name := Tokens("Alexander James Smith")
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"alex", true},
+		{"smi ale", true},
+		{"alex jo", false},
+		{"lex", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := MatchesPrefixes(name, Tokens(tt.query)); got != tt.want {
+				t.Errorf("MatchesPrefixes(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
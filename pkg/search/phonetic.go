@@ -0,0 +1,589 @@
+package search
+
+import "strings"
+
+// Soundex returns the American Soundex code of a normalized word, e.g. "robert"
+// and "rupert" are both "R163". Words without Latin letters have no code.
+func Soundex(word string) string {
+	var code []byte
+	var last byte
+	for i := 0; i < len(word) && len(code) < 4; i++ {
+		c := word[i] &^ 0x20 // Uppercase ASCII
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		digit := soundexDigits[c-'A']
+		if len(code) == 0 {
+			code = append(code, c)
+			last = digit
+			continue
+		}
+		switch {
+		case digit == '0':
+			last = 0 // Vowels separate repeated codes
+		case digit == '-':
+			// H and W do not separate them
+		case digit != last:
+			code = append(code, digit)
+			last = digit
+		}
+	}
+	if len(code) == 0 {
+		return ""
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+// soundexDigits codes each letter A-Z: 0 for vowels, - for H and W
+const soundexDigits = "0123012-02245501262301-202"
+
+// DoubleMetaphone returns the primary and alternate Double Metaphone codes of a
+// normalized word, up to four characters each. The alternate differs from the
+// primary where a name has a common second pronunciation, e.g. "schmidt" is
+// "XMT" and "SMT".
+func DoubleMetaphone(word string) (string, string) {
+	m := &metaphone{value: strings.ToUpper(word)}
+	m.encode()
+	return m.primary.String(), m.alternate.String()
+}
+
+// PhoneticKeys returns the Soundex and Double Metaphone codes of every word of
+// s, tagged by algorithm, for indexing and matching
+func PhoneticKeys(s string) []string {
+	seen := make(map[string]bool)
+	keys := []string{}
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, token := range Tokens(s) {
+		if code := Soundex(token); code != "" {
+			add("s:" + code)
+		}
+		primary, alternate := DoubleMetaphone(token)
+		if primary != "" {
+			add("m:" + primary)
+		}
+		if alternate != "" {
+			add("m:" + alternate)
+		}
+	}
+	return keys
+}
+
+const metaphoneMaxLength = 4
+
+// metaphone holds the state of one Double Metaphone encoding, after Lawrence
+// Philips' original algorithm
+type metaphone struct {
+	value     string
+	primary   strings.Builder
+	alternate strings.Builder
+}
+
+func (m *metaphone) encode() {
+	value := m.value
+	if value == "" {
+		return
+	}
+	slavoGermanic := strings.ContainsAny(value, "WK") || strings.Contains(value, "CZ") || strings.Contains(value, "WITZ")
+
+	index := 0
+	if m.contains(0, 2, "GN", "KN", "PN", "WR", "PS") {
+		index = 1
+	}
+	if value[0] == 'X' {
+		m.add("S")
+		index = 1
+	}
+
+	for !m.complete() && index < len(value) {
+		switch value[index] {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if index == 0 {
+				m.add("A")
+			}
+			index++
+		case 'B':
+			m.add("P")
+			index = m.skip(index, 'B')
+		case 'C':
+			index = m.handleC(index)
+		case 'D':
+			index = m.handleD(index)
+		case 'F':
+			m.add("F")
+			index = m.skip(index, 'F')
+		case 'G':
+			index = m.handleG(index, slavoGermanic)
+		case 'H':
+			if (index == 0 || m.isVowel(index-1)) && m.isVowel(index+1) {
+				m.add("H")
+				index += 2
+			} else {
+				index++
+			}
+		case 'J':
+			index = m.handleJ(index, slavoGermanic)
+		case 'K':
+			m.add("K")
+			index = m.skip(index, 'K')
+		case 'L':
+			index = m.handleL(index)
+		case 'M':
+			m.add("M")
+			if m.at(index+1) == 'M' || (m.contains(index-1, 3, "UMB") && (index+1 == len(value)-1 || m.contains(index+2, 2, "ER"))) {
+				index += 2
+			} else {
+				index++
+			}
+		case 'N':
+			m.add("N")
+			index = m.skip(index, 'N')
+		case 'P':
+			if m.at(index+1) == 'H' {
+				m.add("F")
+				index += 2
+			} else {
+				m.add("P")
+				if m.contains(index+1, 1, "P", "B") {
+					index += 2
+				} else {
+					index++
+				}
+			}
+		case 'Q':
+			m.add("K")
+			index = m.skip(index, 'Q')
+		case 'R':
+			if index == len(value)-1 && !slavoGermanic && m.contains(index-2, 2, "IE") && !m.contains(index-4, 2, "ME", "MA") {
+				m.addBoth("", "R")
+			} else {
+				m.add("R")
+			}
+			index = m.skip(index, 'R')
+		case 'S':
+			index = m.handleS(index, slavoGermanic)
+		case 'T':
+			index = m.handleT(index)
+		case 'V':
+			m.add("F")
+			index = m.skip(index, 'V')
+		case 'W':
+			index = m.handleW(index)
+		case 'X':
+			if index == 0 {
+				m.add("S")
+				index++
+				break
+			}
+			if !(index == len(value)-1 && (m.contains(index-3, 3, "IAU", "EAU") || m.contains(index-2, 2, "AU", "OU"))) {
+				m.add("KS")
+			}
+			if m.contains(index+1, 1, "C", "X") {
+				index += 2
+			} else {
+				index++
+			}
+		case 'Z':
+			if m.at(index+1) == 'H' {
+				m.add("J")
+				index += 2
+				break
+			}
+			if m.contains(index+1, 2, "ZO", "ZI", "ZA") || (slavoGermanic && index > 0 && m.at(index-1) != 'T') {
+				m.addBoth("S", "TS")
+			} else {
+				m.add("S")
+			}
+			index = m.skip(index, 'Z')
+		default:
+			index++
+		}
+	}
+}
+
+func (m *metaphone) handleC(index int) int {
+	switch {
+	case m.conditionC0(index):
+		m.add("K")
+		return index + 2
+	case index == 0 && m.contains(index, 6, "CAESAR"):
+		m.add("S")
+		return index + 2
+	case m.contains(index, 2, "CH"):
+		return m.handleCH(index)
+	case m.contains(index, 2, "CZ") && !m.contains(index-2, 4, "WICZ"):
+		m.addBoth("S", "X")
+		return index + 2
+	case m.contains(index+1, 3, "CIA"):
+		m.add("X")
+		return index + 3
+	case m.contains(index, 2, "CC") && !(index == 1 && m.at(0) == 'M'):
+		if m.contains(index+2, 1, "I", "E", "H") && !m.contains(index+2, 2, "HU") {
+			if (index == 1 && m.at(index-1) == 'A') || m.contains(index-1, 5, "UCCEE", "UCCES") {
+				m.add("KS")
+			} else {
+				m.add("X")
+			}
+			return index + 3
+		}
+		m.add("K")
+		return index + 2
+	case m.contains(index, 2, "CK", "CG", "CQ"):
+		m.add("K")
+		return index + 2
+	case m.contains(index, 2, "CI", "CE", "CY"):
+		if m.contains(index, 3, "CIO", "CIE", "CIA") {
+			m.addBoth("S", "X")
+		} else {
+			m.add("S")
+		}
+		return index + 2
+	}
+	m.add("K")
+	switch {
+	case m.contains(index+1, 2, " C", " Q", " G"):
+		return index + 3
+	case m.contains(index+1, 1, "C", "K", "Q") && !m.contains(index+1, 2, "CE", "CI"):
+		return index + 2
+	}
+	return index + 1
+}
+
+// conditionC0 spots a Germanic "CH" after "A", as in "bacher"
+func (m *metaphone) conditionC0(index int) bool {
+	if m.contains(index, 4, "CHIA") {
+		return true
+	}
+	if index <= 1 || m.isVowel(index-2) || !m.contains(index-1, 3, "ACH") {
+		return false
+	}
+	c := m.at(index + 2)
+	return (c != 'I' && c != 'E') || m.contains(index-2, 6, "BACHER", "MACHER")
+}
+
+func (m *metaphone) handleCH(index int) int {
+	switch {
+	case index > 0 && m.contains(index, 4, "CHAE"):
+		m.addBoth("K", "X")
+	case m.conditionCH0(index), m.conditionCH1(index):
+		m.add("K")
+	case index > 0:
+		if m.contains(0, 2, "MC") {
+			m.add("K")
+		} else {
+			m.addBoth("X", "K")
+		}
+	default:
+		m.add("X")
+	}
+	return index + 2
+}
+
+// conditionCH0 spots a Greek initial "CH", as in "character" or "chemistry"
+func (m *metaphone) conditionCH0(index int) bool {
+	if index != 0 {
+		return false
+	}
+	if !m.contains(index+1, 5, "HARAC", "HARIS") && !m.contains(index+1, 3, "HOR", "HYM", "HIA", "HEM") {
+		return false
+	}
+	return !m.contains(0, 5, "CHORE")
+}
+
+// conditionCH1 spots Germanic and Greek "CH" pronounced "K"
+func (m *metaphone) conditionCH1(index int) bool {
+	return m.contains(0, 4, "VAN ", "VON ") || m.contains(0, 3, "SCH") ||
+		m.contains(index-2, 6, "ORCHES", "ARCHIT", "ORCHID") ||
+		m.contains(index+2, 1, "T", "S") ||
+		((m.contains(index-1, 1, "A", "O", "U", "E") || index == 0) &&
+			(m.contains(index+2, 1, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || index+1 == len(m.value)-1))
+}
+
+func (m *metaphone) handleD(index int) int {
+	switch {
+	case m.contains(index, 2, "DG"):
+		if m.contains(index+2, 1, "I", "E", "Y") {
+			m.add("J")
+			return index + 3
+		}
+		m.add("TK")
+		return index + 2
+	case m.contains(index, 2, "DT", "DD"):
+		m.add("T")
+		return index + 2
+	}
+	m.add("T")
+	return index + 1
+}
+
+func (m *metaphone) handleG(index int, slavoGermanic bool) int {
+	switch {
+	case m.at(index+1) == 'H':
+		return m.handleGH(index)
+	case m.at(index+1) == 'N':
+		if index == 1 && m.isVowel(0) && !slavoGermanic {
+			m.addBoth("KN", "N")
+		} else if !m.contains(index+2, 2, "EY") && m.at(index+1) != 'Y' && !slavoGermanic {
+			m.addBoth("N", "KN")
+		} else {
+			m.add("KN")
+		}
+		return index + 2
+	case m.contains(index+1, 2, "LI") && !slavoGermanic:
+		m.addBoth("KL", "L")
+		return index + 2
+	case index == 0 && (m.at(index+1) == 'Y' || m.contains(index+1, 2, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		m.addBoth("K", "J")
+		return index + 2
+	case (m.contains(index+1, 2, "ER") || m.at(index+1) == 'Y') &&
+		!m.contains(0, 6, "DANGER", "RANGER", "MANGER") &&
+		!m.contains(index-1, 1, "E", "I") && !m.contains(index-1, 3, "RGY", "OGY"):
+		m.addBoth("K", "J")
+		return index + 2
+	case m.contains(index+1, 1, "E", "I", "Y") || m.contains(index-1, 4, "AGGI", "OGGI"):
+		if m.contains(0, 4, "VAN ", "VON ") || m.contains(0, 3, "SCH") || m.contains(index+1, 2, "ET") {
+			m.add("K")
+		} else if m.contains(index+1, 3, "IER") {
+			m.add("J")
+		} else {
+			m.addBoth("J", "K")
+		}
+		return index + 2
+	case m.at(index+1) == 'G':
+		m.add("K")
+		return index + 2
+	}
+	m.add("K")
+	return index + 1
+}
+
+func (m *metaphone) handleGH(index int) int {
+	switch {
+	case index > 0 && !m.isVowel(index-1):
+		m.add("K")
+	case index == 0:
+		if m.at(index+2) == 'I' {
+			m.add("J")
+		} else {
+			m.add("K")
+		}
+	case (index > 1 && m.contains(index-2, 1, "B", "H", "D")) ||
+		(index > 2 && m.contains(index-3, 1, "B", "H", "D")) ||
+		(index > 3 && m.contains(index-4, 1, "B", "H")):
+		// Silent, as in "hugh" or "bough"
+	default:
+		if index > 2 && m.at(index-1) == 'U' && m.contains(index-3, 1, "C", "G", "L", "R", "T") {
+			m.add("F") // As in "laugh" or "tough"
+		} else if index > 0 && m.at(index-1) != 'I' {
+			m.add("K")
+		}
+	}
+	return index + 2
+}
+
+func (m *metaphone) handleJ(index int, slavoGermanic bool) int {
+	if m.contains(index, 4, "JOSE") || m.contains(0, 4, "SAN ") {
+		if (index == 0 && m.at(index+4) == ' ') || len(m.value) == 4 || m.contains(0, 4, "SAN ") {
+			m.add("H")
+		} else {
+			m.addBoth("J", "H")
+		}
+		return index + 1
+	}
+
+	switch {
+	case index == 0:
+		m.addBoth("J", "A")
+	case m.isVowel(index-1) && !slavoGermanic && (m.at(index+1) == 'A' || m.at(index+1) == 'O'):
+		m.addBoth("J", "H")
+	case index == len(m.value)-1:
+		m.addBoth("J", "")
+	case !m.contains(index+1, 1, "L", "T", "K", "S", "N", "M", "B", "Z") && !m.contains(index-1, 1, "S", "K", "L"):
+		m.add("J")
+	}
+	return m.skip(index, 'J')
+}
+
+func (m *metaphone) handleL(index int) int {
+	if m.at(index+1) != 'L' {
+		m.add("L")
+		return index + 1
+	}
+	// Spanish "LL", as in "cabrillo", has no L sound in the primary
+	last := len(m.value) - 1
+	if (index == last-2 && m.contains(index-1, 4, "ILLO", "ILLA", "ALLE")) ||
+		((m.contains(last-1, 2, "AS", "OS") || m.contains(last, 1, "A", "O")) && m.contains(index-1, 4, "ALLE")) {
+		m.addBoth("L", "")
+	} else {
+		m.add("L")
+	}
+	return index + 2
+}
+
+func (m *metaphone) handleS(index int, slavoGermanic bool) int {
+	switch {
+	case m.contains(index-1, 3, "ISL", "YSL"):
+		return index + 1 // Silent, as in "island"
+	case index == 0 && m.contains(index, 5, "SUGAR"):
+		m.addBoth("X", "S")
+		return index + 1
+	case m.contains(index, 2, "SH"):
+		if m.contains(index+1, 4, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			m.add("S")
+		} else {
+			m.add("X")
+		}
+		return index + 2
+	case m.contains(index, 3, "SIO", "SIA") || m.contains(index, 4, "SIAN"):
+		if slavoGermanic {
+			m.add("S")
+		} else {
+			m.addBoth("S", "X")
+		}
+		return index + 3
+	case (index == 0 && m.contains(index+1, 1, "M", "N", "L", "W")) || m.contains(index+1, 1, "Z"):
+		m.addBoth("S", "X")
+		if m.contains(index+1, 1, "Z") {
+			return index + 2
+		}
+		return index + 1
+	case m.contains(index, 2, "SC"):
+		return m.handleSC(index)
+	}
+
+	if index == len(m.value)-1 && m.contains(index-2, 2, "AI", "OI") {
+		m.addBoth("", "S") // French, as in "resnais"
+	} else {
+		m.add("S")
+	}
+	if m.contains(index+1, 1, "S", "Z") {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) handleSC(index int) int {
+	switch {
+	case m.at(index+2) == 'H':
+		if m.contains(index+3, 2, "OO", "ER", "EN", "UY", "ED", "EM") {
+			if m.contains(index+3, 2, "ER", "EN") {
+				m.addBoth("X", "SK")
+			} else {
+				m.add("SK")
+			}
+		} else if index == 0 && !m.isVowel(3) && m.at(3) != 'W' {
+			m.addBoth("X", "S")
+		} else {
+			m.add("X")
+		}
+	case m.contains(index+2, 1, "I", "E", "Y"):
+		m.add("S")
+	default:
+		m.add("SK")
+	}
+	return index + 3
+}
+
+func (m *metaphone) handleT(index int) int {
+	switch {
+	case m.contains(index, 4, "TION"), m.contains(index, 3, "TIA", "TCH"):
+		m.add("X")
+		return index + 3
+	case m.contains(index, 2, "TH") || m.contains(index, 3, "TTH"):
+		if m.contains(index+2, 2, "OM", "AM") || m.contains(0, 4, "VAN ", "VON ") || m.contains(0, 3, "SCH") {
+			m.add("T")
+		} else {
+			m.addBoth("0", "T") // "0" stands for "th"
+		}
+		return index + 2
+	}
+	m.add("T")
+	if m.contains(index+1, 1, "T", "D") {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) handleW(index int) int {
+	switch {
+	case m.contains(index, 2, "WR"):
+		m.add("R")
+		return index + 2
+	case index == 0 && (m.isVowel(index+1) || m.contains(index, 2, "WH")):
+		if m.isVowel(index + 1) {
+			m.addBoth("A", "F")
+		} else {
+			m.add("A")
+		}
+	case (index == len(m.value)-1 && m.isVowel(index-1)) ||
+		m.contains(index-1, 5, "EWSKI", "EWSKY", "OWSKI", "OWSKY") || m.contains(0, 3, "SCH"):
+		m.addBoth("", "F") // Polish, as in "filipowicz"
+	case m.contains(index, 4, "WICZ", "WITZ"):
+		m.addBoth("TS", "FX")
+		return index + 4
+	}
+	return index + 1
+}
+
+// at returns the letter at i, or 0 outside the word
+func (m *metaphone) at(i int) byte {
+	if i < 0 || i >= len(m.value) {
+		return 0
+	}
+	return m.value[i]
+}
+
+func (m *metaphone) isVowel(i int) bool {
+	return strings.IndexByte("AEIOUY", m.at(i)) >= 0
+}
+
+// contains reports whether the length letters from start equal any of options
+func (m *metaphone) contains(start, length int, options ...string) bool {
+	if start < 0 || start+length > len(m.value) {
+		return false
+	}
+	sub := m.value[start : start+length]
+	for _, option := range options {
+		if sub == option {
+			return true
+		}
+	}
+	return false
+}
+
+// skip steps past the letter at index and a doubled copy of it
+func (m *metaphone) skip(index int, letter byte) int {
+	if m.at(index+1) == letter {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) add(code string) {
+	m.addBoth(code, code)
+}
+
+func (m *metaphone) addBoth(primary, alternate string) {
+	appendCode(&m.primary, primary)
+	appendCode(&m.alternate, alternate)
+}
+
+func (m *metaphone) complete() bool {
+	return m.primary.Len() >= metaphoneMaxLength && m.alternate.Len() >= metaphoneMaxLength
+}
+
+func appendCode(b *strings.Builder, code string) {
+	if room := metaphoneMaxLength - b.Len(); room > 0 {
+		if len(code) > room {
+			code = code[:room]
+		}
+		b.WriteString(code)
+	}
+}
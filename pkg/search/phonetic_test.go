@@ -0,0 +1,81 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	// Vectors from the US National Archives' description of the algorithm
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"robert", "R163"},
+		{"rupert", "R163"},
+		{"rubin", "R150"},
+		{"ashcraft", "A261"}, // H does not separate the S and C
+		{"ashcroft", "A261"},
+		{"tymczak", "T522"}, // The vowel separates the two Z-coded letters
+		{"pfister", "P236"}, // F codes like the initial P and is dropped
+		{"honeyman", "H555"},
+		{"lee", "L000"},
+		{"o brien", "O165"},
+		{"", ""},
+		{"42", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := Soundex(tt.word); got != tt.want {
+				t.Errorf("Soundex(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoubleMetaphone(t *testing.T) {
+	// Vectors from Philips' reference implementation
+	tests := []struct {
+		word               string
+		primary, alternate string
+	}{
+		{"smith", "SM0", "XMT"},
+		{"schmidt", "XMT", "SMT"},
+		{"xavier", "SF", "SFR"},
+		{"jose", "HS", "HS"},
+		{"caesar", "SSR", "SSR"},
+		{"michael", "MKL", "MXL"},
+		{"katherine", "K0RN", "KTRN"},
+		{"catherine", "K0RN", "KTRN"},
+		{"gough", "KF", "KF"},
+		{"dumb", "TM", "TM"},
+		{"knight", "NT", "NT"},
+		{"wright", "RT", "RT"},
+		{"arnow", "ARN", "ARNF"},
+		{"jankelowicz", "JNKL", "ANKL"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			primary, alternate := DoubleMetaphone(tt.word)
+			if primary != tt.primary || alternate != tt.alternate {
+				t.Errorf("DoubleMetaphone(%q) = %q, %q; want %q, %q", tt.word, primary, alternate, tt.primary, tt.alternate)
+			}
+		})
+	}
+}
+
+func TestPhoneticKeys(t *testing.T) {
+	want := []string{"s:J500", "m:JN", "m:AN", "s:S530", "m:SM0", "m:XMT"}
+	if got := PhoneticKeys("Jon  Smith"); !slices.Equal(got, want) {
+		t.Errorf("PhoneticKeys = %v, want %v", got, want)
+	}
+
+	// Spellings of a name that sound alike share keys
+	for _, pair := range [][2]string{{"Jon", "John"}, {"Mohammed", "Muhammad"}, {"Catherine", "Katherine"}, {"Aleksandr", "Alexander"}} {
+		a, b := PhoneticKeys(pair[0]), PhoneticKeys(pair[1])
+		if !slices.ContainsFunc(a, func(key string) bool { return key[0] == 'm' && slices.Contains(b, key) }) {
+			t.Errorf("%s %v and %s %v share no Double Metaphone key", pair[0], a, pair[1], b)
+		}
+	}
+}
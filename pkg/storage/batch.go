@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+// ErrRolledBack marks a batch item that was stored and then removed because
+// another item in the batch failed
+var ErrRolledBack = errors.New("rolled back after batch failure")
+
+// BlobResult is the outcome of one item in a batch. For uploads ID is the
+// identifier assigned by the store; for downloads it is the requested ID.
+type BlobResult struct {
+	ID   string
+	Data []byte
+	Err  error
+}
+
+// BatchOptions controls a batch upload
+type BatchOptions struct {
+	Workers         int  // Maximum uploads in flight; defaults to 1
+	RollbackOnError bool // Delete (unpin) every stored item if any item fails
+}
+
+// AddMany uploads items concurrently and returns one result per item, in input
+// order. Items not yet started when ctx is cancelled fail with ctx.Err(). The
+// returned error is the first failure in input order, or nil if all succeeded.
+func AddMany(ctx context.Context, store BlobStore, items [][]byte, opts BatchOptions) ([]BlobResult, error) {
+	results := make([]BlobResult, len(items))
+	runBatch(ctx, len(items), opts.Workers, func(i int) {
+		id, err := store.AddData(ctx, items[i])
+		results[i] = BlobResult{ID: id, Err: err}
+	}, func(i int, err error) {
+		results[i] = BlobResult{Err: err}
+	})
+
+	var firstErr error
+	for _, r := range results {
+		if r.Err != nil {
+			firstErr = r.Err
+			break
+		}
+	}
+	if firstErr == nil || !opts.RollbackOnError {
+		return results, firstErr
+	}
+
+	// Roll back the items that did succeed so a failed batch leaves nothing behind,
+	// even when the failure was the caller giving up
+	rollbackCtx := context.WithoutCancel(ctx)
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if err := store.Delete(rollbackCtx, results[i].ID); err != nil {
+			log.Printf("Failed to roll back batch item %d (%s): %v", i, results[i].ID, err)
+			results[i].Err = err
+			continue
+		}
+		results[i].Err = ErrRolledBack
+	}
+	return results, firstErr
+}
+
+// GetMany fetches blobs concurrently with at most workers requests in flight.
+// Results are returned in the same order as ids; a failed fetch sets Err on its
+// own result without affecting the others.
+func GetMany(ctx context.Context, store BlobStore, ids []string, workers int) []BlobResult {
+	results := make([]BlobResult, len(ids))
+	runBatch(ctx, len(ids), workers, func(i int) {
+		data, err := store.GetData(ctx, ids[i])
+		results[i] = BlobResult{ID: ids[i], Data: data, Err: err}
+	}, func(i int, err error) {
+		results[i] = BlobResult{ID: ids[i], Err: err}
+	})
+	return results
+}
+
+// runBatch calls do for each index 0..n-1 on a pool of workers. Once ctx is
+// done, remaining indexes are passed to skip with the context's error instead.
+func runBatch(ctx context.Context, n, workers int, do func(i int), skip func(i int, err error)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					skip(i, err)
+					continue
+				}
+				do(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
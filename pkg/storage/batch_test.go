@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyStore records the most reads it has seen in flight at once
+type concurrencyStore struct {
+	BlobStore
+	inFlight, peak atomic.Int32
+}
+
+func (s *concurrencyStore) GetData(ctx context.Context, id string) ([]byte, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond) // Long enough for the workers to overlap
+	return s.BlobStore.GetData(ctx, id)
+}
+
+func TestGetMany(t *testing.T) {
+	ctx := context.Background()
+	local := testLocalStore(t)
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id, err := local.AddData(ctx, []byte(fmt.Sprintf("blob %d", i)))
+		if err != nil {
+			t.Fatalf("AddData: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	missing := fmt.Sprintf("%064x", 0)
+	withMissing := append(append(append([]string{}, ids[:3]...), missing), ids[3:]...)
+
+	tests := []struct {
+		name     string
+		ids      []string
+		workers  int
+		wantPeak int32
+	}{
+		{"no IDs", nil, 4, 0},
+		{"zero workers runs one at a time", ids, 0, 1},
+		{"one worker", ids, 1, 1},
+		{"bounded workers", ids, 3, 3},
+		{"more workers than IDs", ids[:2], 8, 2},
+		{"missing blob", withMissing, 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &concurrencyStore{BlobStore: local}
+			results := GetMany(ctx, store, tt.ids, tt.workers)
+			if len(results) != len(tt.ids) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.ids))
+			}
+
+			// Results line up with the requested IDs, and only the missing blob fails
+			blob := 0
+			for i, result := range results {
+				if result.ID != tt.ids[i] {
+					t.Errorf("result %d is for %s, want %s", i, result.ID, tt.ids[i])
+				}
+				if tt.ids[i] == missing {
+					if result.Err == nil {
+						t.Errorf("result %d: fetching a missing blob succeeded", i)
+					}
+					continue
+				}
+				if want := fmt.Sprintf("blob %d", blob); result.Err != nil || string(result.Data) != want {
+					t.Errorf("result %d = %q, %v, want %q", i, result.Data, result.Err, want)
+				}
+				blob++
+			}
+			if got := store.peak.Load(); got > tt.wantPeak {
+				t.Errorf("%d fetches in flight, want at most %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestGetManyCancelled(t *testing.T) {
+	local := testLocalStore(t)
+	id, err := local.AddData(context.Background(), []byte("blob"))
+	if err != nil {
+		t.Fatalf("AddData: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := GetMany(ctx, local, []string{id, id, id}, 2)
+	for i, result := range results {
+		if result.ID != id || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result %d = %s, %v, want %s and context.Canceled", i, result.ID, result.Err, id)
+		}
+	}
+}
+
+var errUpload = errors.New("upload failed")
+
+// uploadStore fails uploads of items starting with "fail", records deletions and
+// calls afterAdd after each successful upload
+type uploadStore struct {
+	BlobStore
+	afterAdd  func()
+	deleteErr error
+	mu        sync.Mutex
+	deleted   []string
+}
+
+func (s *uploadStore) AddData(ctx context.Context, data []byte) (string, error) {
+	if bytes.HasPrefix(data, []byte("fail")) {
+		return "", errUpload
+	}
+	time.Sleep(time.Millisecond) // Let uploads finish out of order
+	id, err := s.BlobStore.AddData(ctx, data)
+	if err == nil && s.afterAdd != nil {
+		s.afterAdd()
+	}
+	return id, err
+}
+
+func (s *uploadStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	s.deleted = append(s.deleted, id)
+	s.mu.Unlock()
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	return s.BlobStore.Delete(ctx, id)
+}
+
+func TestAddMany(t *testing.T) {
+	ctx := context.Background()
+	store := &uploadStore{BlobStore: testLocalStore(t)}
+	var items [][]byte
+	for i := 0; i < 10; i++ {
+		items = append(items, []byte(fmt.Sprintf("blob %d", i)))
+	}
+
+	results, err := AddMany(ctx, store, items, BatchOptions{Workers: 3, RollbackOnError: true})
+	if err != nil {
+		t.Fatalf("AddMany: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+	// Each result is for the item at the same position
+	for i, result := range results {
+		data, err := store.GetData(ctx, result.ID)
+		if result.Err != nil || err != nil || !bytes.Equal(data, items[i]) {
+			t.Errorf("result %d = %s, %v holding %q, want %q", i, result.ID, result.Err, data, items[i])
+		}
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("a successful batch deleted %v", store.deleted)
+	}
+}
+
+func TestAddManyFailures(t *testing.T) {
+	items := [][]byte{[]byte("blob a"), []byte("fail 1"), []byte("blob b"), []byte("fail 2"), []byte("blob c")}
+	tests := []struct {
+		name      string
+		rollback  bool
+		deleteErr error
+		wantOK    error // Err of the items that uploaded
+	}{
+		{"kept without rollback", false, nil, nil},
+		{"rolled back", true, nil, ErrRolledBack},
+		{"rollback fails", true, errors.New("delete failed"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := &uploadStore{BlobStore: testLocalStore(t), deleteErr: tt.deleteErr}
+			results, err := AddMany(ctx, store, items, BatchOptions{Workers: 2, RollbackOnError: tt.rollback})
+			if !errors.Is(err, errUpload) {
+				t.Fatalf("AddMany = %v, want the first item error", err)
+			}
+
+			// Each failed item carries its own error; the others are stored
+			// until rolled back, which deletes exactly them
+			var stored []string
+			for i, result := range results {
+				if bytes.HasPrefix(items[i], []byte("fail")) {
+					if result.ID != "" || !errors.Is(result.Err, errUpload) {
+						t.Errorf("result %d = %s, %v, want errUpload", i, result.ID, result.Err)
+					}
+					continue
+				}
+				if result.ID == "" {
+					t.Fatalf("result %d has no ID", i)
+				}
+				stored = append(stored, result.ID)
+				wantErr := tt.wantOK
+				if tt.deleteErr != nil {
+					wantErr = tt.deleteErr
+				}
+				if !errors.Is(result.Err, wantErr) {
+					t.Errorf("result %d error = %v, want %v", i, result.Err, wantErr)
+				}
+				_, getErr := store.BlobStore.GetData(ctx, result.ID)
+				if rolledBack := tt.rollback && tt.deleteErr == nil; (getErr != nil) != rolledBack {
+					t.Errorf("result %d still stored = %v, want %v", i, getErr == nil, !rolledBack)
+				}
+			}
+
+			var wantDeleted []string
+			if tt.rollback {
+				wantDeleted = stored
+			}
+			slices.Sort(store.deleted)
+			slices.Sort(wantDeleted)
+			if !slices.Equal(store.deleted, wantDeleted) {
+				t.Errorf("deleted %v, want %v", store.deleted, wantDeleted)
+			}
+		})
+	}
+}
+
+func TestAddManyCancelled(t *testing.T) {
+	items := [][]byte{[]byte("blob a"), []byte("blob b"), []byte("blob c")}
+
+	t.Run("before starting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		store := &uploadStore{BlobStore: testLocalStore(t)}
+		results, err := AddMany(ctx, store, items, BatchOptions{Workers: 2, RollbackOnError: true})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("AddMany = %v, want context.Canceled", err)
+		}
+		for i, result := range results {
+			if result.ID != "" || !errors.Is(result.Err, context.Canceled) {
+				t.Errorf("result %d = %s, %v, want context.Canceled", i, result.ID, result.Err)
+			}
+		}
+		if len(store.deleted) != 0 {
+			t.Errorf("nothing was stored but %v was deleted", store.deleted)
+		}
+	})
+
+	t.Run("part way", func(t *testing.T) {
+		// The caller gives up after the first upload; it is still rolled back
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		store := &uploadStore{BlobStore: testLocalStore(t), afterAdd: cancel}
+		results, err := AddMany(ctx, store, items, BatchOptions{Workers: 1, RollbackOnError: true})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("AddMany = %v, want context.Canceled", err)
+		}
+		if !errors.Is(results[0].Err, ErrRolledBack) || !slices.Equal(store.deleted, []string{results[0].ID}) {
+			t.Errorf("first result = %s, %v with %v deleted, want it rolled back", results[0].ID, results[0].Err, store.deleted)
+		}
+		for i, result := range results[1:] {
+			if !errors.Is(result.Err, context.Canceled) {
+				t.Errorf("result %d error = %v, want context.Canceled", i+1, result.Err)
+			}
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+)
+
+// BlobStore stores immutable encrypted blobs addressed by the identifier the
+// backend returns on upload (a CID for IPFS, a SHA-256 digest otherwise).
+// Buffered operations are bounded by STORAGE_TIMEOUT as well as ctx; streams
+// stay open as long as ctx allows, since large transfers can take minutes.
+type BlobStore interface {
+	// AddData uploads data and returns its identifier
+	AddData(ctx context.Context, data []byte) (string, error)
+	// AddReader streams data without buffering it in memory and returns its identifier
+	AddReader(ctx context.Context, reader io.Reader) (string, error)
+	// GetData downloads the full content of a blob
+	GetData(ctx context.Context, id string) ([]byte, error)
+	// GetReader opens a stream over a blob. The caller must close it.
+	GetReader(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes (or unpins) a blob; deleting a missing blob is not an error
+	Delete(ctx context.Context, id string) error
+	// Stat reports whether a blob exists and how large it is
+	Stat(ctx context.Context, id string) (*BlobInfo, error)
+	// Pin asks the backend to retain a blob; stores without garbage collection
+	// only check that it exists
+	Pin(ctx context.Context, id string) error
+	// Pinned reports whether a blob is currently retained
+	Pinned(ctx context.Context, id string) (bool, error)
+	// ListPinned returns the IDs of every retained blob, for orphan detection
+	ListPinned(ctx context.Context) ([]string, error)
+}
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	ID         string    `json:"id"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"` // When the blob was last written; zero if the backend does not say
+}
+
+// NewBlobStore creates the backend selected by STORAGE_BACKEND, behind a
+// read-through cache unless caching is disabled
+func NewBlobStore(config *configs.Config) (BlobStore, error) {
+	var store BlobStore
+	var err error
+	switch config.Storage.Backend {
+	case "ipfs":
+		store, err = NewIPFSClient(config)
+	case "local":
+		store, err = NewLocalStore(config)
+	case "s3":
+		store, err = NewS3Store(config)
+	default:
+		log.Printf("Unknown storage backend %q", config.Storage.Backend)
+		return nil, fmt.Errorf("unknown storage backend: %s", config.Storage.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Cache.MemoryBytes <= 0 {
+		return store, nil
+	}
+	return NewCachedStore(store, int64(config.Cache.MemoryBytes), config.Cache.Dir, int64(config.Cache.DiskBytes))
+}
+
+// withTimeout bounds a single buffered operation; a zero timeout only inherits ctx
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextReader stops a local stream once ctx is done, for sources that do not
+// take a context themselves
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CachedStore is a read-through cache in front of another BlobStore. Blobs are
+// immutable once addressed, so entries never go stale; they are only evicted for
+// space or when the blob is deleted. Recently used blobs are kept in memory, and
+// optionally on local disk so the cache survives restarts.
+type CachedStore struct {
+	BlobStore // Backend for writes, pins and cache misses
+
+	memory *lruCache
+	disk   *diskCache // nil when no cache directory is configured
+	group  singleflight.Group
+}
+
+// NewCachedStore wraps store with a memory cache of memoryBytes and, if dir is
+// set, a disk cache of diskBytes under dir
+func NewCachedStore(store BlobStore, memoryBytes int64, dir string, diskBytes int64) (*CachedStore, error) {
+	cached := &CachedStore{
+		BlobStore: store,
+		memory:    newLRUCache(memoryBytes),
+	}
+	if dir != "" {
+		disk, err := newDiskCache(dir, diskBytes)
+		if err != nil {
+			return nil, err
+		}
+		cached.disk = disk
+	}
+	return cached, nil
+}
+
+// GetData serves a blob from memory, then disk, then the backend. Concurrent
+// misses for the same ID share a single backend fetch, made with the context of
+// whichever caller arrived first.
+func (s *CachedStore) GetData(ctx context.Context, id string) ([]byte, error) {
+	if data, ok := s.memory.get(id); ok {
+		return data, nil
+	}
+	if s.disk != nil {
+		if data, ok := s.disk.get(id); ok {
+			s.memory.put(id, data)
+			return data, nil
+		}
+	}
+
+	result, err, _ := s.group.Do(id, func() (interface{}, error) {
+		data, err := s.BlobStore.GetData(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		s.memory.put(id, data)
+		if s.disk != nil {
+			s.disk.put(id, data)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// GetReader streams from the disk cache when possible. Large streamed blobs are
+// not added to the cache, so they pass straight through to the backend.
+func (s *CachedStore) GetReader(ctx context.Context, id string) (io.ReadCloser, error) {
+	if s.disk != nil {
+		if file, ok := s.disk.open(id); ok {
+			return file, nil
+		}
+	}
+	return s.BlobStore.GetReader(ctx, id)
+}
+
+// Delete removes the blob from the backend and evicts it from the cache
+func (s *CachedStore) Delete(ctx context.Context, id string) error {
+	s.memory.remove(id)
+	if s.disk != nil {
+		s.disk.remove(id)
+	}
+	return s.BlobStore.Delete(ctx, id)
+}
+
+// lruCache is a size-bounded in-memory LRU cache
+type lruCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List // Front is most recently used
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	id   string
+	data []byte
+}
+
+func newLRUCache(maxBytes int64) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(id string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).data, true
+}
+
+func (c *lruCache) put(id string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return // Would evict everything else for a single entry
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[id]; ok {
+		return
+	}
+	c.items[id] = c.order.PushFront(&lruEntry{id: id, data: data})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.evictOldest()
+	}
+}
+
+func (c *lruCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[id]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruCache) evictOldest() {
+	if elem := c.order.Back(); elem != nil {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry)
+	delete(c.items, entry.id)
+	c.size -= int64(len(entry.data))
+}
+
+// diskCache is a size-bounded LRU cache of files in a directory. The index is
+// rebuilt from the directory at startup, in file modification order.
+type diskCache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	size     int64
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type diskEntry struct {
+	name string
+	size int64
+}
+
+func newDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.Printf("Failed to create cache directory %s: %v", dir, err)
+		return nil, err
+	}
+	c := &diskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Failed to read cache directory %s: %v", dir, err)
+		return nil, err
+	}
+	type existing struct {
+		diskEntry
+		modTime int64
+	}
+	var files []existing
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || !isSHA256Hex(e.Name()) {
+			continue
+		}
+		files = append(files, existing{diskEntry{e.Name(), info.Size()}, info.ModTime().UnixNano()})
+	}
+	// Oldest first, so the newest end up at the front
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime < files[j].modTime })
+	for _, f := range files {
+		entry := f.diskEntry
+		c.items[entry.name] = c.order.PushFront(&entry)
+		c.size += entry.size
+	}
+	for c.size > c.maxBytes {
+		c.evictOldest()
+	}
+	return c, nil
+}
+
+// fileName hashes the blob ID so any backend's IDs are safe file names
+func (c *diskCache) fileName(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *diskCache) get(id string) ([]byte, bool) {
+	file, ok := c.open(id)
+	if !ok {
+		return nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Failed to read cached blob %s: %v", id, err)
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *diskCache) open(id string) (*os.File, bool) {
+	name := c.fileName(id)
+
+	c.mu.Lock()
+	elem, ok := c.items[name]
+	if ok {
+		c.order.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	file, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		c.remove(id)
+		return nil, false
+	}
+	return file, true
+}
+
+func (c *diskCache) put(id string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+	name := c.fileName(id)
+
+	// Write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(c.dir, ".cache-*")
+	if err != nil {
+		log.Printf("Failed to cache blob %s: %v", id, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Failed to cache blob %s: %v", id, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[name]; ok {
+		return
+	}
+	c.items[name] = c.order.PushFront(&diskEntry{name: name, size: size})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.evictOldest()
+	}
+}
+
+func (c *diskCache) remove(id string) {
+	name := c.fileName(id)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[name]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *diskCache) evictOldest() {
+	if elem := c.order.Back(); elem != nil {
+		c.removeElement(elem)
+	}
+}
+
+// removeElement drops an entry and its file; callers hold mu
+func (c *diskCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*diskEntry)
+	delete(c.items, entry.name)
+	c.size -= entry.size
+	if err := os.Remove(filepath.Join(c.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to evict cached blob %s: %v", entry.name, err)
+	}
+}
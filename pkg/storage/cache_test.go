@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+)
+
+// countingStore counts backend reads, optionally holding each one until release is closed
+type countingStore struct {
+	BlobStore
+	reads   atomic.Int32
+	release chan struct{}
+}
+
+func (s *countingStore) GetData(ctx context.Context, id string) ([]byte, error) {
+	s.reads.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.BlobStore.GetData(ctx, id)
+}
+
+// testLocalStore returns local blob storage in a temporary directory
+func testLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+	store, err := NewLocalStore(&configs.Config{Storage: configs.StorageConfig{LocalDir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestCachedStoreReadThrough(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		memory    int64
+		disk      bool
+		wantReads int32 // Backend reads for two GetData calls
+	}{
+		{"memory", 1 << 10, false, 1},
+		{"memory and disk", 1 << 10, true, 1},
+		{"blob larger than memory, no disk", 4, false, 2},
+		{"blob larger than memory, on disk", 4, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &countingStore{BlobStore: testLocalStore(t)}
+			dir := ""
+			if tt.disk {
+				dir = t.TempDir()
+			}
+			cached, err := NewCachedStore(backend, tt.memory, dir, 1<<10)
+			if err != nil {
+				t.Fatalf("NewCachedStore: %v", err)
+			}
+
+			id, err := cached.AddData(ctx, []byte("blob content"))
+			if err != nil {
+				t.Fatalf("AddData: %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				data, err := cached.GetData(ctx, id)
+				if err != nil {
+					t.Fatalf("GetData: %v", err)
+				}
+				if string(data) != "blob content" {
+					t.Fatalf("GetData = %q, want the stored content", data)
+				}
+			}
+			if got := backend.reads.Load(); got != tt.wantReads {
+				t.Errorf("backend reads = %d, want %d", got, tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestCachedStoreSharesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingStore{BlobStore: testLocalStore(t), release: make(chan struct{})}
+	cached, err := NewCachedStore(backend, 1<<10, "", 0)
+	if err != nil {
+		t.Fatalf("NewCachedStore: %v", err)
+	}
+	id, err := backend.AddData(ctx, []byte("shared"))
+	if err != nil {
+		t.Fatalf("AddData: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if data, err := cached.GetData(ctx, id); err != nil || string(data) != "shared" {
+				t.Errorf("GetData = %q, %v", data, err)
+			}
+		}()
+	}
+	// Let the callers pile up behind the first fetch before it returns
+	time.Sleep(50 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	if got := backend.reads.Load(); got != 1 {
+		t.Errorf("backend reads = %d, want 1", got)
+	}
+}
+
+func TestCachedStoreDeleteEvicts(t *testing.T) {
+	ctx := context.Background()
+	cached, err := NewCachedStore(testLocalStore(t), 1<<10, t.TempDir(), 1<<10)
+	if err != nil {
+		t.Fatalf("NewCachedStore: %v", err)
+	}
+	id, err := cached.AddData(ctx, []byte("erase me"))
+	if err != nil {
+		t.Fatalf("AddData: %v", err)
+	}
+	if _, err := cached.GetData(ctx, id); err != nil {
+		t.Fatalf("GetData: %v", err)
+	}
+
+	if err := cached.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := cached.GetData(ctx, id); err == nil {
+		t.Error("GetData served a deleted blob")
+	}
+	if reader, err := cached.GetReader(ctx, id); err == nil {
+		reader.Close()
+		t.Error("GetReader served a deleted blob")
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRUCache(8)
+	cache.put("a", []byte("aaa"))
+	cache.put("b", []byte("bbb"))
+	cache.get("a")                // b is now the least recently used
+	cache.put("c", []byte("ccc")) // 9 bytes, so b goes
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := cache.get(tt.id); ok != tt.want {
+			t.Errorf("cached %q = %v, want %v", tt.id, ok, tt.want)
+		}
+	}
+	if cache.size != 6 {
+		t.Errorf("size = %d, want 6", cache.size)
+	}
+
+	cache.put("huge", make([]byte, 9))
+	if _, ok := cache.get("huge"); ok {
+		t.Error("cached an entry larger than the whole cache")
+	}
+}
+
+func TestDiskCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newDiskCache(dir, 8)
+	if err != nil {
+		t.Fatalf("newDiskCache: %v", err)
+	}
+	cache.put("old", []byte("old"))
+	cache.put("new", []byte("new"))
+
+	// Make the modification times distinct, as they would be in practice
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, cache.fileName("old")), past, past); err != nil {
+		t.Fatal(err)
+	}
+	// Stray files are ignored when the index is rebuilt
+	if err := os.WriteFile(filepath.Join(dir, ".cache-partial"), []byte("junk"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := newDiskCache(dir, 8)
+	if err != nil {
+		t.Fatalf("newDiskCache: %v", err)
+	}
+	if data, ok := reopened.get("new"); !ok || !bytes.Equal(data, []byte("new")) {
+		t.Fatalf("get after restart = %q, %v", data, ok)
+	}
+	if reopened.size != 6 {
+		t.Errorf("size = %d, want 6", reopened.size)
+	}
+
+	// The oldest file is evicted first
+	reopened.put("next", []byte("next"))
+	if _, ok := reopened.get("old"); ok {
+		t.Error("the oldest entry survived eviction")
+	}
+	if _, err := os.Stat(filepath.Join(dir, reopened.fileName("old"))); !os.IsNotExist(err) {
+		t.Errorf("evicted file still on disk: %v", err)
+	}
+	file, ok := reopened.open("next")
+	if !ok {
+		t.Fatal("open missed a cached entry")
+	}
+	defer file.Close()
+	if data, _ := io.ReadAll(file); string(data) != "next" {
+		t.Errorf("open read %q, want %q", data, "next")
+	}
+}
@@ -2,122 +2,247 @@ package storage
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"io"
 	"log"
 	"net/http"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
 
+	files "github.com/ipfs/boxo/files"
 	ipfsapi "github.com/ipfs/go-ipfs-api"
 )
 
-// IPFSClient manages interactions with IPFS via Pinata
+// IPFSClient manages interactions with an IPFS HTTP API (Pinata or a local Kubo node)
 type IPFSClient struct {
 	Shell    *ipfsapi.Shell
 	MaxTries int
-	Timeout  time.Duration
+	Timeout  time.Duration // Bounds each buffered attempt; streams are bounded by the caller's context
 }
 
-// NewIPFSClient initializes a new IPFS client using Pinata with authentication
+// NewIPFSClient initializes a new IPFS client for the configured API URL. Pinata
+// credentials are sent only when set. The node is not contacted until first use,
+// so the server can start while IPFS is unreachable.
 func NewIPFSClient(config *configs.Config) (*IPFSClient, error) {
-	// Create a custom HTTP client with Pinata authentication
-	customClient := &http.Client{
-		Transport: &roundTripperWithAuth{
+	var transport http.RoundTripper = http.DefaultTransport
+	if config.IPFS.APIKey != "" {
+		// Wrap the default transport with Pinata authentication
+		transport = &roundTripperWithAuth{
 			transport: http.DefaultTransport.(*http.Transport), // Type assertion to *http.Transport
 			apiKey:    config.IPFS.APIKey,
 			secret:    config.IPFS.Secret,
-		},
-		Timeout: 30 * time.Second,
+		}
 	}
+	// No client-wide timeout: deadlines come from each call's context, so large
+	// streamed transfers are not cut off
+	customClient := &http.Client{Transport: transport}
 
 	// Initialize Shell with the custom client
-	shell := ipfsapi.NewShellWithClient("https://api.pinata.cloud/psa", customClient)
-
-	// Verify connection
-	for attempt := 1; attempt <= 3; attempt++ {
-		if _, err := shell.ID(); err == nil {
-			break
-		} else if attempt == 3 {
-			log.Printf("Failed to connect to IPFS (Pinata) after %d attempts: %v", attempt, err)
-			return nil, err
-		}
-		time.Sleep(time.Duration(attempt) * time.Second)
-	}
+	shell := ipfsapi.NewShellWithClient(config.IPFS.APIURL, customClient)
 
 	return &IPFSClient{
 		Shell:    shell,
 		MaxTries: 3,
-		Timeout:  30 * time.Second,
+		Timeout:  config.Timeouts.Storage,
 	}, nil
 }
 
 // AddData uploads data to IPFS and returns the CID
-func (c *IPFSClient) AddData(data []byte) (string, error) {
-	reader := bytes.NewReader(data)
+func (c *IPFSClient) AddData(ctx context.Context, data []byte) (string, error) {
+	var lastErr error
 	for attempt := 1; attempt <= c.MaxTries; attempt++ {
-		cid, err := c.Shell.Add(reader)
+		attemptCtx, cancel := withTimeout(ctx, c.Timeout)
+		cid, err := c.add(attemptCtx, bytes.NewReader(data))
+		cancel()
+		lastErr = err
 		if err == nil {
 			log.Printf("Successfully added data to IPFS, CID: %s", cid)
 			return cid, nil
 		}
 		log.Printf("Attempt %d failed to add data to IPFS: %v", attempt, err)
 		if attempt < c.MaxTries {
-			time.Sleep(time.Duration(attempt) * time.Second)
-			reader.Reset(data) // Reset reader for retry
+			if err := sleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return "", err
+			}
 		}
 	}
-	return "", logError("Failed to add data to IPFS after %d attempts", c.MaxTries)
-}
-
-func (c *IPFSClient) AddBatch(dataChunks [][]byte) ([]string, error) {
-	var cids []string
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	for _, chunk := range dataChunks {
-		wg.Add(1)
-		go func(data []byte) {
-			defer wg.Done()
-			cid, err := c.AddData(data)
-			if err != nil {
-				log.Printf("Failed to add batch chunk to IPFS: %v", err)
-				return
+	log.Printf("Failed to add data to IPFS after %d attempts", c.MaxTries)
+	return "", errs.Upstream("Storage is unavailable", lastErr)
+}
+
+func (c *IPFSClient) GetData(ctx context.Context, cid string) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= c.MaxTries; attempt++ {
+		data, err := c.cat(ctx, cid)
+		lastErr = err
+		if err == nil {
+			log.Printf("Successfully retrieved data from IPFS, CID: %s", cid)
+			return data, nil
+		}
+		log.Printf("Attempt %d failed to retrieve data from IPFS for CID %s: %v", attempt, cid, err)
+		if attempt < c.MaxTries {
+			if err := sleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return nil, err
 			}
-			mu.Lock()
-			cids = append(cids, cid)
-			mu.Unlock()
-		}(chunk)
+		}
+	}
+	log.Printf("Failed to retrieve data from IPFS for CID %s after %d attempts", cid, c.MaxTries)
+	return nil, errs.Upstream("Storage is unavailable", lastErr)
+}
+
+// cat reads a CID in full within one attempt's timeout
+func (c *IPFSClient) cat(ctx context.Context, cid string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	resp, err := c.Shell.Request("cat", cid).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
 	}
+	return io.ReadAll(resp.Output)
+}
 
-	wg.Wait()
-	if len(cids) != len(dataChunks) {
-		return nil, logError("Failed to add batch to IPFS: incomplete uploads")
+// AddReader streams data to IPFS without buffering it in memory and returns the CID.
+// A stream cannot be replayed, so unlike AddData there are no retries.
+func (c *IPFSClient) AddReader(ctx context.Context, reader io.Reader) (string, error) {
+	cid, err := c.add(ctx, reader)
+	if err != nil {
+		log.Printf("Failed to stream data to IPFS: %v", err)
+		return "", err
 	}
-	return cids, nil
+	log.Printf("Successfully streamed data to IPFS, CID: %s", cid)
+	return cid, nil
 }
 
-func (c *IPFSClient) GetData(cid string) ([]byte, error) {
+// add is Shell.Add with a context, so an abandoned upload stops sending
+func (c *IPFSClient) add(ctx context.Context, reader io.Reader) (string, error) {
+	dir := files.NewSliceDirectory([]files.DirEntry{files.FileEntry("", files.NewReaderFile(reader))})
+	body := files.NewMultiFileReader(dir, true, false)
+
+	var out struct{ Hash string }
+	if err := c.Shell.Request("add").Body(body).Exec(ctx, &out); err != nil {
+		return "", err
+	}
+	return out.Hash, nil
+}
+
+// GetReader opens a stream over the content of a CID. The caller must close it.
+func (c *IPFSClient) GetReader(ctx context.Context, cid string) (io.ReadCloser, error) {
+	var lastErr error
 	for attempt := 1; attempt <= c.MaxTries; attempt++ {
-		reader, err := c.Shell.Cat(cid)
+		resp, err := c.Shell.Request("cat", cid).Send(ctx)
+		if err == nil && resp.Error != nil {
+			resp.Close()
+			err = resp.Error
+		}
+		lastErr = err
 		if err == nil {
-			defer reader.Close()
-			data, err := io.ReadAll(reader)
-			if err != nil {
-				log.Printf("Attempt %d failed to read IPFS data for CID %s: %v", attempt, cid, err)
-				continue
-			}
-			log.Printf("Successfully retrieved data from IPFS, CID: %s", cid)
-			return data, nil
+			return resp.Output, nil
 		}
-		log.Printf("Attempt %d failed to retrieve data from IPFS for CID %s: %v", attempt, cid, err)
+		log.Printf("Attempt %d failed to open IPFS stream for CID %s: %v", attempt, cid, err)
 		if attempt < c.MaxTries {
-			time.Sleep(time.Duration(attempt) * time.Second)
+			if err := sleepContext(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return nil, err
+			}
+		}
+	}
+	log.Printf("Failed to open IPFS stream for CID %s after %d attempts", cid, c.MaxTries)
+	return nil, errs.Upstream("Storage is unavailable", lastErr)
+}
+
+// Delete unpins a CID so the node may garbage-collect it
+func (c *IPFSClient) Delete(ctx context.Context, cid string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	if err := c.Shell.Request("pin/rm", cid).Option("recursive", true).Exec(ctx, nil); err != nil {
+		// Kubo reports an error for content that was never pinned
+		if strings.Contains(err.Error(), "not pinned") {
+			return nil
+		}
+		log.Printf("Failed to unpin CID %s: %v", cid, err)
+		return err
+	}
+	log.Printf("Unpinned CID: %s", cid)
+	return nil
+}
+
+// Pin recursively pins a CID on the node
+func (c *IPFSClient) Pin(ctx context.Context, cid string) error {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	if err := c.Shell.Request("pin/add", cid).Option("recursive", true).Exec(ctx, nil); err != nil {
+		log.Printf("Failed to pin CID %s: %v", cid, err)
+		return err
+	}
+	return nil
+}
+
+// Pinned reports whether a CID is recursively pinned
+func (c *IPFSClient) Pinned(ctx context.Context, cid string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var out struct{ Keys map[string]ipfsapi.PinInfo }
+	err := c.Shell.Request("pin/ls", cid).Option("type", ipfsapi.RecursivePin).Exec(ctx, &out)
+	if err != nil {
+		if strings.Contains(err.Error(), "not pinned") {
+			return false, nil
 		}
+		log.Printf("Failed to check pin for CID %s: %v", cid, err)
+		return false, err
+	}
+	return len(out.Keys) > 0, nil
+}
+
+// ListPinned returns every recursively pinned CID on the node
+func (c *IPFSClient) ListPinned(ctx context.Context) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	pins, err := c.Shell.PinsOfType(ctx, ipfsapi.RecursivePin)
+	if err != nil {
+		log.Printf("Failed to list IPFS pins: %v", err)
+		return nil, err
+	}
+	cids := make([]string, 0, len(pins))
+	for cid := range pins {
+		cids = append(cids, cid)
+	}
+	return cids, nil
+}
+
+// Stat reports the size of the file behind a CID
+func (c *IPFSClient) Stat(ctx context.Context, cid string) (*BlobInfo, error) {
+	ctx, cancel := withTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	stat, err := c.Shell.FilesStat(ctx, "/ipfs/"+cid)
+	if err != nil {
+		log.Printf("Failed to stat CID %s: %v", cid, err)
+		return nil, err
+	}
+	return &BlobInfo{ID: cid, Size: int64(stat.Size)}, nil
+}
+
+// sleepContext waits between retries, returning early if ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return nil, logError("Failed to retrieve data from IPFS for CID %s after %d attempts", cid, c.MaxTries)
 }
 
 // roundTripperWithAuth adds Pinata authentication headers to requests
@@ -133,10 +258,3 @@ func (r roundTripperWithAuth) RoundTrip(req *http.Request) (*http.Response, erro
 	req.Header.Set("pinata_secret_api_key", r.secret)
 	return r.transport.RoundTrip(req)
 }
-
-func logError(format string, args ...interface{}) error {
-	errMsg := fmt.Sprintf(format, args...)
-	err := fmt.Errorf(errMsg)
-	log.Println(err)
-	return err
-}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+)
+
+// LocalStore is a content-addressed blob store on the local filesystem.
+// Blobs are named by the hex SHA-256 of their content and fanned out into
+// subdirectories by the first two characters.
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore creates the storage directory if needed
+func NewLocalStore(config *configs.Config) (*LocalStore, error) {
+	dir := config.Storage.LocalDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.Printf("Failed to create local storage directory %s: %v", dir, err)
+		return nil, err
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+// AddData writes data and returns its SHA-256 digest
+func (s *LocalStore) AddData(ctx context.Context, data []byte) (string, error) {
+	return s.AddReader(ctx, bytes.NewReader(data))
+}
+
+// AddReader streams data into a temporary file while hashing it, then moves it into place
+func (s *LocalStore) AddReader(ctx context.Context, reader io.Reader) (string, error) {
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		log.Printf("Failed to create temporary blob file: %v", err)
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), &contextReader{ctx, reader}); err != nil {
+		tmp.Close()
+		log.Printf("Failed to write blob: %v", err)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		log.Printf("Failed to close blob file: %v", err)
+		return "", err
+	}
+
+	id := hex.EncodeToString(hash.Sum(nil))
+	path, _ := s.path(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		log.Printf("Failed to create blob directory: %v", err)
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		log.Printf("Failed to store blob %s: %v", id, err)
+		return "", err
+	}
+
+	log.Printf("Successfully stored blob locally, ID: %s", id)
+	return id, nil
+}
+
+// GetData reads a blob in full
+func (s *LocalStore) GetData(ctx context.Context, id string) ([]byte, error) {
+	reader, err := s.GetReader(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// GetReader opens a blob for streaming
+func (s *LocalStore) GetReader(ctx context.Context, id string) (io.ReadCloser, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Failed to open blob %s: %v", id, err)
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&contextReader{ctx, file}, file}, nil
+}
+
+// Delete removes a blob
+func (s *LocalStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to delete blob %s: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// Stat reports the size of a blob
+func (s *LocalStore) Stat(ctx context.Context, id string) (*BlobInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("Failed to stat blob %s: %v", id, err)
+		return nil, err
+	}
+	return &BlobInfo{ID: id, Size: info.Size(), ModifiedAt: info.ModTime().UTC()}, nil
+}
+
+// Pin checks that a blob exists; local blobs are kept until deleted
+func (s *LocalStore) Pin(ctx context.Context, id string) error {
+	_, err := s.Stat(ctx, id)
+	return err
+}
+
+// Pinned reports whether a blob exists
+func (s *LocalStore) Pinned(ctx context.Context, id string) (bool, error) {
+	if _, err := s.Stat(ctx, id); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListPinned walks the store and returns every blob ID
+func (s *LocalStore) ListPinned(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() && isSHA256Hex(d.Name()) {
+			ids = append(ids, d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to list local blobs: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// path maps an ID to its file, rejecting anything that is not a SHA-256 digest
+func (s *LocalStore) path(id string) (string, error) {
+	if !isSHA256Hex(id) {
+		return "", errs.Invalid("Invalid blob ID: " + id)
+	}
+	return filepath.Join(s.Dir, id[:2], id), nil
+}
+
+// isSHA256Hex reports whether id is 64 lowercase hex characters
+func isSHA256Hex(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Frhnmj2004/hippocard-server/configs"
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const s3PartSize = 16 << 20 // Bounds memory per streamed upload
+
+// S3Store is a content-addressed blob store on any S3-compatible service,
+// such as AWS S3 or a local MinIO. Objects are keyed by the hex SHA-256 of their content.
+type S3Store struct {
+	Client  *minio.Client
+	Bucket  string
+	Timeout time.Duration // Bounds each buffered operation
+}
+
+// NewS3Store creates an S3 client. Like the other stores it does not contact the
+// service until the first request.
+func NewS3Store(config *configs.Config) (*S3Store, error) {
+	client, err := minio.New(config.S3.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.S3.AccessKey, config.S3.SecretKey, ""),
+		Secure: config.S3.UseSSL,
+		Region: config.S3.Region,
+	})
+	if err != nil {
+		log.Printf("Failed to create S3 client: %v", err)
+		return nil, err
+	}
+	return &S3Store{Client: client, Bucket: config.S3.Bucket, Timeout: config.Timeouts.Storage}, nil
+}
+
+// AddData uploads data under its SHA-256 digest
+func (s *S3Store) AddData(ctx context.Context, data []byte) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	_, err := s.Client.PutObject(ctx, s.Bucket, id, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		log.Printf("Failed to upload blob to S3: %v", err)
+		return "", err
+	}
+	log.Printf("Successfully uploaded blob to S3, ID: %s", id)
+	return id, nil
+}
+
+// AddReader streams data to a temporary key while hashing it, then copies it to
+// its content address. The digest is only known once the upload has finished.
+func (s *S3Store) AddReader(ctx context.Context, reader io.Reader) (string, error) {
+	tmpKey := "uploads/" + uuid.New().String()
+
+	hash := sha256.New()
+	_, err := s.Client.PutObject(ctx, s.Bucket, tmpKey, io.TeeReader(reader, hash), -1, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    s3PartSize,
+	})
+	if err != nil {
+		log.Printf("Failed to stream blob to S3: %v", err)
+		return "", err
+	}
+	defer func() {
+		// Clean up even if the request was cancelled after the upload
+		ctx, cancel := withTimeout(context.WithoutCancel(ctx), s.Timeout)
+		defer cancel()
+		if err := s.Client.RemoveObject(ctx, s.Bucket, tmpKey, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("Failed to remove temporary S3 object %s: %v", tmpKey, err)
+		}
+	}()
+
+	id := hex.EncodeToString(hash.Sum(nil))
+	_, err = s.Client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.Bucket, Object: id},
+		minio.CopySrcOptions{Bucket: s.Bucket, Object: tmpKey},
+	)
+	if err != nil {
+		log.Printf("Failed to move streamed blob to %s: %v", id, err)
+		return "", err
+	}
+
+	log.Printf("Successfully streamed blob to S3, ID: %s", id)
+	return id, nil
+}
+
+// GetData downloads a blob in full
+func (s *S3Store) GetData(ctx context.Context, id string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	reader, err := s.GetReader(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// GetReader opens a blob for streaming
+func (s *S3Store) GetReader(ctx context.Context, id string) (io.ReadCloser, error) {
+	if !isSHA256Hex(id) {
+		return nil, errs.Invalid("Invalid blob ID: " + id)
+	}
+	object, err := s.Client.GetObject(ctx, s.Bucket, id, minio.GetObjectOptions{})
+	if err != nil {
+		log.Printf("Failed to get blob %s from S3: %v", id, err)
+		return nil, err
+	}
+	// GetObject is lazy; stat now so a missing blob fails here rather than mid-read
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		log.Printf("Failed to get blob %s from S3: %v", id, err)
+		return nil, err
+	}
+	return object, nil
+}
+
+// Delete removes a blob; S3 treats deleting a missing key as success
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	if !isSHA256Hex(id) {
+		return errs.Invalid("Invalid blob ID: " + id)
+	}
+	ctx, cancel := withTimeout(ctx, s.Timeout)
+	defer cancel()
+	if err := s.Client.RemoveObject(ctx, s.Bucket, id, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("Failed to delete blob %s from S3: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// Stat reports the size of a blob
+func (s *S3Store) Stat(ctx context.Context, id string) (*BlobInfo, error) {
+	if !isSHA256Hex(id) {
+		return nil, errs.Invalid("Invalid blob ID: " + id)
+	}
+	ctx, cancel := withTimeout(ctx, s.Timeout)
+	defer cancel()
+	info, err := s.Client.StatObject(ctx, s.Bucket, id, minio.StatObjectOptions{})
+	if err != nil {
+		log.Printf("Failed to stat blob %s in S3: %v", id, err)
+		return nil, err
+	}
+	return &BlobInfo{ID: id, Size: info.Size, ModifiedAt: info.LastModified.UTC()}, nil
+}
+
+// Pin checks that a blob exists; S3 objects are kept until deleted
+func (s *S3Store) Pin(ctx context.Context, id string) error {
+	_, err := s.Stat(ctx, id)
+	return err
+}
+
+// Pinned reports whether a blob exists
+func (s *S3Store) Pinned(ctx context.Context, id string) (bool, error) {
+	if _, err := s.Stat(ctx, id); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListPinned returns every content-addressed object in the bucket
+func (s *S3Store) ListPinned(ctx context.Context) ([]string, error) {
+	var ids []string
+	for object := range s.Client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{}) {
+		if object.Err != nil {
+			log.Printf("Failed to list S3 blobs: %v", object.Err)
+			return nil, object.Err
+		}
+		if isSHA256Hex(object.Key) {
+			ids = append(ids, object.Key)
+		}
+	}
+	return ids, nil
+}
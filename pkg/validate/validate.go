@@ -0,0 +1,288 @@
+// Package validate checks request structs against rules declared in their
+// `validate` tags, e.g.
+//
+//	Medication string `json:"medication" validate:"required,max=200"`
+//
+// Rules are comma-separated and checked in order:
+//
+//	required   the value is not empty (zero, "", no items or JSON null)
+//	omitempty  skip the remaining rules when the value is empty
+//	min=N      strings: at least N characters; slices and maps: at least N
+//	           items; numbers: at least N
+//	max=N      the upper bound, read like min
+//	len=N      strings: exactly N characters; slices, maps and []byte: exactly N items
+//	oneof=a b  the string is one of the space-separated values
+//	email      the string is a bare email address
+//	date       the string is a YYYY-MM-DD date
+//	phone      the string is a phone number of 7 to 15 digits
+//	unique     the slice has no repeated values
+//	dive       the rules after it apply to each item of the slice
+//
+// Nested structs, and structs held in slices and maps, are checked too. Fields
+// are reported by their JSON names (or query names, for query parameters), so
+// errors match what the client sent. A struct implementing Tidier is tidied
+// before it is checked.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// Tidier is implemented by requests that normalize their own values, e.g. by
+// trimming free text, so their rules see what will be stored
+type Tidier interface {
+	Tidy()
+}
+
+// Struct checks every field of v, a struct or a pointer to one. It returns nil
+// or an errs.InvalidFields error listing the first broken rule of each field.
+// A malformed tag is a programming error and panics.
+func Struct(v any) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+	if tidier, ok := v.(Tidier); ok {
+		tidier.Tidy()
+	}
+	var fields []errs.FieldError
+	checkStruct(value, "", &fields)
+	if len(fields) > 0 {
+		return errs.InvalidFields(fields...)
+	}
+	return nil
+}
+
+// checkStruct checks the fields of a struct value, naming them under prefix
+func checkStruct(value reflect.Value, prefix string, fields *[]errs.FieldError) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		// Embedded structs are flattened, as encoding/json does
+		path := join(prefix, name)
+		if field.Anonymous && name == field.Name {
+			path = prefix
+		}
+
+		fieldValue := value.Field(i)
+		rules, itemRules, dive := splitDive(field.Tag.Get("validate"))
+		if rules != "" {
+			if message := checkRules(fieldValue, rules); message != "" {
+				*fields = append(*fields, errs.FieldError{Field: path, Message: message})
+				continue
+			}
+		}
+		if dive && !checkItems(fieldValue, itemRules, path, fields) {
+			continue
+		}
+		checkNested(fieldValue, path, fields)
+	}
+}
+
+// splitDive separates a field's own rules from those a dive applies to its items
+func splitDive(tag string) (rules, itemRules string, dive bool) {
+	parts := strings.Split(tag, ",")
+	i := slices.Index(parts, "dive")
+	if i < 0 {
+		return tag, "", false
+	}
+	return strings.Join(parts[:i], ","), strings.Join(parts[i+1:], ","), true
+}
+
+// checkItems applies rules to each item of a slice, naming them by index under
+// path, and reports whether every item kept them
+func checkItems(value reflect.Value, rules, path string, fields *[]errs.FieldError) bool {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		panic("validate: dive does not apply to " + value.Type().String())
+	}
+	if rules == "" {
+		return true
+	}
+	ok := true
+	for i := 0; i < value.Len(); i++ {
+		if message := checkRules(value.Index(i), rules); message != "" {
+			*fields = append(*fields, errs.FieldError{Field: join(path, strconv.Itoa(i)), Message: message})
+			ok = false
+		}
+	}
+	return ok
+}
+
+// checkNested checks the structs a field holds, directly or in a slice or map
+func checkNested(value reflect.Value, path string, fields *[]errs.FieldError) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			checkNested(value.Elem(), path, fields)
+		}
+	case reflect.Struct:
+		checkStruct(value, path, fields)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			checkNested(value.Index(i), join(path, strconv.Itoa(i)), fields)
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+		for _, key := range keys {
+			checkNested(value.MapIndex(key), join(path, fmt.Sprint(key)), fields)
+		}
+	}
+}
+
+// checkRules applies a field's rules in order and describes the first one it
+// breaks, or returns "" when it keeps them all
+func checkRules(value reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if isEmpty(value) {
+				return "is required"
+			}
+		case "omitempty":
+			if isEmpty(value) {
+				return ""
+			}
+		case "min", "max", "len":
+			if message := checkBound(value, name, arg); message != "" {
+				return message
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			if !slices.Contains(allowed, value.String()) {
+				return "must be one of " + strings.Join(allowed, ", ")
+			}
+		case "email":
+			if address, err := mail.ParseAddress(value.String()); err != nil || address.Address != value.String() {
+				return "must be an email address"
+			}
+		case "date":
+			if _, err := time.Parse(time.DateOnly, value.String()); err != nil {
+				return "must be a YYYY-MM-DD date"
+			}
+		case "phone":
+			if digits := countDigits(value.String()); digits < 7 || digits > 15 {
+				return "must be a phone number of 7 to 15 digits"
+			}
+		case "unique":
+			seen := make(map[any]bool, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				item := value.Index(i).Interface()
+				if seen[item] {
+					return "must not repeat values"
+				}
+				seen[item] = true
+			}
+		default:
+			panic("validate: unknown rule " + rule)
+		}
+	}
+	return ""
+}
+
+// checkBound applies a min, max or len rule to a string, collection or number
+func checkBound(value reflect.Value, rule, arg string) string {
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("validate: " + rule + " needs a number, got " + arg)
+	}
+
+	var size float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size, unit = float64(value.Len()), " items"
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			unit = " bytes"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = value.Float()
+	default:
+		panic("validate: " + rule + " does not apply to " + value.Type().String())
+	}
+
+	switch {
+	case rule == "min" && size < bound:
+		return "must be at least " + arg + unit
+	case rule == "max" && size > bound:
+		return "must be at most " + arg + unit
+	case rule == "len" && size != bound:
+		return "must be exactly " + arg + unit
+	}
+	return ""
+}
+
+// countDigits counts the ASCII digits of s, ignoring spacing and punctuation
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// isEmpty reports whether a value counts as missing: its zero value, an empty
+// string or collection, or a JSON null
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if value.Type() == rawMessageType {
+			return value.Len() == 0 || string(value.Bytes()) == "null"
+		}
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// fieldName returns the name a client uses for a field: its JSON name, else
+// its query parameter name, else its Go name. ok is false for skipped fields.
+func fieldName(field reflect.StructField) (name string, ok bool) {
+	tag, found := field.Tag.Lookup("json")
+	if !found {
+		tag = field.Tag.Get("query")
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
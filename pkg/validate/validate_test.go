@@ -0,0 +1,161 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Frhnmj2004/hippocard-server/pkg/errs"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+// Embedded is exported, as embedded structs with unexported types are skipped
+type Embedded struct {
+	Note string `json:"note" validate:"max=3"`
+}
+
+type testRequest struct {
+	Embedded
+	Name      string                 `json:"name,omitempty" validate:"required,max=5"`
+	Email     string                 `json:"email" validate:"omitempty,email"`
+	Role      string                 `json:"role" validate:"oneof=doctor nurse"`
+	Born      string                 `json:"born" validate:"omitempty,date"`
+	Phone     string                 `json:"phone" validate:"omitempty,phone"`
+	Dosage    uint64                 `json:"dosage" validate:"min=1,max=1000"`
+	Key       []byte                 `json:"key" validate:"omitempty,len=4"`
+	Tags      []string               `json:"tags" validate:"unique,max=3,dive,required,max=4"`
+	Data      json.RawMessage        `json:"data" validate:"required"`
+	Address   *testAddress           `json:"address"`
+	Addresses []testAddress          `json:"addresses"`
+	ByName    map[string]testAddress `json:"by_name"`
+	Page      int                    `query:"page" validate:"omitempty,min=1"`
+	Skipped   string                 `json:"-" validate:"required"`
+}
+
+// validRequest returns a request that keeps every rule
+func validRequest() *testRequest {
+	return &testRequest{
+		Name:   "Ann",
+		Role:   "nurse",
+		Dosage: 5,
+		Tags:   []string{"a", "b"},
+		Data:   json.RawMessage(`{}`),
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*testRequest)
+		field   string
+		message string
+	}{
+		{"missing", func(r *testRequest) { r.Name = "" }, "name", "is required"},
+		{"too long in characters", func(r *testRequest) { r.Name = "Zoëëëë" }, "name", "must be at most 5 characters"},
+		{"email", func(r *testRequest) { r.Email = "Ann <ann@example.com>" }, "email", "must be an email address"},
+		{"oneof", func(r *testRequest) { r.Role = "admin" }, "role", "must be one of doctor, nurse"},
+		{"date", func(r *testRequest) { r.Born = "2023-02-29" }, "born", "must be a YYYY-MM-DD date"},
+		{"short phone", func(r *testRequest) { r.Phone = "+1 555-01" }, "phone", "must be a phone number of 7 to 15 digits"},
+		{"long phone", func(r *testRequest) { r.Phone = "+44 20 7946 0958 0000" }, "phone", "must be a phone number of 7 to 15 digits"},
+		{"below min", func(r *testRequest) { r.Dosage = 0 }, "dosage", "must be at least 1"},
+		{"above max", func(r *testRequest) { r.Dosage = 1001 }, "dosage", "must be at most 1000"},
+		{"len of bytes", func(r *testRequest) { r.Key = []byte{1, 2} }, "key", "must be exactly 4 bytes"},
+		{"repeated", func(r *testRequest) { r.Tags = []string{"a", "a"} }, "tags", "must not repeat values"},
+		{"too many items", func(r *testRequest) { r.Tags = []string{"a", "b", "c", "d"} }, "tags", "must be at most 3 items"},
+		{"item rule", func(r *testRequest) { r.Tags = []string{"a", "toolong"} }, "tags.1", "must be at most 4 characters"},
+		{"empty item", func(r *testRequest) { r.Tags = []string{"", "a"} }, "tags.0", "is required"},
+		{"JSON null", func(r *testRequest) { r.Data = json.RawMessage("null") }, "data", "is required"},
+		{"pointer", func(r *testRequest) { r.Address = &testAddress{} }, "address.city", "is required"},
+		{"slice of structs", func(r *testRequest) { r.Addresses = []testAddress{{City: "Oslo"}, {}} }, "addresses.1.city", "is required"},
+		{"map of structs", func(r *testRequest) { r.ByName = map[string]testAddress{"home": {}} }, "by_name.home.city", "is required"},
+		{"embedded", func(r *testRequest) { r.Note = "long" }, "note", "must be at most 3 characters"},
+		{"query name", func(r *testRequest) { r.Page = -1 }, "page", "must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.edit(req)
+			err := Struct(req)
+			want := []errs.FieldError{{Field: tt.field, Message: tt.message}}
+			var e *errs.Error
+			if !errors.As(err, &e) || !errors.Is(err, errs.ErrInvalid) || !slices.Equal(e.Fields, want) {
+				t.Errorf("Struct = %v, want %v", err, want)
+			}
+		})
+	}
+
+	if err := Struct(validRequest()); err != nil {
+		t.Errorf("Struct of a valid request = %v", err)
+	}
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	req := validRequest()
+	req.Name, req.Role, req.Dosage = "", "admin", 0
+	var e *errs.Error
+	if err := Struct(req); !errors.As(err, &e) {
+		t.Fatalf("Struct = %v, want field errors", err)
+	}
+	var got []string
+	for _, field := range e.Fields {
+		got = append(got, field.Field)
+	}
+	if want := []string{"name", "role", "dosage"}; !slices.Equal(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+type tidyRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func (r *tidyRequest) Tidy() {
+	r.Name = strings.TrimSpace(r.Name)
+}
+
+func TestStructTidiesFirst(t *testing.T) {
+	req := &tidyRequest{Name: "  "}
+	if err := Struct(req); err == nil {
+		t.Error("Struct accepted a name of only spaces")
+	}
+	req.Name = " Ann "
+	if err := Struct(req); err != nil || req.Name != "Ann" {
+		t.Errorf("Struct = %v with name %q, want Ann", err, req.Name)
+	}
+}
+
+func TestStructPanicsOnMalformedTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"not a struct", "request"},
+		{"unknown rule", &struct {
+			A string `validate:"requred"`
+		}{}},
+		{"bound without a number", &struct {
+			A string `validate:"max=ten"`
+		}{}},
+		{"bound on a bool", &struct {
+			A bool `validate:"max=1"`
+		}{}},
+		{"dive on a string", &struct {
+			A string `validate:"dive,required"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Struct did not panic")
+				}
+			}()
+			Struct(tt.v)
+		})
+	}
+}